@@ -0,0 +1,423 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+func (c *cli) commands() map[string]command {
+	var (
+		tree       folder.TreeOptions
+		serve      = serveOptions{addr: "localhost:8080"}
+		gen        = folder.DefaultGeneratorConfig()
+		patch      bool
+		bestEffort bool
+	)
+	genDepth, genFanOut := int(folder.MaxDepth), folder.MaxChild
+
+	list := []command{
+		{
+			name:  "ls",
+			usage: "ls [--org ID] [--format F]",
+			flags: func(c *cli, fs *flag.FlagSet) { formatFlag(c, fs, formatTree) },
+			run:   (*cli).ls,
+		},
+		{
+			name:  "tree",
+			usage: "tree [name] [--org ID] [--depth N] [--ascii] [--sorted]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.IntVar(&tree.MaxDepth, "depth", 0, "only print this many levels, 0 prints everything")
+				fs.BoolVar(&tree.ASCII, "ascii", false, "draw the tree with ASCII characters only")
+				fs.BoolVar(&tree.Sorted, "sorted", false, "sort siblings by name")
+			},
+			maxArgs: 1,
+			run: func(c *cli, args []string) error {
+				return c.tree(args, tree)
+			},
+		},
+		{
+			name:    "children",
+			usage:   "children <name> --org ID [--format F]",
+			flags:   func(c *cli, fs *flag.FlagSet) { formatFlag(c, fs, formatTree) },
+			minArgs: 1,
+			maxArgs: 1,
+			run:     (*cli).children,
+		},
+		{
+			name:  "mv",
+			usage: "mv <name> <dst> [--org ID] [--in-place] [--format F]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				formatFlag(c, fs, formatTree)
+				inPlaceFlag(c, fs)
+			},
+			minArgs: 2,
+			maxArgs: 2,
+			run:     (*cli).mv,
+		},
+		{
+			name:  "validate",
+			usage: "validate",
+			run:   (*cli).validate,
+		},
+		{
+			name:  "generate",
+			usage: "generate [--seed N] [--orgs N] [--roots N] [--depth N] [--fanout N] [--org ID] [--format F] [-o file]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				formatFlag(c, fs, formatJSON)
+				outFlag(c, fs)
+				fs.Int64Var(&gen.Seed, "seed", gen.Seed, "rng seed, the same seed always generates the same data")
+				fs.IntVar(&gen.Orgs, "orgs", gen.Orgs, "number of orgs, --org is used as the first one")
+				fs.IntVar(&gen.RootsPerOrg, "roots", gen.RootsPerOrg, "root folders per org")
+				fs.IntVar(&genDepth, "depth", genDepth, "levels in every root's tree")
+				fs.IntVar(&genFanOut, "fanout", genFanOut, "at most this many children per folder")
+				fs.BoolVar(&gen.UniqueNames, "unique", gen.UniqueNames, "never reuse a folder name")
+			},
+			run: func(c *cli, args []string) error {
+				if genDepth < 1 || genFanOut < 1 {
+					return usageErrorf("--depth and --fanout must be at least 1")
+				}
+				gen.Depth = folder.Fixed(genDepth)
+				gen.FanOut = folder.Uniform{Min: 1, Max: genFanOut}
+				return c.generate(gen)
+			},
+		},
+		{
+			name:  "export",
+			usage: "export [--org ID] [--format F] [-o file]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				formatFlag(c, fs, formatJSON)
+				outFlag(c, fs)
+			},
+			run: (*cli).export,
+		},
+		{
+			name:    "import",
+			usage:   "import <file> [--org ID] [--in-place]",
+			flags:   inPlaceFlag,
+			minArgs: 1,
+			maxArgs: 1,
+			run:     (*cli).importFile,
+		},
+		{
+			name:  "diff",
+			usage: "diff <file> [--org ID] [--format json|tree] [--patch]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				formatFlag(c, fs, formatTree)
+				fs.BoolVar(&patch, "patch", false, "print a patch for apply instead of the changes")
+			},
+			minArgs: 1,
+			maxArgs: 1,
+			run: func(c *cli, args []string) error {
+				return c.diff(args, patch)
+			},
+		},
+		{
+			name:  "apply",
+			usage: "apply <patch> [--org ID] [--best-effort] [--in-place]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				inPlaceFlag(c, fs)
+				fs.BoolVar(&bestEffort, "best-effort", false, "skip the operations that conflict instead of applying nothing")
+			},
+			minArgs: 1,
+			maxArgs: 1,
+			run: func(c *cli, args []string) error {
+				opts := folder.ApplyOptions{Mode: folder.Strict}
+				if bestEffort {
+					opts.Mode = folder.BestEffort
+				}
+				return c.applyPatch(args, opts)
+			},
+		},
+		{
+			name:  "serve",
+			usage: "serve [--addr host:port] [--store dir]",
+			flags: func(c *cli, fs *flag.FlagSet) {
+				fs.StringVar(&serve.addr, "addr", serve.addr, "address to listen on")
+				fs.StringVar(&serve.store, "store", "", "keep moves in this directory, seeded from --data on the first run")
+			},
+			run: func(c *cli, args []string) error {
+				return c.serve(serve)
+			},
+		},
+		{
+			name:  "shell",
+			usage: "shell [--org ID]",
+			run:   (*cli).shell,
+		},
+	}
+
+	commands := make(map[string]command, len(list))
+	for _, cmd := range list {
+		commands[cmd.name] = cmd
+	}
+	return commands
+}
+
+// loadOrg loads --data and keeps only the folders of --org when it's set
+func (c *cli) loadOrg() ([]folder.Folder, error) {
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return nil, err
+	}
+	folders, err := c.load()
+	if err != nil || !ok {
+		return folders, err
+	}
+	return folder.NewDriver(folders).GetFoldersByOrgID(orgID), nil
+}
+
+func (c *cli) ls(args []string) error {
+	folders, err := c.loadOrg()
+	if err != nil {
+		return err
+	}
+	return c.output(folders)
+}
+
+func (c *cli) tree(args []string, opts folder.TreeOptions) error {
+	folders, err := c.loadOrg()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		name := args[0]
+		root, ok := findFolder(folders, name)
+		if !ok {
+			return folder.ErrFolderNotFound
+		}
+		children, err := folder.NewDriver(folders).GetAllChildFolders(root.OrgId, name)
+		if err != nil {
+			return err
+		}
+		folders = append([]folder.Folder{root}, children...)
+	}
+	return folder.WriteTree(c.stdout, folders, opts)
+}
+
+func (c *cli) children(args []string) error {
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return usageErrorf("children needs --org")
+	}
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	children, err := folder.NewDriver(folders).GetAllChildFolders(orgID, args[0])
+	if err != nil {
+		return err
+	}
+	return c.output(children)
+}
+
+func (c *cli) mv(args []string) error {
+	name, dst := args[0], args[1]
+	orgID, checkOrg, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	if checkOrg {
+		// --org guards against moving a folder of the wrong customer
+		if src, ok := findFolder(folders, name); ok && src.OrgId != orgID {
+			return folder.ErrFolderNotInOrg
+		}
+	}
+
+	before := append([]folder.Folder{}, folders...)
+	after, err := folder.NewDriver(folders).MoveFolder(name, dst)
+	if err != nil {
+		return err
+	}
+
+	moved := folder.HighlightMoved(before, after)
+	changed := []folder.Folder{}
+	for _, f := range after {
+		if moved[f.Name] {
+			changed = append(changed, f)
+		}
+	}
+	fmt.Fprintf(c.stderr, "moved %d folders\n", len(changed))
+	if err := c.output(changed); err != nil {
+		return err
+	}
+	return c.save(after)
+}
+
+func (c *cli) validate(args []string) error {
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	problems := folder.Validate(folders)
+	for _, problem := range problems {
+		fmt.Fprintln(c.stdout, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d problems in %d folders", errInvalid, len(problems), len(folders))
+	}
+	fmt.Fprintf(c.stdout, "ok, %d folders\n", len(folders))
+	return nil
+}
+
+func (c *cli) generate(cfg folder.GeneratorConfig) error {
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	if ok {
+		cfg.OrgIDs = []uuid.UUID{orgID}
+	}
+	return c.output(folder.GenerateData(cfg))
+}
+
+func (c *cli) export(args []string) error {
+	folders, err := c.loadOrg()
+	if err != nil {
+		return err
+	}
+	return c.output(folders)
+}
+
+// importFile adds the folders of a file to --data, --org moves them all into that org
+func (c *cli) importFile(args []string) error {
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	incoming, err := readFolders(args[0], c.stdin)
+	if err != nil {
+		return err
+	}
+	if ok {
+		for i := range incoming {
+			incoming[i].OrgId = orgID
+		}
+	}
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	// nothing is written unless the result is valid
+	merged := append(folders, incoming...)
+	if problems := folder.Validate(merged); len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Fprintln(c.stdout, problem)
+		}
+		return fmt.Errorf("%w: importing %s would leave %d problems", errInvalid, args[0], len(problems))
+	}
+
+	fmt.Fprintf(c.stderr, "imported %d folders, %d in total\n", len(incoming), len(merged))
+	return c.save(merged)
+}
+
+// diff reports how the folders of a file differ from --data, as JSON, a
+// readable report or a patch that turns --data into the file
+func (c *cli) diff(args []string, patch bool) error {
+	if err := checkFormat(c.format, formatJSON, formatTree); err != nil {
+		return err
+	}
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	before, err := c.load()
+	if err != nil {
+		return err
+	}
+	after, err := readFolders(args[0], c.stdin)
+	if err != nil {
+		return err
+	}
+
+	if patch {
+		if ok {
+			before = folder.NewDriver(before).GetFoldersByOrgID(orgID)
+			after = folder.NewDriver(after).GetFoldersByOrgID(orgID)
+		}
+		p, err := folder.NewPatch(before, after)
+		if err != nil {
+			return err
+		}
+		return writeJSON(c.stdout, p)
+	}
+
+	entries := folder.Diff(before, after)
+	if ok {
+		// the changes of folders that are in the org on either side
+		kept := []folder.DiffEntry{}
+		for _, e := range entries {
+			if e.OrgID == orgID || e.OldOrgID == orgID {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	if c.format == formatJSON {
+		return writeJSON(c.stdout, entries)
+	}
+	return folder.WriteDiff(c.stdout, entries)
+}
+
+// applyPatch applies a patch written by diff --patch to --data, --org applies it to that org
+func (c *cli) applyPatch(args []string, opts folder.ApplyOptions) error {
+	orgID, ok, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	if ok {
+		opts.OrgID = orgID
+	}
+	var patch folder.Patch
+	if err := readJSON(args[0], c.stdin, &patch); err != nil {
+		return err
+	}
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	// the store keeps the folders in the order of the file
+	store := folder.NewMemoryStore(folders)
+	d, err := folder.NewDriverWithStore(store)
+	if err != nil {
+		return err
+	}
+	res, err := folder.Apply(d, patch, opts)
+	for _, conflict := range res.Conflicts {
+		fmt.Fprintln(c.stderr, conflict)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "applied %d of %d operations\n", res.Applied, len(patch))
+	folders, err = store.Load()
+	if err != nil {
+		return err
+	}
+	return c.save(folders)
+}
+
+// findFolder returns the first folder called name
+func findFolder(folders []folder.Folder, name string) (folder.Folder, bool) {
+	for _, f := range folders {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return folder.Folder{}, false
+}
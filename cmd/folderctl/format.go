@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// output and data file formats
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatYAML   = "yaml"
+	formatCSV    = "csv"
+	formatTree   = "tree"
+)
+
+// csvHeader is the first record of every CSV file, the same keys as the JSON fields
+var csvHeader = []string{"name", "org_id", "paths"}
+
+// yamlFolder is the YAML shape of a folder, uuid.UUID has no YAML marshalling of its own
+type yamlFolder struct {
+	Name  string `yaml:"name"`
+	OrgId string `yaml:"org_id"`
+	Paths string `yaml:"paths"`
+}
+
+// formatForPath picks the data format from a file extension, JSON when it's unknown
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".csv":
+		return formatCSV
+	}
+	return formatJSON
+}
+
+func checkFormat(format string, allowed ...string) error {
+	for _, f := range allowed {
+		if format == f {
+			return nil
+		}
+	}
+	return usageErrorf("unknown format %q, want one of %s", format, strings.Join(allowed, "|"))
+}
+
+// readFolders reads a data file, "-" reads JSON or NDJSON from stdin
+func readFolders(path string, stdin io.Reader) ([]folder.Folder, error) {
+	if path == "-" {
+		return folder.LoadFolders(stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var folders []folder.Folder
+	switch formatForPath(path) {
+	case formatYAML:
+		folders, err = decodeYAML(file)
+	case formatCSV:
+		folders, err = decodeCSV(file)
+	default:
+		// the JSON decoder detects NDJSON by itself
+		folders, err = folder.LoadFolders(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error: reading %s: %w", path, err)
+	}
+	return folders, nil
+}
+
+// writeFolders writes folders to a data file atomically, in the format its extension asks for
+func writeFolders(path string, folders []folder.Folder) error {
+	var buf bytes.Buffer
+	if err := encodeFolders(&buf, formatForPath(path), folders); err != nil {
+		return err
+	}
+	return folder.WriteFileAtomic(path, buf.Bytes())
+}
+
+// writeJSON writes v as indented JSON, the same layout as sample.json
+func writeJSON(w io.Writer, v any) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// readJSON decodes a JSON file into v, "-" reads stdin
+func readJSON(path string, stdin io.Reader, v any) error {
+	r := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("Error: reading %s: %w", path, err)
+	}
+	return nil
+}
+
+func encodeFolders(w io.Writer, format string, folders []folder.Folder) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, folders)
+	case formatNDJSON:
+		return folder.EncodeFolders(w, folder.FormatNDJSON, folders)
+	case formatYAML:
+		return encodeYAML(w, folders)
+	case formatCSV:
+		return encodeCSV(w, folders)
+	case formatTree:
+		return folder.WriteTree(w, folders, folder.TreeOptions{ShowCount: true})
+	}
+	return usageErrorf("unknown format %q", format)
+}
+
+func encodeYAML(w io.Writer, folders []folder.Folder) error {
+	out := make([]yamlFolder, len(folders))
+	for i, f := range folders {
+		out[i] = yamlFolder{Name: f.Name, OrgId: f.OrgId.String(), Paths: f.Paths}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func decodeYAML(r io.Reader) ([]folder.Folder, error) {
+	in := []yamlFolder{}
+	if err := yaml.NewDecoder(r).Decode(&in); err != nil && err != io.EOF {
+		return nil, err
+	}
+	folders := make([]folder.Folder, len(in))
+	for i, f := range in {
+		orgID, err := uuid.FromString(f.OrgId)
+		if err != nil {
+			return nil, fmt.Errorf("folder %d: org_id: %w", i, err)
+		}
+		folders[i] = folder.Folder{Name: f.Name, OrgId: orgID, Paths: f.Paths}
+	}
+	return folders, nil
+}
+
+func encodeCSV(w io.Writer, folders []folder.Folder) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, f := range folders {
+		if err := cw.Write([]string{f.Name, f.OrgId.String(), f.Paths}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func decodeCSV(r io.Reader) ([]folder.Folder, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return []folder.Folder{}, nil
+	}
+	if strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("header is %q, want %q", strings.Join(records[0], ","), strings.Join(csvHeader, ","))
+	}
+
+	folders := make([]folder.Folder, 0, len(records)-1)
+	for i, record := range records[1:] {
+		orgID, err := uuid.FromString(record[1])
+		if err != nil {
+			// +2 for the header and because lines count from 1
+			return nil, fmt.Errorf("line %d: org_id: %w", i+2, err)
+		}
+		folders = append(folders, folder.Folder{Name: record[0], OrgId: orgID, Paths: record[2]})
+	}
+	return folders, nil
+}
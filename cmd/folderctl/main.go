@@ -0,0 +1,254 @@
+// Command folderctl inspects and edits folder dumps from the command line.
+//
+//	folderctl ls       [--org ID] [--format F]           list folders
+//	folderctl tree     [name] [--org ID] [--depth N]     print the hierarchy
+//	folderctl children <name> --org ID [--format F]      list every descendant of a folder
+//	folderctl mv       <name> <dst> [--in-place]         move a folder and its subtree
+//	folderctl validate                                   check the dump for broken paths, duplicates ...
+//	folderctl generate [--seed N] [--orgs N] [-o file]   generate random data
+//	folderctl export   [--org ID] [--format F] [-o file] convert the dump to another format
+//	folderctl import   <file> [--in-place]               add the folders from file to the dump
+//	folderctl diff     <file> [--format json|tree]       report what file changed compared to the dump
+//	folderctl apply    <patch> [--best-effort]           apply a patch made by diff --patch
+//	folderctl shell    [--org ID]                        explore and edit the dump interactively
+//	folderctl serve    [--addr host:port] [--store dir]  serve the dump over HTTP, see package server
+//
+// Every command reads --data, a JSON, NDJSON, YAML or CSV file picked by its
+// extension, or the embedded sample data when --data is not set. --format is
+// one of json, ndjson, yaml, csv or tree. Commands that change the data only
+// write it back to --data with --in-place, otherwise they are a dry run.
+//
+// Exit codes: 0 on success, 1 when the driver or I/O fails, 2 for bad usage
+// and 3 when validate finds problems.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2
+	exitInvalid = 3
+)
+
+// errInvalid is returned by validate when the data has problems
+var errInvalid = errors.New("Error: data is not valid")
+
+// usageError is bad input from the user rather than a failure
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return "Error: " + e.msg
+}
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// cli is the state shared by all commands
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	// common flags
+	data    string
+	org     string
+	format  string
+	inPlace bool
+	out     string
+}
+
+type command struct {
+	name  string
+	usage string
+	// registers the command's flags, the common ones are added by run
+	flags func(c *cli, fs *flag.FlagSet)
+	// number of positional arguments, min and max
+	minArgs, maxArgs int
+	run              func(c *cli, args []string) error
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes one folderctl invocation and returns its exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	commands := c.commands()
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.printUsage(commands)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown command %q\n\n", args[0])
+		c.printUsage(commands)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("folderctl "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.data, "data", "", "folder data file, the embedded sample data when empty")
+	fs.StringVar(&c.org, "org", "", "only act on this org")
+	if cmd.flags != nil {
+		cmd.flags(c, fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: folderctl %s\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		// already reported by the flag set
+		return exitUsage
+	}
+	if c.format != "" {
+		err = checkFormat(c.format, formatJSON, formatNDJSON, formatYAML, formatCSV, formatTree)
+	}
+	if err != nil {
+		return c.exitCode(err)
+	}
+	if len(positional) < cmd.minArgs || len(positional) > cmd.maxArgs {
+		err = usageErrorf("usage: folderctl %s", cmd.usage)
+	} else {
+		err = cmd.run(c, positional)
+	}
+	return c.exitCode(err)
+}
+
+// exitCode reports err on stderr and maps it to an exit code
+func (c *cli) exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	if strings.HasPrefix(err.Error(), "Error: ") {
+		fmt.Fprintln(c.stderr, err)
+	} else {
+		fmt.Fprintln(c.stderr, "Error:", err)
+	}
+
+	var usage usageError
+	switch {
+	case errors.Is(err, errInvalid):
+		return exitInvalid
+	case errors.As(err, &usage):
+		return exitUsage
+	}
+	return exitError
+}
+
+// parseInterspersed parses flags that come before, between or after the
+// positional arguments, the flag package stops at the first positional one
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// everything after a "--" is positional
+		if len(args) > len(rest) && args[len(args)-len(rest)-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func (c *cli) printUsage(commands map[string]command) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(c.stderr, "usage: folderctl <command> [flags]")
+	fmt.Fprintln(c.stderr)
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "run folderctl <command> -h for the flags of a command")
+}
+
+// orgID parses --org, ok is false when it isn't set
+func (c *cli) orgID() (id uuid.UUID, ok bool, err error) {
+	if c.org == "" {
+		return uuid.Nil, false, nil
+	}
+	id, err = uuid.FromString(c.org)
+	if err != nil {
+		return uuid.Nil, false, usageErrorf("--org %q is not a valid uuid", c.org)
+	}
+	return id, true, nil
+}
+
+// load reads --data
+func (c *cli) load() ([]folder.Folder, error) {
+	if c.data == "" {
+		return folder.GetSampleData()
+	}
+	return readFolders(c.data, c.stdin)
+}
+
+// save writes folders back to --data when --in-place is set, and says so either way
+func (c *cli) save(folders []folder.Folder) error {
+	if !c.inPlace {
+		fmt.Fprintln(c.stderr, "dry run, pass --in-place to write the changes to --data")
+		return nil
+	}
+	if c.data == "" || c.data == "-" {
+		return usageErrorf("--in-place needs a --data file")
+	}
+	return writeFolders(c.data, folders)
+}
+
+// output writes folders to -o, or to stdout when it isn't set
+func (c *cli) output(folders []folder.Folder) error {
+	if c.out == "" {
+		return encodeFolders(c.stdout, c.format, folders)
+	}
+	var buf strings.Builder
+	if err := encodeFolders(&buf, c.format, folders); err != nil {
+		return err
+	}
+	return folder.WriteFileAtomic(c.out, []byte(buf.String()))
+}
+
+func formatFlag(c *cli, fs *flag.FlagSet, def string) {
+	fs.StringVar(&c.format, "format", def, "output format: json, ndjson, yaml, csv or tree")
+}
+
+func inPlaceFlag(c *cli, fs *flag.FlagSet) {
+	fs.BoolVar(&c.inPlace, "in-place", false, "write the changes back to --data")
+}
+
+func outFlag(c *cli, fs *flag.FlagSet) {
+	fs.StringVar(&c.out, "o", "", "write to this file instead of stdout")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	orgA = "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7"
+	orgB = "c1556e17-b7c0-45a3-a6ae-9546248fb17a"
+)
+
+func testFolders() []folder.Folder {
+	a, b := uuid.FromStringOrNil(orgA), uuid.FromStringOrNil(orgB)
+	return []folder.Folder{
+		{Name: "alpha", OrgId: a, Paths: "alpha"},
+		{Name: "bravo", OrgId: a, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: a, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: a, Paths: "alpha.delta"},
+		{Name: "echo", OrgId: b, Paths: "echo"},
+	}
+}
+
+// writeData writes the test folders to a data file in a temp dir
+func writeData(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, writeFolders(path, testFolders()))
+	return path
+}
+
+type result struct {
+	code           int
+	stdout, stderr string
+}
+
+func runCLI(args ...string) result {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(""), &stdout, &stderr)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func Test_folderctl_Usage(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "no command", args: nil, code: exitUsage, stderr: "usage: folderctl <command>"},
+		{name: "help", args: []string{"help"}, code: exitOK, stderr: "usage: folderctl <command>"},
+		{name: "unknown command", args: []string{"rm"}, code: exitUsage, stderr: `unknown command "rm"`},
+		{name: "unknown flag", args: []string{"ls", "--nope"}, code: exitUsage, stderr: "flag provided but not defined"},
+		{name: "bad format", args: []string{"ls", "--format", "xml"}, code: exitUsage, stderr: `unknown format "xml"`},
+		{name: "bad org", args: []string{"ls", "--org", "nope"}, code: exitUsage, stderr: `--org "nope" is not a valid uuid`},
+		{name: "missing arguments", args: []string{"mv", "alpha"}, code: exitUsage, stderr: "usage: folderctl mv"},
+		{name: "children without org", args: []string{"children", "alpha"}, code: exitUsage, stderr: "children needs --org"},
+		{name: "missing data file", args: []string{"ls", "--data", "missing.json"}, code: exitError, stderr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := runCLI(tt.args...)
+			assert.Equal(t, tt.code, got.code)
+			assert.Contains(t, got.stderr, tt.stderr)
+		})
+	}
+}
+
+func Test_folderctl_Read(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	got := runCLI("ls", "--data", data, "--org", orgB, "--format", "csv")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, "name,org_id,paths\necho,"+orgB+",echo\n", got.stdout)
+
+	got = runCLI("children", "bravo", "--data", data, "--org", orgA, "--format", "ndjson")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, `{"name":"charlie","org_id":"`+orgA+`","paths":"alpha.bravo.charlie"}`+"\n", got.stdout)
+
+	// driver errors exit with 1
+	got = runCLI("children", "echo", "--data", data, "--org", orgA)
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, folder.ErrFolderNotInOrg.Error()+"\n", got.stderr)
+
+	got = runCLI("tree", "bravo", "--data", data, "--ascii")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, orgA+"\n`-- bravo\n    `-- charlie\n", got.stdout)
+}
+
+func Test_folderctl_Move(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.yaml")
+	before, err := os.ReadFile(data)
+	require.NoError(t, err)
+
+	// without --in-place nothing is written
+	got := runCLI("mv", "bravo", "delta", "--data", data, "--format", "csv")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, "name,org_id,paths\n"+
+		"bravo,"+orgA+",alpha.delta.bravo\n"+
+		"charlie,"+orgA+",alpha.delta.bravo.charlie\n", got.stdout)
+	assert.Contains(t, got.stderr, "dry run")
+	after, err := os.ReadFile(data)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// flags may come before the arguments too
+	got = runCLI("mv", "--in-place", "--data", data, "bravo", "delta")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alpha.delta.bravo.charlie", folders[2].Paths)
+
+	got = runCLI("mv", "delta", "bravo", "--data", data, "--in-place")
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, folder.ErrMoveToDescendant.Error()+"\n", got.stderr)
+
+	got = runCLI("mv", "alpha", "echo", "--data", data, "--org", orgB)
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, folder.ErrFolderNotInOrg.Error()+"\n", got.stderr)
+}
+
+func Test_folderctl_Validate(t *testing.T) {
+	t.Parallel()
+	got := runCLI("validate", "--data", writeData(t, "folders.ndjson"))
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, "ok, 5 folders\n", got.stdout)
+
+	broken := filepath.Join(t.TempDir(), "broken.csv")
+	require.NoError(t, os.WriteFile(broken, []byte("name,org_id,paths\nbravo,"+orgA+",alpha.bravo\n"), 0o600))
+	got = runCLI("validate", "--data", broken)
+	assert.Equal(t, exitInvalid, got.code)
+	assert.Equal(t, `folder 0 ("bravo"): parent "alpha" does not exist in org `+orgA+"\n", got.stdout)
+}
+
+func Test_folderctl_ExportImport(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	data := writeData(t, "folders.json")
+	exported := filepath.Join(dir, "org_b.csv")
+
+	got := runCLI("export", "--data", data, "--org", orgB, "--format", "csv", "-o", exported)
+	assert.Equal(t, exitOK, got.code, got.stderr)
+
+	// importing the same folders again clashes with the names already there
+	got = runCLI("import", exported, "--data", data, "--in-place")
+	assert.Equal(t, exitInvalid, got.code)
+	assert.Contains(t, got.stdout, "name is already used")
+
+	// into an empty data set, moving them into another org on the way
+	target := filepath.Join(dir, "target.yaml")
+	require.NoError(t, os.WriteFile(target, nil, 0o600))
+	got = runCLI("import", exported, "--data", target, "--org", orgA, "--in-place")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+
+	folders, err := readFolders(target, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []folder.Folder{{Name: "echo", OrgId: uuid.FromStringOrNil(orgA), Paths: "echo"}}, folders)
+}
+
+func Test_folderctl_Diff(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+	changed := writeData(t, "changed.yaml")
+	got := runCLI("mv", "bravo", "delta", "--data", changed, "--in-place")
+	require.Equal(t, exitOK, got.code, got.stderr)
+
+	got = runCLI("diff", changed, "--data", data)
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, orgA+"\n  moved    alpha.bravo -> alpha.delta.bravo (+1 below)\n\n1 moved\n", got.stdout)
+
+	got = runCLI("diff", changed, "--data", data, "--format", "json")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Contains(t, got.stdout, `"kind": "moved"`)
+
+	got = runCLI("diff", changed, "--data", data, "--org", orgB)
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, "no changes\n", got.stdout)
+
+	got = runCLI("diff", changed, "--data", data, "--format", "csv")
+	assert.Equal(t, exitUsage, got.code)
+	assert.Contains(t, got.stderr, `unknown format "csv", want one of json|tree`)
+}
+
+func Test_folderctl_Patch(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+	changed := writeData(t, "changed.json")
+	got := runCLI("mv", "bravo", "delta", "--data", changed, "--in-place")
+	require.Equal(t, exitOK, got.code, got.stderr)
+
+	got = runCLI("diff", changed, "--data", data, "--patch")
+	require.Equal(t, exitOK, got.code, got.stderr)
+	patch := filepath.Join(t.TempDir(), "patch.json")
+	require.NoError(t, os.WriteFile(patch, []byte(got.stdout), 0o600))
+
+	got = runCLI("apply", patch, "--data", data, "--in-place")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Contains(t, got.stderr, "applied 1 of 1 operations")
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alpha.delta.bravo.charlie", folders[2].Paths)
+
+	// bravo isn't where the patch expects it any more
+	got = runCLI("apply", patch, "--data", data)
+	assert.Equal(t, exitError, got.code)
+	assert.Contains(t, got.stderr, "operation 0 (move bravo): "+folder.ErrPathChanged.Error())
+	assert.Contains(t, got.stderr, folder.ErrPatchConflict.Error())
+
+	got = runCLI("apply", patch, "--data", data, "--best-effort")
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Contains(t, got.stderr, "applied 0 of 1 operations")
+}
+
+func Test_folderctl_Generate(t *testing.T) {
+	t.Parallel()
+	args := []string{"generate", "--seed", "7", "--roots", "2", "--depth", "3", "--fanout", "2", "--org", orgA}
+	first, second := runCLI(args...), runCLI(args...)
+	assert.Equal(t, exitOK, first.code, first.stderr)
+	assert.Equal(t, first.stdout, second.stdout)
+
+	folders, err := folder.LoadFolders(strings.NewReader(first.stdout))
+	require.NoError(t, err)
+	assert.NotEmpty(t, folders)
+	assert.Equal(t, uuid.FromStringOrNil(orgA), folders[0].OrgId)
+	assert.Empty(t, folder.Validate(folders))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/server"
+)
+
+type serveOptions struct {
+	addr  string
+	store string
+}
+
+// serve runs the HTTP API until it gets an interrupt. Without --store moves
+// only live in memory.
+func (c *cli) serve(opts serveOptions) error {
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	driver := folder.NewDriver(folders)
+	if opts.store != "" {
+		store, err := folder.OpenFileStore(opts.store, folders, folder.FileStoreOptions{})
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if driver, err = folder.NewDriverWithStore(store); err != nil {
+			return err
+		}
+	}
+
+	api := server.New(driver, server.Options{})
+	srv := &http.Server{
+		Addr:              opts.addr,
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	// event streams never finish on their own
+	srv.RegisterOnShutdown(api.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(c.stderr, "listening on http://%s\n", opts.addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
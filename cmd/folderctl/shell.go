@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"golang.org/x/term"
+)
+
+const shellHelp = `commands:
+  orgs                  list the orgs in the data
+  org <id>              switch org, a unique prefix of the id is enough
+  pwd                   print the current org and folder
+  cd [folder|path|..]   change the current folder, no argument goes back to the org root
+  ls [folder]           list the folders directly below a folder
+  tree [folder] [depth] print the hierarchy below a folder
+  find <lquery>         list the paths in the org that match an lquery, e.g. *.bravo.*
+  mv <folder> <dst>     move a folder of the org and its subtree, "." is the current folder
+  undo                  revert the last mv in the org
+  redo                  make the last undone mv in the org again
+  history               list the commands run so far
+  save [file]           write the data back to the loaded file, or to file
+  exit                  leave the shell, also ctrl-d
+`
+
+var errUnsaved = errors.New("Error: there are unsaved changes, save them or exit again to drop them")
+
+// shell is the state of an interactive session
+type shell struct {
+	out io.Writer
+	// file the data was loaded from, empty for the sample data
+	file    string
+	folders []folder.Folder
+	driver  *folder.History
+
+	org uuid.UUID
+	// name of the current folder, empty at the org root. Names survive moves, paths don't
+	cwd string
+
+	history []string
+	dirty   bool
+	// set once exit was refused because of unsaved changes
+	warned bool
+	done   bool
+}
+
+func newShell(out io.Writer, file string, folders []folder.Folder, org uuid.UUID) *shell {
+	s := &shell{out: out, file: file, org: org}
+	s.setFolders(folders)
+	if s.org == uuid.Nil && len(folders) > 0 {
+		s.org = folders[0].OrgId
+	}
+	return s
+}
+
+// setFolders replaces the data, the driver gets its own copy as it moves folders in place
+func (s *shell) setFolders(folders []folder.Folder) {
+	s.folders = folders
+	// never fails for a driver from NewDriver
+	s.driver, _ = folder.NewHistory(folder.NewDriver(append([]folder.Folder{}, folders...)), folder.HistoryOptions{})
+}
+
+func (s *shell) prompt() string {
+	org := s.org.String()[:8]
+	if s.cwd == "" {
+		return org + ":/> "
+	}
+	f, _ := s.find(s.cwd)
+	return org + ":/" + f.Paths + "> "
+}
+
+// exec runs one command line
+func (s *shell) exec(line string) error {
+	args := strings.Fields(line)
+	if len(args) == 0 {
+		return nil
+	}
+	s.history = append(s.history, strings.TrimSpace(line))
+
+	cmd, args := args[0], args[1:]
+	if cmd != "exit" && cmd != "quit" {
+		s.warned = false
+	}
+	switch cmd {
+	case "help", "?":
+		fmt.Fprint(s.out, shellHelp)
+		return nil
+	case "orgs":
+		return s.orgs()
+	case "org":
+		if len(args) != 1 {
+			return usageErrorf("usage: org <id>")
+		}
+		return s.switchOrg(args[0])
+	case "pwd":
+		fmt.Fprintln(s.out, strings.TrimSuffix(s.prompt(), "> "))
+		return nil
+	case "cd":
+		if len(args) > 1 {
+			return usageErrorf("usage: cd [folder|path|..]")
+		}
+		return s.cd(args)
+	case "ls":
+		if len(args) > 1 {
+			return usageErrorf("usage: ls [folder]")
+		}
+		return s.ls(args)
+	case "tree":
+		return s.tree(args)
+	case "find":
+		if len(args) != 1 {
+			return usageErrorf("usage: find <lquery>")
+		}
+		return s.findPaths(args[0])
+	case "mv":
+		if len(args) != 2 {
+			return usageErrorf("usage: mv <folder> <dst>")
+		}
+		return s.mv(args[0], args[1])
+	case "undo":
+		return s.undoMove(s.driver.Undo, "undone")
+	case "redo":
+		return s.undoMove(s.driver.Redo, "redone")
+	case "history":
+		for i, line := range s.history {
+			fmt.Fprintf(s.out, "%4d  %s\n", i+1, line)
+		}
+		return nil
+	case "save":
+		if len(args) > 1 {
+			return usageErrorf("usage: save [file]")
+		}
+		return s.save(args)
+	case "exit", "quit":
+		return s.exit()
+	}
+	return usageErrorf("unknown command %q, try help", cmd)
+}
+
+// exit ends the session, the first time it is refused when there are unsaved changes
+func (s *shell) exit() error {
+	if s.dirty && !s.warned {
+		s.warned = true
+		return errUnsaved
+	}
+	s.done = true
+	return nil
+}
+
+func (s *shell) orgFolders() []folder.Folder {
+	return s.driver.GetFoldersByOrgID(s.org)
+}
+
+// find looks a folder up by name in the current org
+func (s *shell) find(name string) (folder.Folder, bool) {
+	for _, f := range s.orgFolders() {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return folder.Folder{}, false
+}
+
+// resolve turns a cd style argument into a folder, ok is false for the org root
+func (s *shell) resolve(arg string) (f folder.Folder, ok bool, err error) {
+	switch arg {
+	case "", "/":
+		return folder.Folder{}, false, nil
+	case ".":
+		if s.cwd == "" {
+			return folder.Folder{}, false, nil
+		}
+		f, _ = s.find(s.cwd)
+		return f, true, nil
+	case "..":
+		if s.cwd == "" {
+			return folder.Folder{}, false, nil
+		}
+		cwd, _ := s.find(s.cwd)
+		parent := parentPath(cwd.Paths)
+		if parent == "" {
+			return folder.Folder{}, false, nil
+		}
+		arg = parent
+	}
+
+	// a full path from the org root, or a name
+	arg = strings.TrimPrefix(arg, "/")
+	for _, f := range s.orgFolders() {
+		if f.Paths == arg || f.Name == arg {
+			return f, true, nil
+		}
+	}
+	if s.driver.CheckFolderExists(arg) {
+		return folder.Folder{}, false, folder.ErrFolderNotInOrg
+	}
+	return folder.Folder{}, false, folder.ErrFolderNotFound
+}
+
+// children returns the folders directly below path, the roots when path is empty
+func (s *shell) children(path string) []folder.Folder {
+	res := []folder.Folder{}
+	for _, f := range s.orgFolders() {
+		if parentPath(f.Paths) == path {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func (s *shell) orgs() error {
+	counts := map[uuid.UUID]int{}
+	orgs := []uuid.UUID{}
+	for _, f := range s.folders {
+		if counts[f.OrgId] == 0 {
+			orgs = append(orgs, f.OrgId)
+		}
+		counts[f.OrgId]++
+	}
+	for _, org := range orgs {
+		mark := " "
+		if org == s.org {
+			mark = "*"
+		}
+		fmt.Fprintf(s.out, "%s %s  %d folders\n", mark, org, counts[org])
+	}
+	return nil
+}
+
+func (s *shell) switchOrg(prefix string) error {
+	matches := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, f := range s.folders {
+		if !seen[f.OrgId] && strings.HasPrefix(f.OrgId.String(), prefix) {
+			matches = append(matches, f.OrgId)
+		}
+		seen[f.OrgId] = true
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("Error: no org starts with %q", prefix)
+	case 1:
+		s.org, s.cwd = matches[0], ""
+		return nil
+	}
+	return fmt.Errorf("Error: %q matches %d orgs", prefix, len(matches))
+}
+
+func (s *shell) cd(args []string) error {
+	arg := ""
+	if len(args) == 1 {
+		arg = args[0]
+	}
+	f, ok, err := s.resolve(arg)
+	if err != nil {
+		return err
+	}
+	s.cwd = ""
+	if ok {
+		s.cwd = f.Name
+	}
+	return nil
+}
+
+func (s *shell) ls(args []string) error {
+	arg := "."
+	if len(args) == 1 {
+		arg = args[0]
+	}
+	f, _, err := s.resolve(arg)
+	if err != nil {
+		return err
+	}
+
+	// folders with children get a trailing /, like ls -F
+	children := s.children(f.Paths)
+	hasChildren := map[string]bool{}
+	for _, c := range s.orgFolders() {
+		hasChildren[parentPath(c.Paths)] = true
+	}
+	for _, c := range children {
+		if hasChildren[c.Paths] {
+			fmt.Fprintln(s.out, c.Name+"/")
+		} else {
+			fmt.Fprintln(s.out, c.Name)
+		}
+	}
+	return nil
+}
+
+func (s *shell) tree(args []string) error {
+	opts := folder.TreeOptions{ShowCount: true}
+	arg := "."
+	for _, a := range args {
+		if depth, err := strconv.Atoi(a); err == nil {
+			opts.MaxDepth = depth
+		} else {
+			arg = a
+		}
+	}
+	f, ok, err := s.resolve(arg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return folder.WriteTree(s.out, s.orgFolders(), opts)
+	}
+	children, err := s.driver.GetAllChildFolders(s.org, f.Name)
+	if err != nil {
+		return err
+	}
+	return folder.WriteTree(s.out, append([]folder.Folder{f}, children...), opts)
+}
+
+func (s *shell) findPaths(pattern string) error {
+	q, err := folder.ParseLQuery(pattern)
+	if err != nil {
+		return err
+	}
+	for _, f := range q.Filter(s.orgFolders()) {
+		fmt.Fprintln(s.out, f.Paths)
+	}
+	return nil
+}
+
+// mv moves a folder of the current org below another one, both given like cd's argument
+func (s *shell) mv(name, dst string) error {
+	src, ok, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return usageErrorf("can't move the org root")
+	}
+	to, ok, err := s.resolve(dst)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return usageErrorf("can't move to the org root")
+	}
+
+	before := s.folders
+	after, err := s.driver.MoveFolder(src.Name, to.Name)
+	if err != nil {
+		return err
+	}
+	s.folders = append([]folder.Folder{}, after...)
+	s.dirty = true
+
+	fmt.Fprintf(s.out, "moved %d folders\n", len(folder.HighlightMoved(before, s.folders)))
+	return nil
+}
+
+// undoMove runs Undo or Redo of the history for the current org
+func (s *shell) undoMove(op func(uuid.UUID) ([]folder.Folder, error), done string) error {
+	after, err := op(s.org)
+	if err != nil {
+		return err
+	}
+	s.folders = append([]folder.Folder{}, after...)
+	s.dirty = true
+	if _, ok := s.find(s.cwd); !ok {
+		s.cwd = ""
+	}
+	fmt.Fprintln(s.out, done)
+	return nil
+}
+
+func (s *shell) save(args []string) error {
+	path := s.file
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" || path == "-" {
+		return usageErrorf("the data wasn't loaded from a file, use save <file>")
+	}
+	if err := writeFolders(path, s.folders); err != nil {
+		return err
+	}
+	if path == s.file {
+		s.dirty = false
+	}
+	fmt.Fprintf(s.out, "saved %d folders to %s\n", len(s.folders), path)
+	return nil
+}
+
+var shellCommands = []string{"cd", "exit", "find", "help", "history", "ls", "mv", "org", "orgs", "pwd", "redo", "save", "tree", "undo"}
+
+// complete completes the word before pos: a command name for the first word,
+// otherwise an org id for org and a folder name or path for everything else.
+// The word is only extended by the prefix all candidates share.
+func (s *shell) complete(line string, pos int) (string, int, bool) {
+	start := strings.LastIndexByte(line[:pos], ' ') + 1
+	word := line[start:pos]
+
+	candidates := []string{}
+	fields := strings.Fields(line[:start])
+	switch {
+	case len(fields) == 0:
+		candidates = shellCommands
+	case fields[0] == "org":
+		seen := map[uuid.UUID]bool{}
+		for _, f := range s.folders {
+			if !seen[f.OrgId] {
+				seen[f.OrgId] = true
+				candidates = append(candidates, f.OrgId.String())
+			}
+		}
+	case fields[0] == "save":
+		return line, pos, false
+	default:
+		// a word with a "." in it is a path, anything else a name
+		for _, f := range s.orgFolders() {
+			if strings.Contains(word, ".") {
+				candidates = append(candidates, f.Paths)
+			} else {
+				candidates = append(candidates, f.Name)
+			}
+		}
+	}
+
+	matches := []string{}
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			matches = append(matches, c)
+		}
+	}
+	if len(matches) == 0 {
+		return line, pos, false
+	}
+	sort.Strings(matches)
+
+	completed := commonPrefix(matches)
+	if len(matches) == 1 {
+		completed += " "
+	}
+	if completed == word {
+		return line, pos, false
+	}
+	return line[:start] + completed + line[pos:], start + len(completed), true
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// runShell reads commands from a terminal with line editing, completion and
+// history, or one per line from any other input such as a script
+func (c *cli) runShell(s *shell) error {
+	if in, ok := c.stdin.(*os.File); ok && term.IsTerminal(int(in.Fd())) {
+		return c.runTerminal(s, in)
+	}
+
+	failed := 0
+	scanner := bufio.NewScanner(c.stdin)
+	for !s.done && scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if err := s.exec(line); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// the input ended without exit, which can't be repeated to drop the changes
+	if !s.done {
+		if err := s.exit(); err != nil {
+			fmt.Fprintln(c.stderr, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("Error: %d commands failed", failed)
+	}
+	return nil
+}
+
+func (c *cli) runTerminal(s *shell, in *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, c.stdout}, s.prompt())
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return s.complete(line, pos)
+	}
+	s.out = t
+	fmt.Fprintln(t, "type help for the commands, tab completes folder names")
+
+	for !s.done {
+		t.SetPrompt(s.prompt())
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			// ctrl-d, like exit
+			if err := s.exit(); err != nil {
+				fmt.Fprintln(t, err)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.exec(line); err != nil {
+			fmt.Fprintln(t, err)
+		}
+	}
+	return nil
+}
+
+func (c *cli) shell(args []string) error {
+	orgID, _, err := c.orgID()
+	if err != nil {
+		return err
+	}
+	folders, err := c.load()
+	if err != nil {
+		return err
+	}
+	return c.runShell(newShell(c.stdout, c.data, folders, orgID))
+}
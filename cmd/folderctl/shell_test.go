@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runShellScript(t *testing.T, data string, script string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"shell", "--data", data}, strings.NewReader(script), &stdout, &stderr)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func Test_folderctl_Shell(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	got := runShellScript(t, data, `
+# comments and blank lines are skipped
+pwd
+ls
+cd bravo
+pwd
+ls
+cd ..
+ls
+find *.charlie
+mv bravo delta
+cd charlie
+pwd
+undo
+pwd
+tree alpha 1
+save
+exit
+`)
+	assert.Equal(t, exitOK, got.code, got.stderr)
+	assert.Equal(t, "38b9879b:/\n"+
+		"alpha/\n"+
+		"38b9879b:/alpha.bravo\n"+
+		"charlie\n"+
+		"bravo/\n"+
+		"delta\n"+
+		"alpha.bravo.charlie\n"+
+		"moved 2 folders\n"+
+		"38b9879b:/alpha.delta.bravo.charlie\n"+
+		"undone\n"+
+		"38b9879b:/alpha.bravo.charlie\n"+
+		orgA+"\n"+
+		"└── alpha (2)\n"+
+		"saved 5 folders to "+data+"\n", got.stdout)
+
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testFolders(), folders)
+}
+
+func Test_folderctl_Shell_Errors(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	got := runShellScript(t, data, `
+cd echo
+org c15
+mv echo .
+find a..b
+nope
+mv alpha echo
+undo
+redo
+exit
+exit
+`)
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, "Error: Folder does not exist in the specified organization\n"+
+		"Error: can't move to the org root\n"+
+		"Error: Invalid lquery: item 2 \"\": empty label\n"+
+		"Error: unknown command \"nope\", try help\n"+
+		// alpha is in the other org
+		"Error: Folder does not exist in the specified organization\n"+
+		"Error: Nothing to undo\n"+
+		"Error: Nothing to redo\n"+
+		"Error: 7 commands failed\n", got.stderr)
+}
+
+func Test_folderctl_Shell_UndoPerOrg(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	got := runShellScript(t, data, `
+mv charlie delta
+org c15
+undo
+org 38b
+undo
+tree alpha
+redo
+tree alpha
+save
+exit
+`)
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, "Error: Nothing to undo\nError: 1 commands failed\n", got.stderr, "org B has nothing to undo")
+	assert.Equal(t, "moved 1 folders\n"+
+		"undone\n"+
+		orgA+"\n"+
+		"└── alpha (2)\n"+
+		"    ├── bravo (1)\n"+
+		"    │   └── charlie\n"+
+		"    └── delta\n"+
+		"redone\n"+
+		orgA+"\n"+
+		"└── alpha (2)\n"+
+		"    ├── bravo\n"+
+		"    └── delta (1)\n"+
+		"        └── charlie\n"+
+		"saved 5 folders to "+data+"\n", got.stdout)
+
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "alpha.delta.charlie", folders[2].Paths)
+}
+
+func Test_folderctl_Shell_UnsavedChanges(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	// the first exit is refused, the second one drops the changes
+	got := runShellScript(t, data, "mv charlie delta\nexit\nexit\nls\n")
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, errUnsaved.Error()+"\nError: 1 commands failed\n", got.stderr)
+	assert.Equal(t, "moved 1 folders\n", got.stdout, "nothing runs after exit")
+
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testFolders(), folders)
+
+	// the end of the input is an exit too, the changes are dropped but not silently
+	got = runShellScript(t, data, "mv charlie delta\n")
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, errUnsaved.Error()+"\nError: 1 commands failed\n", got.stderr)
+	folders, err = readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testFolders(), folders)
+}
+
+func Test_folderctl_Shell_MoveInOrg(t *testing.T) {
+	t.Parallel()
+	data := writeData(t, "folders.json")
+
+	got := runShellScript(t, data, `
+mv charlie echo
+org c15
+mv bravo .
+mv echo alpha
+org 38b
+cd delta
+mv charlie .
+mv charlie alpha.bravo
+save
+exit
+`)
+	assert.Equal(t, exitError, got.code)
+	assert.Equal(t, "Error: Folder does not exist in the specified organization\n"+
+		"Error: Folder does not exist in the specified organization\n"+
+		"Error: Folder does not exist in the specified organization\n"+
+		"Error: 3 commands failed\n", got.stderr, "only folders of the current org move")
+	assert.Equal(t, "moved 1 folders\nmoved 1 folders\nsaved 5 folders to "+data+"\n", got.stdout)
+
+	folders, err := readFolders(data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, testFolders(), folders)
+}
+
+func Test_folderctl_Shell_Complete(t *testing.T) {
+	t.Parallel()
+	folders := testFolders()
+	folders = append(folders, folders[0])
+	folders[len(folders)-1].Name, folders[len(folders)-1].Paths = "alphabet", "alphabet"
+	s := newShell(&bytes.Buffer{}, "", folders, uuid.Nil)
+
+	tests := [...]struct {
+		line    string
+		want    string
+		changed bool
+	}{
+		{line: "tr", want: "tree ", changed: true},
+		{line: "h", want: "h", changed: false},
+		{line: "cd ch", want: "cd charlie ", changed: true},
+		{line: "cd al", want: "cd alpha", changed: true},
+		{line: "cd alpha", want: "cd alpha", changed: false},
+		{line: "cd alpha.b", want: "cd alpha.bravo", changed: true},
+		{line: "cd ec", want: "cd ec", changed: false},
+		{line: "org c", want: "org " + orgB + " ", changed: true},
+		{line: "mv bravo d", want: "mv bravo delta ", changed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			t.Parallel()
+			got, pos, ok := s.complete(tt.line, len(tt.line))
+			assert.Equal(t, tt.changed, ok)
+			if ok {
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, len(tt.want), pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Package audit records who changed the folder tree, what changed and when.
+//
+// Wrap a driver and move folders through the wrapper. Every move, including
+// the ones that fail, becomes an Entry handed to a Sink:
+//
+//	log := audit.NewMemorySink()
+//	d := audit.Wrap(driver, log, audit.Options{})
+//	d.As("jane@example.com").MoveFolder("bravo", "delta")
+//	entries, _ := log.Query(audit.Query{OrgID: orgID, Folder: "bravo"})
+//
+// The old and new path of every moved folder come from the change events of
+// drivers that implement folder.Notifier. For other drivers entries of
+// successful moves only have the new paths.
+package audit
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// Outcome is whether the audited change was made.
+type Outcome string
+
+const (
+	Succeeded Outcome = "succeeded"
+	Failed    Outcome = "failed"
+)
+
+// Entry is one attempt to change the tree.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// who made the change, see Driver.As
+	Actor string `json:"actor"`
+	// uuid.Nil when a failed change names no folder the driver knows the org of
+	OrgID uuid.UUID `json:"org_id"`
+	// one of the folder mutation ops, e.g. folder.OpMove
+	Op string `json:"op"`
+	// the arguments of the driver method, e.g. name and dst for a move
+	Args []string `json:"args"`
+	// every affected folder ordered by path with its path before and after, empty when the change failed
+	Changes []folder.Change `json:"changes"`
+	Outcome Outcome         `json:"outcome"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// Sink stores entries. Write is called once per entry, in the order the changes were made.
+type Sink interface {
+	Write(e Entry) error
+}
+
+// Query selects entries, zero fields match everything.
+type Query struct {
+	OrgID uuid.UUID
+	// entries whose arguments or changes name this folder
+	Folder string
+	// entries at or after Since and before Until
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether e is selected by q.
+func (q Query) Match(e Entry) bool {
+	if q.OrgID != uuid.Nil && e.OrgID != q.OrgID {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	if q.Folder == "" {
+		return true
+	}
+	for _, arg := range e.Args {
+		if arg == q.Folder {
+			return true
+		}
+	}
+	for _, c := range e.Changes {
+		if c.Name == q.Folder || c.OldName == q.Folder {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the entries selected by q.
+func (q Query) Filter(entries []Entry) []Entry {
+	res := []Entry{}
+	for _, e := range entries {
+		if q.Match(e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+type Options struct {
+	// the actor of changes made without As
+	Actor string
+	// logs entries the sink failed to write, log.Default() when nil
+	ErrorLog *log.Logger
+	// stamps the entries, time.Now when nil
+	Now func() time.Time
+}
+
+// Driver is a folder.IDriver that audits the changes made through it. Reads
+// go straight to the wrapped driver.
+type Driver struct {
+	folder.IDriver
+	sink  Sink
+	opts  Options
+	actor string
+}
+
+// orgLookup is implemented by the drivers of package folder
+type orgLookup interface {
+	GetFolderOrgID(name string) uuid.UUID
+}
+
+// Wrap returns a driver that writes an Entry to sink for every change made through it.
+func Wrap(d folder.IDriver, sink Sink, opts Options) *Driver {
+	if opts.ErrorLog == nil {
+		opts.ErrorLog = log.Default()
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Driver{IDriver: d, sink: sink, opts: opts, actor: opts.Actor}
+}
+
+// As returns a driver sharing d's sink that records actor for its changes.
+func (d *Driver) As(actor string) *Driver {
+	as := *d
+	as.actor = actor
+	return &as
+}
+
+// Subscribe subscribes to the events of the wrapped driver, so the SSE stream
+// and webhooks keep working through an audited driver. When the wrapped driver
+// doesn't publish any the subscription is closed right away.
+func (d *Driver) Subscribe(opts folder.SubscribeOptions) *folder.Subscription {
+	if n, ok := d.IDriver.(folder.Notifier); ok {
+		return n.Subscribe(opts)
+	}
+	sub := folder.NewEventBus(folder.EventBusOptions{}).Subscribe(opts)
+	sub.Close()
+	return sub
+}
+
+// MoveFolder moves the folder like the wrapped driver and audits the attempt.
+// A sink error is logged and doesn't fail the move, which has already happened.
+func (d *Driver) MoveFolder(name string, dst string) ([]folder.Folder, error) {
+	e := Entry{Time: d.opts.Now(), Actor: d.actor, Op: folder.OpMove, Args: []string{name, dst}}
+	if l, ok := d.IDriver.(orgLookup); ok {
+		e.OrgID = l.GetFolderOrgID(name)
+	}
+	var sub *folder.Subscription
+	if n, ok := d.IDriver.(folder.Notifier); ok {
+		sub = n.Subscribe(folder.SubscribeOptions{OrgID: e.OrgID})
+	}
+
+	folders, err := d.IDriver.MoveFolder(name, dst)
+	if sub != nil {
+		sub.Close()
+		// events are published before MoveFolder returns
+		for event := range sub.C {
+			if event.Type == folder.FolderMoved && event.Name == name {
+				e.OrgID, e.Changes = event.OrgID, event.Changes
+			}
+		}
+	}
+
+	switch {
+	case err != nil:
+		e.Outcome, e.Error = Failed, err.Error()
+	case sub == nil:
+		e.Outcome = Succeeded
+		e.OrgID, e.Changes = movedFolders(folders, name)
+	default:
+		e.Outcome = Succeeded
+	}
+	d.write(e)
+	return folders, err
+}
+
+func (d *Driver) write(e Entry) {
+	if e.Changes == nil {
+		e.Changes = []folder.Change{}
+	}
+	if err := d.sink.Write(e); err != nil {
+		d.opts.ErrorLog.Printf("audit: %s of %v by %q not recorded: %v", e.Op, e.Args, e.Actor, err)
+	}
+}
+
+// movedFolders finds the folder called name and its descendants in the result of a move
+func movedFolders(folders []folder.Folder, name string) (uuid.UUID, []folder.Change) {
+	var root folder.Folder
+	for _, f := range folders {
+		if f.Name == name {
+			root = f
+			break
+		}
+	}
+	changes := []folder.Change{}
+	for _, f := range folders {
+		if f.OrgId == root.OrgId && (f.Name == name || folder.IsChildFolder(f, root.Paths)) {
+			changes = append(changes, folder.Change{Name: f.Name, NewPath: f.Paths})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].NewPath < changes[j].NewPath })
+	return root.OrgId, changes
+}
@@ -0,0 +1,258 @@
+package audit_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/audit"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// clock returns a Now that starts at start and moves a minute per call
+func clock() func() time.Time {
+	now := start.Add(-time.Minute)
+	return func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+}
+
+func Test_audit_MoveFolder(t *testing.T) {
+	t.Parallel()
+	sink := audit.NewMemorySink()
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{Actor: "system", Now: clock()})
+
+	_, err := d.As("jane").MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("bravo", "foxtrot")
+	assert.ErrorIs(t, err, folder.ErrMoveToOtherOrg)
+	_, err = d.As("joe").MoveFolder("nope", "delta")
+	assert.ErrorIs(t, err, folder.ErrSourceNotFound)
+
+	entries, err := sink.Query(audit.Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []audit.Entry{
+		{
+			Time: start, Actor: "jane", OrgID: foldertest.OrgA, Op: folder.OpMove, Args: []string{"bravo", "delta"},
+			Changes: []folder.Change{
+				{Name: "bravo", OldPath: "alpha.bravo", NewPath: "alpha.delta.bravo"},
+				{Name: "charlie", OldPath: "alpha.bravo.charlie", NewPath: "alpha.delta.bravo.charlie"},
+			},
+			Outcome: audit.Succeeded,
+		},
+		{
+			Time: start.Add(time.Minute), Actor: "system", OrgID: foldertest.OrgA, Op: folder.OpMove, Args: []string{"bravo", "foxtrot"},
+			Changes: []folder.Change{}, Outcome: audit.Failed, Error: folder.ErrMoveToOtherOrg.Error(),
+		},
+		{
+			Time: start.Add(2 * time.Minute), Actor: "joe", Op: folder.OpMove, Args: []string{"nope", "delta"},
+			Changes: []folder.Change{}, Outcome: audit.Failed, Error: folder.ErrSourceNotFound.Error(),
+		},
+	}, entries)
+}
+
+func Test_audit_WithoutEvents(t *testing.T) {
+	t.Parallel()
+	sink := audit.NewMemorySink()
+	// hides the events and the org lookup of the driver
+	plain := struct{ folder.IDriver }{folder.NewDriver(foldertest.MoveData())}
+	d := audit.Wrap(plain, sink, audit.Options{})
+
+	_, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	entries, err := sink.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, foldertest.OrgA, entries[0].OrgID)
+	assert.Equal(t, []folder.Change{
+		{Name: "bravo", NewPath: "golf.bravo"},
+		{Name: "charlie", NewPath: "golf.bravo.charlie"},
+	}, entries[0].Changes)
+}
+
+func Test_audit_Query(t *testing.T) {
+	t.Parallel()
+	sink := audit.NewMemorySink()
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{Now: clock()})
+	moves := [][2]string{{"bravo", "delta"}, {"foxtrot", "golf"}, {"echo", "golf"}, {"delta", "golf"}}
+	for _, m := range moves {
+		_, _ = d.MoveFolder(m[0], m[1])
+	}
+
+	// the Args of each selected entry
+	tests := [...]struct {
+		name  string
+		query audit.Query
+		want  []string
+	}{
+		{name: "everything", query: audit.Query{}, want: []string{"bravo delta", "foxtrot golf", "echo golf", "delta golf"}},
+		{name: "org", query: audit.Query{OrgID: foldertest.OrgB}, want: []string{"foxtrot golf"}},
+		{name: "argument", query: audit.Query{Folder: "echo"}, want: []string{"echo golf"}},
+		// charlie moved along with bravo and delta
+		{name: "moved with a parent", query: audit.Query{Folder: "charlie"}, want: []string{"bravo delta", "delta golf"}},
+		{name: "since", query: audit.Query{Since: start.Add(2 * time.Minute)}, want: []string{"echo golf", "delta golf"}},
+		{name: "until", query: audit.Query{Until: start.Add(2 * time.Minute)}, want: []string{"bravo delta", "foxtrot golf"}},
+		{name: "all fields", query: audit.Query{OrgID: foldertest.OrgA, Folder: "golf", Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, want: []string{"echo golf"}},
+		{name: "unknown folder", query: audit.Query{Folder: "zulu"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			entries, err := sink.Query(tt.query)
+			require.NoError(t, err)
+			got := []string{}
+			for _, e := range entries {
+				got = append(got, strings.Join(e.Args, " "))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_audit_FileSink(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	sink, err := audit.OpenFileSink(path)
+	require.NoError(t, err)
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{Now: clock()})
+	_, err = d.As("jane").MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("bravo", "bravo")
+	require.Error(t, err)
+	require.NoError(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{
+		"time": "2024-03-01T09:00:00Z", "actor": "jane", "org_id": "`+foldertest.OrgA.String()+`",
+		"op": "move", "args": ["bravo", "delta"], "outcome": "succeeded",
+		"changes": [
+			{"name": "bravo", "old_path": "alpha.bravo", "new_path": "alpha.delta.bravo"},
+			{"name": "charlie", "old_path": "alpha.bravo.charlie", "new_path": "alpha.delta.bravo.charlie"}
+		]
+	}`, lines[0])
+
+	// entries are appended after reopening, a torn last line is skipped
+	sink, err = audit.OpenFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+	d = audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{})
+	_, err = d.MoveFolder("golf", "alpha")
+	require.NoError(t, err)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2024-03-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err := sink.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, audit.Failed, entries[1].Outcome)
+	assert.Equal(t, []string{"golf", "alpha"}, entries[2].Args)
+	entries, err = sink.Query(audit.Query{Folder: "bravo", Until: start.Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_audit_FileSinkTornTail(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+	sink, err := audit.OpenFileSink(path)
+	require.NoError(t, err)
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{Now: clock()})
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	// a crash in the middle of the second entry
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2024-03-`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// the entries written after reopening don't end up on the torn line
+	sink, err = audit.OpenFileSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+	d = audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{Now: clock()})
+	_, err = d.MoveFolder("echo", "golf")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("golf", "alpha")
+	require.NoError(t, err)
+
+	entries, err := sink.Query(audit.Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"bravo", "delta"}, entries[0].Args)
+	assert.Equal(t, []string{"echo", "golf"}, entries[1].Args)
+	assert.Equal(t, []string{"golf", "alpha"}, entries[2].Args)
+}
+
+func Test_audit_ReadEntriesCorrupt(t *testing.T) {
+	t.Parallel()
+	_, err := audit.ReadEntries(strings.NewReader("{\"op\":\"move\"}\nnot json\n{\"op\":\"move\"}\n"))
+	assert.ErrorIs(t, err, audit.ErrCorruptLog)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func Test_audit_SinkFunc(t *testing.T) {
+	t.Parallel()
+	var got []audit.Entry
+	var logged bytes.Buffer
+	sink := audit.SinkFunc(func(e audit.Entry) error {
+		got = append(got, e)
+		if e.Outcome == audit.Failed {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), sink, audit.Options{ErrorLog: log.New(&logged, "", 0)})
+
+	_, err := d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	// the sink error doesn't replace the move's
+	_, err = d.As("jane").MoveFolder("bravo", "foxtrot")
+	assert.ErrorIs(t, err, folder.ErrMoveToOtherOrg)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "audit: move of [bravo foxtrot] by \"jane\" not recorded: unavailable\n", logged.String())
+}
+
+func Test_audit_DriverSuite(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		return audit.Wrap(folder.NewDriver(folders), audit.NewMemorySink(), audit.Options{})
+	})
+}
+
+func Test_audit_Subscribe(t *testing.T) {
+	t.Parallel()
+	d := audit.Wrap(folder.NewDriver(foldertest.MoveData()), audit.NewMemorySink(), audit.Options{})
+	sub := d.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA})
+	defer sub.Close()
+
+	_, err := d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	e := <-sub.C
+	assert.Equal(t, folder.FolderMoved, e.Type)
+	assert.Equal(t, "bravo", e.Name)
+
+	// without events the subscription ends right away
+	plain := audit.Wrap(struct{ folder.IDriver }{folder.NewDriver(foldertest.MoveData())}, audit.NewMemorySink(), audit.Options{})
+	_, ok := <-plain.Subscribe(folder.SubscribeOptions{}).C
+	assert.False(t, ok)
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+)
+
+var ErrCorruptLog = errors.New("Error: Audit log is corrupt")
+
+// MemorySink keeps entries in memory, it is safe for concurrent use.
+type MemorySink struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Write(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// Query returns the entries selected by q, oldest first.
+func (s *MemorySink) Query(q Query) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return q.Filter(s.entries), nil
+}
+
+// FileSink appends entries to a file as JSON lines, one per entry, and
+// fsyncs every line before Write returns. It is safe for concurrent use.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileSink opens the log at path, creating it when it doesn't exist. A
+// torn final line, left by a crash while writing, is cut off so the next entry
+// starts on a line of its own.
+func OpenFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, folder.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err == nil && len(data) > 0 && data[len(data)-1] != '\n' {
+		if err = f.Truncate(int64(bytes.LastIndexByte(data, '\n') + 1)); err == nil {
+			err = f.Sync()
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileSink{file: f}, nil
+}
+
+func (s *FileSink) Write(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Query reads the log back and returns the entries selected by q, oldest first.
+func (s *FileSink) Query(q Query) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := ReadEntries(io.NewSectionReader(s.file, 0, 1<<62))
+	if err != nil {
+		return nil, err
+	}
+	return q.Filter(entries), nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// ReadEntries reads a log written by a FileSink. A torn final line, left by a
+// crash while writing, is skipped.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := []Entry{}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			if i == len(lines)-1 {
+				// not terminated by a newline, so it was never fully written
+				break
+			}
+			return nil, fmt.Errorf("%w: line %d: %v", ErrCorruptLog, i+1, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// SinkFunc is a Sink calling a function for every entry, e.g. to forward them
+// to another system.
+type SinkFunc func(e Entry) error
+
+func (f SinkFunc) Write(e Entry) error {
+	return f(e)
+}
@@ -0,0 +1,206 @@
+package folder_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// Benchmarks for the driver on different tree shapes and sizes. The baseline
+// lives in testdata/bench/baseline.txt, to check a change against it:
+//
+//	go test ./folder -run '^$' -bench . -benchmem > new.txt
+//	benchstat folder/testdata/bench/baseline.txt new.txt
+//
+// -short skips the 1M folder sizes.
+
+var benchSizes = []int{1_000, 10_000, 100_000, 1_000_000}
+
+var (
+	benchOrg   = uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	benchOther = uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")
+)
+
+// benchTree is a generated tree plus the folders the benchmarks operate on
+type benchTree struct {
+	folders []folder.Folder
+	// first root in benchOrg, its first child and a leaf
+	root, child, leaf string
+}
+
+// buildBenchTree makes about n folders of the given shape in benchOrg plus a
+// small tree in benchOther. Names are short so the deep shapes stay in memory.
+//
+//	wide      one root with every other folder as its child
+//	deep      chains 32 levels deep
+//	balanced  every folder has 8 children
+//	skewed    Zipf fan-out, most folders are leaves and a few have hundreds of children
+func buildBenchTree(shape string, n int) benchTree {
+	folders := make([]folder.Folder, 0, n+100)
+	next := 0
+	add := func(orgID uuid.UUID, parent string) string {
+		name := "f" + strconv.FormatInt(int64(next), 36)
+		next++
+		path := name
+		if parent != "" {
+			path = parent + "." + name
+		}
+		folders = append(folders, folder.Folder{Name: name, OrgId: orgID, Paths: path})
+		return path
+	}
+
+	switch shape {
+	case "wide":
+		root := add(benchOrg, "")
+		for len(folders) < n {
+			add(benchOrg, root)
+		}
+	case "deep":
+		for len(folders) < n {
+			path := ""
+			for depth := 0; depth < 32 && len(folders) < n; depth++ {
+				path = add(benchOrg, path)
+			}
+		}
+	case "balanced":
+		// breadth first so the tree fills up level by level
+		queue := []string{add(benchOrg, "")}
+		for len(folders) < n {
+			parent := queue[0]
+			queue = queue[1:]
+			for i := 0; i < 8 && len(folders) < n; i++ {
+				queue = append(queue, add(benchOrg, parent))
+			}
+		}
+	case "skewed":
+		rng := rand.New(rand.NewSource(1))
+		fanOut := folder.NewZipf(1.1, 500)
+		var grow func(parent string, depth int)
+		grow = func(parent string, depth int) {
+			if depth >= 12 {
+				return
+			}
+			for i := fanOut.Sample(rng); i > 0 && len(folders) < n; i-- {
+				grow(add(benchOrg, parent), depth+1)
+			}
+		}
+		for len(folders) < n {
+			root := add(benchOrg, "")
+			// make sure every root has at least one child to act on
+			grow(add(benchOrg, root), 2)
+			grow(root, 1)
+		}
+	default:
+		panic("unknown shape " + shape)
+	}
+
+	// the last folder added for benchOrg never has children, so it can be moved
+	// below the first root or its first child without ever being their ancestor
+	tree := benchTree{root: folders[0].Name, child: folders[1].Name, leaf: folders[len(folders)-1].Name}
+
+	parent := ""
+	for i := 0; i < 100; i++ {
+		if i%10 == 0 {
+			parent = ""
+		}
+		parent = add(benchOther, parent)
+	}
+	tree.folders = folders
+	return tree
+}
+
+// runShapes runs fn for every shape and size, fn resets the timer after its own setup
+func runShapes(b *testing.B, fn func(b *testing.B, tree benchTree)) {
+	for _, shape := range []string{"wide", "deep", "balanced", "skewed"} {
+		for _, n := range benchSizes {
+			b.Run(fmt.Sprintf("%s/%d", shape, n), func(b *testing.B) {
+				if n >= 1_000_000 && testing.Short() {
+					b.Skip("skipping 1M folders in short mode")
+				}
+				b.ReportAllocs()
+				fn(b, buildBenchTree(shape, n))
+			})
+		}
+	}
+}
+
+func BenchmarkGetFoldersByOrgID(b *testing.B) {
+	runShapes(b, func(b *testing.B, tree benchTree) {
+		d := folder.NewDriver(tree.folders)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			d.GetFoldersByOrgID(benchOrg)
+		}
+	})
+}
+
+func BenchmarkGetAllChildFolders(b *testing.B) {
+	runShapes(b, func(b *testing.B, tree benchTree) {
+		d := folder.NewDriver(tree.folders)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.GetAllChildFolders(benchOrg, tree.root); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMoveFolder(b *testing.B) {
+	runShapes(b, func(b *testing.B, tree benchTree) {
+		d := folder.NewDriver(tree.folders)
+		// bounce a leaf between two folders so every iteration is a real move
+		dsts := [2]string{tree.root, tree.child}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.MoveFolder(tree.leaf, dsts[i%2]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCheckFolderExists(b *testing.B) {
+	runShapes(b, func(b *testing.B, tree benchTree) {
+		d := folder.NewDriver(tree.folders).(interface {
+			CheckFolderExists(name string) bool
+			CheckFolderExistsWithinOrg(orgID uuid.UUID, name string) bool
+		})
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if !d.CheckFolderExists(tree.leaf) || d.CheckFolderExistsWithinOrg(benchOther, tree.leaf) {
+				b.Fatal("unexpected existence result")
+			}
+		}
+	})
+}
+
+func BenchmarkLoadDriver(b *testing.B) {
+	for _, format := range []struct {
+		name   string
+		format folder.Format
+	}{{"json", folder.FormatJSON}, {"ndjson", folder.FormatNDJSON}} {
+		b.Run(format.name, func(b *testing.B) {
+			runShapes(b, func(b *testing.B, tree benchTree) {
+				var buf bytes.Buffer
+				if err := folder.EncodeFolders(&buf, format.format, tree.folders); err != nil {
+					b.Fatal(err)
+				}
+				data := buf.Bytes()
+				b.SetBytes(int64(len(data)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, err := folder.LoadDriver(bytes.NewReader(data), folder.StreamOptions{}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package folder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// DiffKind is how a folder changed between two folder sets.
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	// same name, different parent
+	DiffMoved DiffKind = "moved"
+	// same parent and children, different name
+	DiffRenamed DiffKind = "renamed"
+	// same name, different org
+	DiffOrgChanged DiffKind = "org_changed"
+)
+
+// DiffEntry is one change found by Diff.
+type DiffEntry struct {
+	Kind DiffKind `json:"kind"`
+	// the name after the change, before it for a removed folder
+	Name string `json:"name"`
+	// the name before a rename, empty otherwise
+	OldName string `json:"old_name,omitempty"`
+	// the org after the change, before it for a removed folder
+	OrgID uuid.UUID `json:"org_id"`
+	// the org before an org change, uuid.Nil otherwise
+	OldOrgID uuid.UUID `json:"old_org_id"`
+	// empty for an added folder
+	OldPath string `json:"old_path,omitempty"`
+	// empty for a removed folder
+	NewPath string `json:"new_path,omitempty"`
+	// folders below this one that changed with it and have no entry of their own
+	Descendants int `json:"descendants,omitempty"`
+}
+
+// Diff compares two folder sets, e.g. two dumps of the same customer, and
+// returns their differences ordered by org and path. Folders are matched by
+// name, names being unique like the driver expects. A folder that is gone
+// from the same place a new one with the same children appeared is renamed,
+// as is a leaf when it is the only one gone and the only one new below a
+// parent. Subtrees are collapsed: moving, adding, removing or changing the
+// org of a folder gives a single entry for the folder, with Descendants
+// counting the folders below it that came along.
+func Diff(before, after []Folder) []DiffEntry {
+	old, cur := foldersByName(before), foldersByName(after)
+
+	removed := map[string]bool{}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			removed[name] = true
+		}
+	}
+	added := map[string]bool{}
+	for name := range cur {
+		if _, ok := old[name]; !ok {
+			added[name] = true
+		}
+	}
+
+	// old name to new name
+	renamed := findRenames(old, cur, removed, added)
+	newName := func(name string) string {
+		if n, ok := renamed[name]; ok {
+			return n
+		}
+		return name
+	}
+	for oldName, name := range renamed {
+		delete(removed, oldName)
+		delete(added, name)
+	}
+
+	entries := []DiffEntry{}
+	// names of the folders whose entry is folded into an ancestor's
+	collapsed := map[string]bool{}
+	orgChanged := map[string]bool{}
+	for name, a := range cur {
+		oldName := name
+		if added[name] {
+			entries = append(entries, DiffEntry{Kind: DiffAdded, Name: name, OrgID: a.OrgId, NewPath: a.Paths})
+			collapsed[name] = added[lastLabel(parentPath(a.Paths))]
+			continue
+		}
+		for o, n := range renamed {
+			if n == name {
+				oldName = o
+				entries = append(entries, DiffEntry{Kind: DiffRenamed, Name: name, OldName: o, OrgID: a.OrgId, OldPath: old[o].Paths, NewPath: a.Paths})
+			}
+		}
+		b := old[oldName]
+		switch {
+		case b.OrgId != a.OrgId:
+			orgChanged[name] = true
+			entries = append(entries, DiffEntry{Kind: DiffOrgChanged, Name: name, OrgID: a.OrgId, OldOrgID: b.OrgId, OldPath: b.Paths, NewPath: a.Paths})
+		case newName(lastLabel(parentPath(b.Paths))) != lastLabel(parentPath(a.Paths)):
+			entries = append(entries, DiffEntry{Kind: DiffMoved, Name: name, OrgID: a.OrgId, OldPath: b.Paths, NewPath: a.Paths})
+		}
+	}
+	for name, b := range old {
+		if removed[name] {
+			entries = append(entries, DiffEntry{Kind: DiffRemoved, Name: name, OrgID: b.OrgId, OldPath: b.Paths})
+			collapsed[name] = removed[lastLabel(parentPath(b.Paths))]
+		}
+	}
+	for name := range orgChanged {
+		// the parent took it along to the other org
+		parent := lastLabel(parentPath(cur[name].Paths))
+		collapsed[name] = orgChanged[parent] && cur[parent].OrgId == cur[name].OrgId && newName(lastLabel(parentPath(old[name].Paths))) == parent
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if (e.Kind == DiffAdded || e.Kind == DiffRemoved || e.Kind == DiffOrgChanged) && collapsed[e.Name] {
+			continue
+		}
+		kept = append(kept, e)
+	}
+	entries = kept
+
+	ownEntry := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		ownEntry[e.Name] = true
+	}
+	for i, e := range entries {
+		switch e.Kind {
+		case DiffAdded, DiffOrgChanged:
+			entries[i].Descendants = countBelow(after, e.OrgID, e.NewPath, func(f Folder) bool { return collapsed[f.Name] })
+		case DiffRemoved:
+			entries[i].Descendants = countBelow(before, e.OrgID, e.OldPath, func(f Folder) bool { return collapsed[f.Name] })
+		case DiffMoved:
+			// the folders that were below it before and didn't change by themselves
+			entries[i].Descendants = countBelow(after, e.OrgID, e.NewPath, func(f Folder) bool {
+				b, ok := old[f.Name]
+				return ok && !ownEntry[f.Name] && b.OrgId == e.OrgID && IsChildFolder(b, e.OldPath)
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.OrgID != b.OrgID {
+			return a.OrgID.String() < b.OrgID.String()
+		}
+		if a.path() != b.path() {
+			return a.path() < b.path()
+		}
+		return a.Kind < b.Kind
+	})
+	return entries
+}
+
+// path is where the folder is after the change, or was before it was removed
+func (e DiffEntry) path() string {
+	if e.NewPath != "" {
+		return e.NewPath
+	}
+	return e.OldPath
+}
+
+// findRenames pairs removed and added folders that are the same folder under
+// another name, a level at a time so the renames of parents are known
+func findRenames(old, cur map[string]Folder, removed, added map[string]bool) map[string]string {
+	renamed := map[string]string{}
+	type key struct {
+		org    uuid.UUID
+		parent string
+	}
+	// the names of the children of every folder, by parent name
+	oldChildren, curChildren := childNames(old), childNames(cur)
+
+	addedBy := map[key][]string{}
+	for name := range added {
+		f := cur[name]
+		k := key{f.OrgId, lastLabel(parentPath(f.Paths))}
+		addedBy[k] = append(addedBy[k], name)
+	}
+	removedByDepth := map[int][]string{}
+	maxDepth := 0
+	for name := range removed {
+		depth := strings.Count(old[name].Paths, ".")
+		removedByDepth[depth] = append(removedByDepth[depth], name)
+		maxDepth = max(maxDepth, depth)
+	}
+
+	used := map[string]bool{}
+	for depth := 0; depth <= maxDepth; depth++ {
+		removedBy := map[key][]string{}
+		for _, name := range removedByDepth[depth] {
+			f := old[name]
+			parent := lastLabel(parentPath(f.Paths))
+			if n, ok := renamed[parent]; ok {
+				parent = n
+			}
+			k := key{f.OrgId, parent}
+			removedBy[k] = append(removedBy[k], name)
+		}
+		for k, names := range removedBy {
+			candidates := addedBy[k]
+			sort.Strings(names)
+			sort.Strings(candidates)
+			for _, name := range names {
+				for _, c := range candidates {
+					if !used[c] && len(oldChildren[name]) > 0 && sameNames(oldChildren[name], curChildren[c]) {
+						renamed[name], used[c] = c, true
+						break
+					}
+				}
+			}
+			// a lone leaf on both sides
+			if len(names) == 1 && len(candidates) == 1 && !used[candidates[0]] && len(oldChildren[names[0]]) == 0 && len(curChildren[candidates[0]]) == 0 {
+				renamed[names[0]], used[candidates[0]] = candidates[0], true
+			}
+		}
+	}
+	return renamed
+}
+
+func foldersByName(folders []Folder) map[string]Folder {
+	res := make(map[string]Folder, len(folders))
+	for _, f := range folders {
+		if _, ok := res[f.Name]; !ok {
+			res[f.Name] = f
+		}
+	}
+	return res
+}
+
+func childNames(folders map[string]Folder) map[string][]string {
+	res := map[string][]string{}
+	for name, f := range folders {
+		if parent := parentPath(f.Paths); parent != "" {
+			res[lastLabel(parent)] = append(res[lastLabel(parent)], name)
+		}
+	}
+	return res
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, name := range a {
+		seen[name] = true
+	}
+	for _, name := range b {
+		if !seen[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// countBelow counts the folders of orgID below root that match
+func countBelow(folders []Folder, orgID uuid.UUID, root string, match func(Folder) bool) int {
+	n := 0
+	for _, f := range folders {
+		if f.OrgId == orgID && IsChildFolder(f, root) && match(f) {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteDiff prints entries to w as a report, one block per org:
+//
+//	38b9879b-f73b-4b0e-b9d9-4fc4c23643a7
+//	  moved    alpha.bravo -> alpha.delta.bravo (+1 below)
+//	  renamed  alpha.delta -> alpha.yankee
+//	  removed  golf
+//
+//	1 moved, 1 renamed, 1 removed
+func WriteDiff(w io.Writer, entries []DiffEntry) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	counts := map[DiffKind]int{}
+	for i, e := range entries {
+		if i == 0 || e.OrgID != entries[i-1].OrgID {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w, e.OrgID); err != nil {
+				return err
+			}
+		}
+		counts[e.Kind]++
+
+		var line string
+		switch e.Kind {
+		case DiffAdded:
+			line = "added    " + e.NewPath
+		case DiffRemoved:
+			line = "removed  " + e.OldPath
+		case DiffMoved:
+			line = "moved    " + e.OldPath + " -> " + e.NewPath
+		case DiffRenamed:
+			line = "renamed  " + e.OldPath + " -> " + e.NewPath
+		case DiffOrgChanged:
+			line = fmt.Sprintf("org      %s -> %s, from %s", e.OldPath, e.NewPath, e.OldOrgID)
+		}
+		if e.Descendants > 0 {
+			line += fmt.Sprintf(" (+%d below)", e.Descendants)
+		}
+		if _, err := fmt.Fprintln(w, "  "+line); err != nil {
+			return err
+		}
+	}
+
+	summary := []string{}
+	for _, kind := range []DiffKind{DiffAdded, DiffRemoved, DiffMoved, DiffRenamed, DiffOrgChanged} {
+		if counts[kind] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[kind], strings.ReplaceAll(string(kind), "_", " ")))
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%s\n", strings.Join(summary, ", "))
+	return err
+}
+
+// RenderDiff is WriteDiff into a string.
+func RenderDiff(entries []DiffEntry) string {
+	var b strings.Builder
+	// writes to a strings.Builder never fail
+	_ = WriteDiff(&b, entries)
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package folder_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// edited returns MoveData after the edits made by edit
+func edited(t *testing.T, edit func(d folder.IDriver, e folder.Editor)) []folder.Folder {
+	t.Helper()
+	d, e := editor(t)
+	edit(d, e)
+	return append(d.GetFoldersByOrgID(foldertest.OrgA), d.GetFoldersByOrgID(foldertest.OrgB)...)
+}
+
+func Test_folder_Diff(t *testing.T) {
+	t.Parallel()
+	a, b := foldertest.OrgA, foldertest.OrgB
+	tests := [...]struct {
+		name  string
+		after []folder.Folder
+		want  []folder.DiffEntry
+	}{
+		{
+			name:  "no changes",
+			after: foldertest.MoveData(),
+			want:  []folder.DiffEntry{},
+		},
+		{
+			name: "subtree move is one entry",
+			after: edited(t, func(d folder.IDriver, e folder.Editor) {
+				_, err := d.MoveFolder("bravo", "golf")
+				require.NoError(t, err)
+			}),
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffMoved, Name: "bravo", OrgID: a, OldPath: "alpha.bravo", NewPath: "golf.bravo", Descendants: 1},
+			},
+		},
+		{
+			name: "renamed parent",
+			after: edited(t, func(d folder.IDriver, e folder.Editor) {
+				_, err := e.RenameFolder(a, "delta", "yankee")
+				require.NoError(t, err)
+			}),
+			// echo has the same parent under its new name
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffRenamed, Name: "yankee", OldName: "delta", OrgID: a, OldPath: "alpha.delta", NewPath: "alpha.yankee"},
+			},
+		},
+		{
+			name: "renamed leaf",
+			after: edited(t, func(d folder.IDriver, e folder.Editor) {
+				_, err := e.RenameFolder(a, "charlie", "zulu")
+				require.NoError(t, err)
+			}),
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffRenamed, Name: "zulu", OldName: "charlie", OrgID: a, OldPath: "alpha.bravo.charlie", NewPath: "alpha.bravo.zulu"},
+			},
+		},
+		{
+			name: "renamed and moved",
+			after: edited(t, func(d folder.IDriver, e folder.Editor) {
+				_, err := e.RenameFolder(a, "delta", "yankee")
+				require.NoError(t, err)
+				_, err = d.MoveFolder("yankee", "golf")
+				require.NoError(t, err)
+			}),
+			// a rename is only found in place
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffRemoved, Name: "delta", OrgID: a, OldPath: "alpha.delta", Descendants: 0},
+				{Kind: folder.DiffMoved, Name: "echo", OrgID: a, OldPath: "alpha.delta.echo", NewPath: "golf.yankee.echo"},
+				{Kind: folder.DiffAdded, Name: "yankee", OrgID: a, NewPath: "golf.yankee"},
+			},
+		},
+		{
+			name: "added and removed subtrees",
+			after: edited(t, func(d folder.IDriver, e folder.Editor) {
+				_, err := e.DeleteFolder(a, "bravo")
+				require.NoError(t, err)
+				_, err = e.CreateFolder(a, "hotel", "golf")
+				require.NoError(t, err)
+				_, err = e.CreateFolder(a, "india", "hotel")
+				require.NoError(t, err)
+				_, err = e.CreateFolder(a, "juliet", "india")
+				require.NoError(t, err)
+			}),
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffRemoved, Name: "bravo", OrgID: a, OldPath: "alpha.bravo", Descendants: 1},
+				{Kind: folder.DiffAdded, Name: "hotel", OrgID: a, NewPath: "golf.hotel", Descendants: 2},
+			},
+		},
+		{
+			name: "org changed",
+			after: []folder.Folder{
+				{Name: "alpha", OrgId: b, Paths: "foxtrot.alpha"},
+				{Name: "bravo", OrgId: b, Paths: "foxtrot.alpha.bravo"},
+				{Name: "charlie", OrgId: b, Paths: "foxtrot.alpha.bravo.charlie"},
+				{Name: "delta", OrgId: b, Paths: "foxtrot.alpha.delta"},
+				{Name: "echo", OrgId: b, Paths: "foxtrot.alpha.delta.echo"},
+				{Name: "foxtrot", OrgId: b, Paths: "foxtrot"},
+				{Name: "golf", OrgId: b, Paths: "golf"},
+			},
+			want: []folder.DiffEntry{
+				{Kind: folder.DiffOrgChanged, Name: "golf", OrgID: b, OldOrgID: a, OldPath: "golf", NewPath: "golf"},
+				{Kind: folder.DiffOrgChanged, Name: "alpha", OrgID: b, OldOrgID: a, OldPath: "alpha", NewPath: "foxtrot.alpha", Descendants: 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := folder.Diff(foldertest.MoveData(), tt.after)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func Test_folder_Diff_MovedDescendants(t *testing.T) {
+	t.Parallel()
+	a := foldertest.OrgA
+	before := []folder.Folder{
+		{Name: "alpha", OrgId: a, Paths: "alpha"},
+		{Name: "bravo", OrgId: a, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: a, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: a, Paths: "delta"},
+		{Name: "echo", OrgId: a, Paths: "delta.echo"},
+	}
+	// every level swapped, and delta with its child moved below bravo
+	after := []folder.Folder{
+		{Name: "bravo", OrgId: a, Paths: "bravo"},
+		{Name: "alpha", OrgId: a, Paths: "bravo.alpha"},
+		{Name: "charlie", OrgId: a, Paths: "bravo.alpha.charlie"},
+		{Name: "delta", OrgId: a, Paths: "bravo.delta"},
+		{Name: "echo", OrgId: a, Paths: "bravo.delta.echo"},
+	}
+	assert.ElementsMatch(t, []folder.DiffEntry{
+		{Kind: folder.DiffMoved, Name: "bravo", OrgID: a, OldPath: "alpha.bravo", NewPath: "bravo"},
+		{Kind: folder.DiffMoved, Name: "alpha", OrgID: a, OldPath: "alpha", NewPath: "bravo.alpha"},
+		{Kind: folder.DiffMoved, Name: "charlie", OrgID: a, OldPath: "alpha.bravo.charlie", NewPath: "bravo.alpha.charlie"},
+		{Kind: folder.DiffMoved, Name: "delta", OrgID: a, OldPath: "delta", NewPath: "bravo.delta", Descendants: 1},
+	}, folder.Diff(before, after))
+}
+
+func Test_folder_Diff_Order(t *testing.T) {
+	t.Parallel()
+	after := edited(t, func(d folder.IDriver, e folder.Editor) {
+		_, err := d.MoveFolder("echo", "golf")
+		require.NoError(t, err)
+		_, err = e.CreateFolder(foldertest.OrgB, "hotel", "foxtrot")
+		require.NoError(t, err)
+		_, err = e.DeleteFolder(foldertest.OrgA, "charlie")
+		require.NoError(t, err)
+	})
+
+	paths := []string{}
+	for _, e := range folder.Diff(foldertest.MoveData(), after) {
+		paths = append(paths, e.OrgID.String()+" "+string(e.Kind)+" "+e.OldPath+" "+e.NewPath)
+	}
+	assert.Equal(t, []string{
+		foldertest.OrgA.String() + " removed alpha.bravo.charlie ",
+		foldertest.OrgA.String() + " moved alpha.delta.echo golf.echo",
+		foldertest.OrgB.String() + " added  foxtrot.hotel",
+	}, paths)
+}
+
+func Test_folder_Diff_JSON(t *testing.T) {
+	t.Parallel()
+	entries := folder.Diff(foldertest.MoveData(), edited(t, func(d folder.IDriver, e folder.Editor) {
+		_, err := d.MoveFolder("bravo", "delta")
+		require.NoError(t, err)
+	}))
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"kind": "moved", "name": "bravo", "org_id": "`+foldertest.OrgA.String()+`",
+		"old_org_id": "00000000-0000-0000-0000-000000000000",
+		"old_path": "alpha.bravo", "new_path": "alpha.delta.bravo", "descendants": 1
+	}]`, string(data))
+}
+
+func Test_folder_RenderDiff(t *testing.T) {
+	t.Parallel()
+	before := append(foldertest.MoveData(), folder.Folder{Name: "kilo", OrgId: foldertest.OrgB, Paths: "foxtrot.kilo"})
+	after := []folder.Folder{
+		{Name: "alpha", OrgId: foldertest.OrgA, Paths: "alpha"},
+		{Name: "yankee", OrgId: foldertest.OrgA, Paths: "alpha.yankee"},
+		{Name: "echo", OrgId: foldertest.OrgA, Paths: "alpha.yankee.echo"},
+		{Name: "bravo", OrgId: foldertest.OrgA, Paths: "alpha.yankee.echo.bravo"},
+		{Name: "charlie", OrgId: foldertest.OrgA, Paths: "alpha.yankee.echo.bravo.charlie"},
+		{Name: "foxtrot", OrgId: foldertest.OrgB, Paths: "foxtrot"},
+		{Name: "hotel", OrgId: foldertest.OrgB, Paths: "foxtrot.hotel"},
+		{Name: "golf", OrgId: foldertest.OrgB, Paths: "foxtrot.golf"},
+		{Name: "kilo", OrgId: foldertest.OrgA, Paths: "kilo"},
+	}
+	assertGolden(t, "diff_report", folder.RenderDiff(folder.Diff(before, after)))
+	assert.Equal(t, "no changes\n", folder.RenderDiff(folder.Diff(after, after)))
+}
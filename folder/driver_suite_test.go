@@ -0,0 +1,60 @@
+package folder_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_DriverSuite(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		return folder.NewDriver(folders)
+	})
+}
+
+func Test_folder_DriverSuite_LoadDriver(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		b := folder.NewBuilder()
+		for _, f := range folders {
+			b.Add(f)
+		}
+		return b.Driver()
+	})
+}
+
+func Test_folder_DriverSuite_MemoryStore(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		d, err := folder.NewDriverWithStore(folder.NewMemoryStore(folders))
+		require.NoError(t, err)
+		return d
+	})
+}
+
+func Test_folder_DriverSuite_FileStore(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		store, err := folder.OpenFileStore(t.TempDir(), folders, folder.FileStoreOptions{})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+		d, err := folder.NewDriverWithStore(store)
+		require.NoError(t, err)
+		return d
+	})
+}
+
+func Test_folder_DriverSuite_EventStore(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		d, err := folder.NewDriverWithStore(folder.NewEventStore(folders, folder.EventStoreOptions{SnapshotEvery: 2}))
+		require.NoError(t, err)
+		return d
+	})
+}
+
+func Test_folder_DriverSuite_History(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		h, err := folder.NewHistory(folder.NewDriver(folders), folder.HistoryOptions{})
+		require.NoError(t, err)
+		return h
+	})
+}
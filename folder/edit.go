@@ -0,0 +1,180 @@
+package folder
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrFolderExists      = errors.New("Error: Folder already exists")
+	ErrInvalidFolderName = errors.New("Error: Folder name is not a valid ltree label")
+)
+
+// Editor is implemented by drivers that can change the tree beyond moving
+// folders. Names stay unique across orgs, like MoveFolder expects.
+type Editor interface {
+	// CreateFolder adds a folder called name below parent, or a root folder when parent is empty.
+	CreateFolder(orgID uuid.UUID, name string, parent string) (Folder, error)
+	// RenameFolder renames a folder and returns it and its descendants at their new paths.
+	RenameFolder(orgID uuid.UUID, name string, newName string) ([]Folder, error)
+	// DeleteFolder removes a folder and its descendants and returns what was removed.
+	DeleteFolder(orgID uuid.UUID, name string) ([]Folder, error)
+}
+
+// indexInOrg returns the position of the folder called name in orgID
+func (f *driver) indexInOrg(orgID uuid.UUID, name string) (int, error) {
+	if !f.CheckFolderExists(name) {
+		return -1, ErrFolderNotFound
+	}
+	for _, i := range f.byName[name] {
+		if f.folders[i].OrgId == orgID {
+			return i, nil
+		}
+	}
+	return -1, ErrFolderNotInOrg
+}
+
+// subtree returns the positions of the folder at i and of its descendants
+func (f *driver) subtree(i int) []int {
+	root := f.folders[i]
+	res := []int{i}
+	for j, folder := range f.folders {
+		if folder.OrgId == root.OrgId && IsChildFolder(folder, root.Paths) {
+			res = append(res, j)
+		}
+	}
+	return res
+}
+
+func (f *driver) checkNewName(name string) error {
+	if !IsValidLabel(name) {
+		return ErrInvalidFolderName
+	}
+	if f.CheckFolderExists(name) {
+		return ErrFolderExists
+	}
+	return nil
+}
+
+func (f *driver) CreateFolder(orgID uuid.UUID, name string, parent string) (Folder, error) {
+	if err := f.checkNewName(name); err != nil {
+		return Folder{}, err
+	}
+	path := name
+	if parent != "" {
+		i, err := f.indexInOrg(orgID, parent)
+		if err != nil {
+			return Folder{}, err
+		}
+		path = f.folders[i].Paths + "." + name
+	}
+
+	created := Folder{Name: name, OrgId: orgID, Paths: path}
+	if f.store != nil {
+		if err := f.store.Apply(Mutation{Op: OpCreate, Args: []string{orgID.String(), name, parent}, Changed: []Folder{created}}); err != nil {
+			return Folder{}, err
+		}
+	}
+	f.add(created)
+
+	f.publish(Event{Type: FolderCreated, OrgID: orgID, Name: name, Changes: []Change{{Name: name, NewPath: path}}})
+	return created, nil
+}
+
+func (f *driver) RenameFolder(orgID uuid.UUID, name string, newName string) ([]Folder, error) {
+	i, err := f.indexInOrg(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.checkNewName(newName); err != nil {
+		return nil, err
+	}
+
+	oldRoot := f.folders[i].Paths
+	newRoot := newName
+	if parent := parentPath(oldRoot); parent != "" {
+		newRoot = parent + "." + newName
+	}
+
+	subtree := f.subtree(i)
+	renamed := make([]Folder, len(subtree))
+	for j, k := range subtree {
+		renamed[j] = f.folders[k]
+		renamed[j].Paths = newRoot + f.folders[k].Paths[len(oldRoot):]
+	}
+	renamed[0].Name = newName
+
+	if f.store != nil {
+		mutation := Mutation{Op: OpRename, Args: []string{orgID.String(), name, newName}, Changed: renamed, Removed: []Folder{f.folders[i]}}
+		if err := f.store.Apply(mutation); err != nil {
+			return nil, err
+		}
+	}
+
+	notify := f.notifying()
+	event := Event{Type: FolderRenamed, OrgID: orgID, Name: name}
+	for j, k := range subtree {
+		if notify {
+			change := Change{Name: renamed[j].Name, OldPath: f.folders[k].Paths, NewPath: renamed[j].Paths}
+			if k == i {
+				change.OldName = name
+			}
+			event.Changes = append(event.Changes, change)
+		}
+		f.folders[k] = renamed[j]
+	}
+	f.reindex()
+
+	f.publish(event)
+	return renamed, nil
+}
+
+func (f *driver) DeleteFolder(orgID uuid.UUID, name string) ([]Folder, error) {
+	i, err := f.indexInOrg(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	subtree := f.subtree(i)
+	deleted := make([]Folder, len(subtree))
+	gone := make(map[int]bool, len(subtree))
+	for j, k := range subtree {
+		deleted[j] = f.folders[k]
+		gone[k] = true
+	}
+
+	if f.store != nil {
+		if err := f.store.Apply(Mutation{Op: OpDelete, Args: []string{orgID.String(), name}, Removed: deleted}); err != nil {
+			return nil, err
+		}
+	}
+
+	// a new slice, the old one may be the caller's or returned by an earlier call
+	kept := make([]Folder, 0, len(f.folders)-len(deleted))
+	for k, folder := range f.folders {
+		if !gone[k] {
+			kept = append(kept, folder)
+		}
+	}
+	f.folders = kept
+	f.reindex()
+
+	if f.notifying() {
+		event := Event{Type: FolderDeleted, OrgID: orgID, Name: name}
+		for _, folder := range deleted {
+			event.Changes = append(event.Changes, Change{Name: folder.Name, OldPath: folder.Paths})
+		}
+		f.publish(event)
+	}
+	return deleted, nil
+}
+
+// parentPath is the path without its last label, "" for a root
+func parentPath(path string) string {
+	if dot := strings.LastIndex(path, "."); dot >= 0 {
+		return path[:dot]
+	}
+	return ""
+}
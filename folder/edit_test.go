@@ -0,0 +1,180 @@
+package folder_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func names(folders []folder.Folder) []string {
+	res := []string{}
+	for _, f := range folders {
+		res = append(res, f.Name)
+	}
+	return res
+}
+
+func paths(folders []folder.Folder) []string {
+	res := []string{}
+	for _, f := range folders {
+		res = append(res, f.Paths)
+	}
+	return res
+}
+
+func editor(t *testing.T) (folder.IDriver, folder.Editor) {
+	t.Helper()
+	d := folder.NewDriver(foldertest.MoveData())
+	e, ok := d.(folder.Editor)
+	require.True(t, ok)
+	return d, e
+}
+
+func Test_folder_CreateFolder(t *testing.T) {
+	t.Parallel()
+	d, e := editor(t)
+
+	created, err := e.CreateFolder(foldertest.OrgA, "hotel", "bravo")
+	require.NoError(t, err)
+	assert.Equal(t, folder.Folder{Name: "hotel", OrgId: foldertest.OrgA, Paths: "alpha.bravo.hotel"}, created)
+
+	root, err := e.CreateFolder(foldertest.OrgB, "india", "")
+	require.NoError(t, err)
+	assert.Equal(t, "india", root.Paths)
+
+	children, err := d.GetAllChildFolders(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"charlie", "hotel"}, names(children))
+
+	// the new folders can be moved like any other
+	_, err = d.MoveFolder("hotel", "golf")
+	require.NoError(t, err)
+}
+
+func Test_folder_RenameFolder(t *testing.T) {
+	t.Parallel()
+	d, e := editor(t)
+
+	renamed, err := e.RenameFolder(foldertest.OrgA, "alpha", "zulu")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zulu", "zulu.bravo", "zulu.bravo.charlie", "zulu.delta", "zulu.delta.echo"}, paths(renamed))
+
+	assert.False(t, d.(interface{ CheckFolderExists(string) bool }).CheckFolderExists("alpha"))
+	children, err := d.GetAllChildFolders(foldertest.OrgA, "zulu")
+	require.NoError(t, err)
+	assert.Len(t, children, 4)
+
+	renamed, err = e.RenameFolder(foldertest.OrgA, "delta", "yankee")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"zulu.yankee", "zulu.yankee.echo"}, paths(renamed))
+}
+
+func Test_folder_DeleteFolder(t *testing.T) {
+	t.Parallel()
+	d, e := editor(t)
+
+	deleted, err := e.DeleteFolder(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha.bravo", "alpha.bravo.charlie"}, paths(deleted))
+	assert.Equal(t, []string{"alpha", "delta", "echo", "golf"}, names(d.GetFoldersByOrgID(foldertest.OrgA)))
+
+	_, err = d.GetAllChildFolders(foldertest.OrgA, "charlie")
+	assert.ErrorIs(t, err, folder.ErrFolderNotFound)
+	// the name can be used again
+	_, err = e.CreateFolder(foldertest.OrgA, "bravo", "golf")
+	assert.NoError(t, err)
+}
+
+func Test_folder_DeleteFolder_KeepsCallerData(t *testing.T) {
+	t.Parallel()
+	data := foldertest.MoveData()
+	d := folder.NewDriver(data)
+	moved, err := d.MoveFolder("echo", "golf")
+	require.NoError(t, err)
+	movedPaths := paths(moved)
+
+	_, err = d.(folder.Editor).DeleteFolder(foldertest.OrgA, "alpha")
+	require.NoError(t, err)
+	// the slice given to NewDriver and the one MoveFolder returned are unchanged
+	assert.Equal(t, movedPaths, paths(data))
+	assert.Equal(t, movedPaths, paths(moved))
+}
+
+func Test_folder_EditErrors(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name string
+		edit func(e folder.Editor) error
+		want error
+	}{
+		{"create existing name", func(e folder.Editor) error {
+			_, err := e.CreateFolder(foldertest.OrgB, "alpha", "")
+			return err
+		}, folder.ErrFolderExists},
+		{"create invalid name", func(e folder.Editor) error {
+			_, err := e.CreateFolder(foldertest.OrgA, "a.b", "")
+			return err
+		}, folder.ErrInvalidFolderName},
+		{"create below unknown parent", func(e folder.Editor) error {
+			_, err := e.CreateFolder(foldertest.OrgA, "hotel", "nope")
+			return err
+		}, folder.ErrFolderNotFound},
+		{"create below parent of another org", func(e folder.Editor) error {
+			_, err := e.CreateFolder(foldertest.OrgA, "hotel", "foxtrot")
+			return err
+		}, folder.ErrFolderNotInOrg},
+		{"rename unknown folder", func(e folder.Editor) error {
+			_, err := e.RenameFolder(foldertest.OrgA, "nope", "hotel")
+			return err
+		}, folder.ErrFolderNotFound},
+		{"rename to existing name", func(e folder.Editor) error {
+			_, err := e.RenameFolder(foldertest.OrgA, "bravo", "foxtrot")
+			return err
+		}, folder.ErrFolderExists},
+		{"rename to invalid name", func(e folder.Editor) error {
+			_, err := e.RenameFolder(foldertest.OrgA, "bravo", "two words")
+			return err
+		}, folder.ErrInvalidFolderName},
+		{"delete folder of another org", func(e folder.Editor) error {
+			_, err := e.DeleteFolder(foldertest.OrgB, "alpha")
+			return err
+		}, folder.ErrFolderNotInOrg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d, e := editor(t)
+			assert.ErrorIs(t, tt.edit(e), tt.want)
+			// failed edits change nothing
+			assert.Equal(t, folder.NewDriver(foldertest.MoveData()).GetFoldersByOrgID(foldertest.OrgA), d.GetFoldersByOrgID(foldertest.OrgA))
+		})
+	}
+}
+
+func Test_folder_EditsSurviveRestart(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, d := openFileDriver(t, dir, folder.FileStoreOptions{})
+	e := d.(folder.Editor)
+
+	_, err := e.CreateFolder(foldertest.OrgA, "hotel", "golf")
+	require.NoError(t, err)
+	_, err = e.RenameFolder(foldertest.OrgA, "alpha", "zulu")
+	require.NoError(t, err)
+	_, err = e.DeleteFolder(foldertest.OrgA, "delta")
+	require.NoError(t, err)
+	want := orgPaths(t, d)
+	require.NoError(t, store.Close())
+
+	assert.Equal(t, map[string]string{
+		"zulu": "zulu", "bravo": "zulu.bravo", "charlie": "zulu.bravo.charlie",
+		"foxtrot": "foxtrot", "golf": "golf", "hotel": "golf.hotel",
+	}, want)
+
+	store, d = openFileDriver(t, dir, folder.FileStoreOptions{})
+	assert.Equal(t, want, orgPaths(t, d))
+	require.NoError(t, store.Close())
+}
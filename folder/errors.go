@@ -0,0 +1,16 @@
+package folder
+
+import "errors"
+
+// Errors returned by the driver. The messages are the ones from the spec so
+// callers can keep comparing err.Error(), but errors.Is is preferred.
+var (
+	ErrFolderNotFound = errors.New("Error: Folder does not exist")
+	ErrFolderNotInOrg = errors.New("Error: Folder does not exist in the specified organization")
+
+	ErrSourceNotFound      = errors.New("Error: Source folder does not exist")
+	ErrDestinationNotFound = errors.New("Error: Destination folder does not exist")
+	ErrMoveToSelf          = errors.New("Error: Cannot move a folder to itself")
+	ErrMoveToOtherOrg      = errors.New("Error: Cannot move a folder to a different organization")
+	ErrMoveToDescendant    = errors.New("Error: Cannot move a folder to a child of itself")
+)
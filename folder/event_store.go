@@ -0,0 +1,262 @@
+package folder
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// DefaultSnapshotEvery is how many records an EventStore appends between snapshots.
+const DefaultSnapshotEvery = 100
+
+var (
+	ErrReadOnly      = errors.New("Error: Driver is read-only")
+	ErrBeforeHistory = errors.New("Error: Time is before the start of the history")
+	ErrUnknownSeq    = errors.New("Error: Event has not happened yet")
+	ErrUnknownMutation = errors.New("Error: Mutation is not a create, move, rename or delete")
+)
+
+// EventStore is a Store whose folders are a projection of an append-only log
+// of domain events: the folders created, moved, renamed and deleted. Every
+// state the folders have been in can be rebuilt with AsOf and AsOfSeq. A
+// snapshot is taken every SnapshotEvery records, so a rebuild replays at most
+// that many. The log is kept in memory and it is safe for concurrent use.
+//
+//	store := folder.NewEventStore(folders, folder.EventStoreOptions{})
+//	driver, _ := folder.NewDriverWithStore(store)
+//	...
+//	tuesday, _ := store.AsOf(lastTuesday)
+//	tuesday.GetFoldersByOrgID(orgID)
+type EventStore struct {
+	opts EventStoreOptions
+
+	mu      sync.Mutex
+	records []LogRecord
+	// snapshots[0] is the seed, ordered by Seq
+	snapshots []eventSnapshot
+	// the current projection
+	folders []Folder
+	index   map[folderKey]int
+	closed  bool
+}
+
+type EventStoreOptions struct {
+	// records between snapshots, DefaultSnapshotEvery when zero
+	SnapshotEvery int
+	// stamps the records, time.Now when nil. A time before the previous
+	// record's, e.g. after the wall clock was set back, is stamped with the
+	// previous record's time instead, AsOf needs them in order.
+	Now func() time.Time
+}
+
+// LogRecord is one event in the log of an EventStore, shaped like the Event
+// a driver publishes for the same change.
+type LogRecord struct {
+	// numbers the records from 1, the seed folders are the state at 0
+	Seq   uint64    `json:"seq"`
+	Time  time.Time `json:"time"`
+	Type  EventType `json:"type"`
+	OrgID uuid.UUID `json:"org_id"`
+	// the folder the change was made to, by its name before the change
+	Name string `json:"name"`
+	// the old and new path of every affected folder
+	Changes []Change `json:"changes"`
+}
+
+type eventSnapshot struct {
+	seq     uint64
+	folders []Folder
+}
+
+// NewEventStore returns a store starting out with a copy of seed, stamped with the current time.
+func NewEventStore(seed []Folder, opts EventStoreOptions) *EventStore {
+	if opts.SnapshotEvery <= 0 {
+		opts.SnapshotEvery = DefaultSnapshotEvery
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	folders := append([]Folder{}, seed...)
+	return &EventStore{
+		opts: opts,
+		// record 0 only marks the start of the history for AsOf, the seed is snapshots[0]
+		records:   []LogRecord{{Time: opts.Now()}},
+		snapshots: []eventSnapshot{{folders: append([]Folder{}, seed...)}},
+		folders:   folders,
+		index:     indexFolders(folders),
+	}
+}
+
+func (s *EventStore) Load() ([]Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	return append([]Folder{}, s.folders...), nil
+}
+
+// Apply appends the event m describes to the log and updates the projection.
+func (s *EventStore) Apply(m Mutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	r, err := s.record(m)
+	if err != nil {
+		return err
+	}
+	seq := uint64(len(s.records))
+	now := s.opts.Now()
+	if prev := s.records[len(s.records)-1].Time; now.Before(prev) {
+		now = prev
+	}
+	r.Seq, r.Time = seq, now
+	s.records = append(s.records, r)
+	s.folders = applyMutation(s.folders, s.index, r.mutation())
+	if seq%uint64(s.opts.SnapshotEvery) == 0 {
+		s.snapshots = append(s.snapshots, eventSnapshot{seq: seq, folders: append([]Folder{}, s.folders...)})
+	}
+	return nil
+}
+
+func (s *EventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Seq returns the number of the latest record, 0 before anything was applied.
+func (s *EventStore) Seq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return uint64(len(s.records) - 1)
+}
+
+// Records returns the records after seq, oldest first.
+func (s *EventStore) Records(after uint64) []LogRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if after >= uint64(len(s.records)) {
+		return []LogRecord{}
+	}
+	return append([]LogRecord{}, s.records[after+1:]...)
+}
+
+// AsOf returns a read-only driver over the folders as they were at t, after
+// every record stamped at or before it.
+func (s *EventStore) AsOf(t time.Time) (IDriver, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the first record after t
+	n := sort.Search(len(s.records), func(i int) bool { return s.records[i].Time.After(t) })
+	if n == 0 {
+		return nil, ErrBeforeHistory
+	}
+	return s.asOf(uint64(n - 1)), nil
+}
+
+// AsOfSeq returns a read-only driver over the folders as they were right after record seq.
+func (s *EventStore) AsOfSeq(seq uint64) (IDriver, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq >= uint64(len(s.records)) {
+		return nil, ErrUnknownSeq
+	}
+	return s.asOf(seq), nil
+}
+
+// asOf replays the records after the latest snapshot up to seq, the caller holds s.mu
+func (s *EventStore) asOf(seq uint64) IDriver {
+	i := sort.Search(len(s.snapshots), func(i int) bool { return s.snapshots[i].seq > seq }) - 1
+	snap := s.snapshots[i]
+	folders := append([]Folder{}, snap.folders...)
+	index := indexFolders(folders)
+	for _, r := range s.records[snap.seq+1 : seq+1] {
+		folders = applyMutation(folders, index, r.mutation())
+	}
+	return readOnlyDriver{newDriver(folders)}
+}
+
+// record turns m into an event, reading the old paths from the projection.
+// The caller holds s.mu.
+func (s *EventStore) record(m Mutation) (LogRecord, error) {
+	oldPath := func(f Folder) string {
+		if i, ok := s.index[folderKey{f.OrgId, f.Name}]; ok {
+			return s.folders[i].Paths
+		}
+		return ""
+	}
+	r := LogRecord{Changes: []Change{}}
+	switch {
+	case m.Op == OpMove && len(m.Args) > 0 && len(m.Changed) > 0:
+		r.Type, r.OrgID, r.Name = FolderMoved, m.Changed[0].OrgId, m.Args[0]
+		for _, f := range m.Changed {
+			r.Changes = append(r.Changes, Change{Name: f.Name, OldPath: oldPath(f), NewPath: f.Paths})
+		}
+	case m.Op == OpCreate && len(m.Changed) > 0:
+		r.Type, r.OrgID, r.Name = FolderCreated, m.Changed[0].OrgId, m.Changed[0].Name
+		for _, f := range m.Changed {
+			r.Changes = append(r.Changes, Change{Name: f.Name, NewPath: f.Paths})
+		}
+	case m.Op == OpRename && len(m.Removed) == 1 && len(m.Changed) > 0:
+		old := m.Removed[0]
+		r.Type, r.OrgID, r.Name = FolderRenamed, old.OrgId, old.Name
+		for _, f := range m.Changed {
+			change := Change{Name: f.Name, OldPath: oldPath(f), NewPath: f.Paths}
+			if change.OldPath == "" {
+				// the renamed folder is only known by its old name
+				change.OldName, change.OldPath = old.Name, old.Paths
+			}
+			r.Changes = append(r.Changes, change)
+		}
+	case m.Op == OpDelete && len(m.Removed) > 0:
+		r.Type, r.OrgID, r.Name = FolderDeleted, m.Removed[0].OrgId, m.Removed[0].Name
+		for _, f := range m.Removed {
+			r.Changes = append(r.Changes, Change{Name: f.Name, OldPath: f.Paths})
+		}
+	default:
+		return LogRecord{}, ErrUnknownMutation
+	}
+	return r, nil
+}
+
+// mutation is the change r makes to the folders of the projection
+func (r LogRecord) mutation() Mutation {
+	m := Mutation{Changed: []Folder{}}
+	for _, c := range r.Changes {
+		if c.OldName != "" || r.Type == FolderDeleted {
+			name := c.Name
+			if c.OldName != "" {
+				name = c.OldName
+			}
+			m.Removed = append(m.Removed, Folder{Name: name, OrgId: r.OrgID, Paths: c.OldPath})
+		}
+		if r.Type != FolderDeleted {
+			m.Changed = append(m.Changed, Folder{Name: c.Name, OrgId: r.OrgID, Paths: c.NewPath})
+		}
+	}
+	return m
+}
+
+// readOnlyDriver hides everything but the reads of IDriver
+type readOnlyDriver struct {
+	d *driver
+}
+
+func (r readOnlyDriver) GetFoldersByOrgID(orgID uuid.UUID) []Folder {
+	return r.d.GetFoldersByOrgID(orgID)
+}
+
+func (r readOnlyDriver) GetAllChildFolders(orgID uuid.UUID, name string) ([]Folder, error) {
+	return r.d.GetAllChildFolders(orgID, name)
+}
+
+func (readOnlyDriver) MoveFolder(string, string) ([]Folder, error) {
+	return nil, ErrReadOnly
+}
@@ -0,0 +1,207 @@
+package folder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monday is when the history of eventStore starts, every change is made a day after the previous one
+var monday = time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+
+// eventStore returns a store over the move data after a week of changes
+func eventStore(t *testing.T, opts folder.EventStoreOptions) *folder.EventStore {
+	t.Helper()
+	now := monday
+	opts.Now = func() time.Time {
+		defer func() { now = now.Add(24 * time.Hour) }()
+		return now
+	}
+	store := folder.NewEventStore(foldertest.MoveData(), opts)
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+	e := d.(folder.Editor)
+
+	// tuesday
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	// wednesday
+	renamed, err := e.RenameFolder(foldertest.OrgA, "delta", "yankee")
+	require.NoError(t, err)
+	// the log keeps its own copy
+	renamed[0].Name = "changed"
+	// thursday
+	_, err = e.CreateFolder(foldertest.OrgA, "hotel", "golf")
+	require.NoError(t, err)
+	// friday
+	_, err = e.DeleteFolder(foldertest.OrgA, "golf")
+	require.NoError(t, err)
+	return store
+}
+
+// paths of org A as of each day of the week
+var weekPaths = [][]string{
+	{"alpha", "alpha.bravo", "alpha.bravo.charlie", "alpha.delta", "alpha.delta.echo", "golf"},
+	{"alpha", "alpha.delta.bravo", "alpha.delta.bravo.charlie", "alpha.delta", "alpha.delta.echo", "golf"},
+	{"alpha", "alpha.yankee.bravo", "alpha.yankee.bravo.charlie", "alpha.yankee", "alpha.yankee.echo", "golf"},
+	{"alpha", "alpha.yankee.bravo", "alpha.yankee.bravo.charlie", "alpha.yankee", "alpha.yankee.echo", "golf", "golf.hotel"},
+	{"alpha", "alpha.yankee.bravo", "alpha.yankee.bravo.charlie", "alpha.yankee", "alpha.yankee.echo"},
+}
+
+func Test_folder_EventStoreAsOfSeq(t *testing.T) {
+	t.Parallel()
+	// snapshots don't change what is rebuilt
+	for _, every := range []int{1, 2, 3, 0} {
+		store := eventStore(t, folder.EventStoreOptions{SnapshotEvery: every})
+		assert.Equal(t, uint64(4), store.Seq())
+		for seq, want := range weekPaths {
+			d, err := store.AsOfSeq(uint64(seq))
+			require.NoError(t, err)
+			assert.ElementsMatch(t, want, paths(d.GetFoldersByOrgID(foldertest.OrgA)), "seq %d, snapshot every %d", seq, every)
+			assert.Len(t, d.GetFoldersByOrgID(foldertest.OrgB), 1)
+		}
+		_, err := store.AsOfSeq(5)
+		assert.ErrorIs(t, err, folder.ErrUnknownSeq)
+	}
+}
+
+func Test_folder_EventStoreAsOf(t *testing.T) {
+	t.Parallel()
+	store := eventStore(t, folder.EventStoreOptions{})
+	day := 24 * time.Hour
+
+	// where was bravo last tuesday?
+	tuesday, err := store.AsOf(monday.Add(day + 5*time.Hour))
+	require.NoError(t, err)
+	children, err := tuesday.GetAllChildFolders(foldertest.OrgA, "delta")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alpha.delta.bravo", "alpha.delta.bravo.charlie", "alpha.delta.echo"}, paths(children))
+
+	tests := [...]struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{"start of the history", monday, weekPaths[0]},
+		{"just before a change", monday.Add(2*day - time.Nanosecond), weekPaths[1]},
+		{"at a change", monday.Add(2 * day), weekPaths[2]},
+		{"long after", monday.Add(365 * day), weekPaths[4]},
+	}
+	for _, tt := range tests {
+		d, err := store.AsOf(tt.at)
+		require.NoError(t, err, tt.name)
+		assert.ElementsMatch(t, tt.want, paths(d.GetFoldersByOrgID(foldertest.OrgA)), tt.name)
+	}
+
+	_, err = store.AsOf(monday.Add(-time.Second))
+	assert.ErrorIs(t, err, folder.ErrBeforeHistory)
+}
+
+func Test_folder_EventStoreReadOnly(t *testing.T) {
+	t.Parallel()
+	store := eventStore(t, folder.EventStoreOptions{})
+	d, err := store.AsOfSeq(1)
+	require.NoError(t, err)
+
+	_, err = d.MoveFolder("echo", "golf")
+	assert.ErrorIs(t, err, folder.ErrReadOnly)
+	_, ok := d.(folder.Editor)
+	assert.False(t, ok)
+	// rebuilding the same state again gives the same folders
+	again, err := store.AsOfSeq(1)
+	require.NoError(t, err)
+	assert.Equal(t, d.GetFoldersByOrgID(foldertest.OrgA), again.GetFoldersByOrgID(foldertest.OrgA))
+}
+
+func Test_folder_EventStoreRecords(t *testing.T) {
+	t.Parallel()
+	store := eventStore(t, folder.EventStoreOptions{})
+
+	records := store.Records(0)
+	types := []folder.EventType{}
+	for i, r := range records {
+		assert.Equal(t, uint64(i+1), r.Seq)
+		assert.Equal(t, monday.Add(time.Duration(i+1)*24*time.Hour), r.Time)
+		assert.Equal(t, foldertest.OrgA, r.OrgID)
+		types = append(types, r.Type)
+	}
+	assert.Equal(t, []folder.EventType{folder.FolderMoved, folder.FolderRenamed, folder.FolderCreated, folder.FolderDeleted}, types)
+	assert.Equal(t, "delta", records[1].Name)
+	assert.Equal(t, []folder.Change{
+		{Name: "yankee", OldName: "delta", OldPath: "alpha.delta", NewPath: "alpha.yankee"},
+		{Name: "bravo", OldPath: "alpha.delta.bravo", NewPath: "alpha.yankee.bravo"},
+		{Name: "charlie", OldPath: "alpha.delta.bravo.charlie", NewPath: "alpha.yankee.bravo.charlie"},
+		{Name: "echo", OldPath: "alpha.delta.echo", NewPath: "alpha.yankee.echo"},
+	}, records[1].Changes)
+	assert.Equal(t, []folder.Change{{Name: "golf", OldPath: "golf"}, {Name: "hotel", OldPath: "golf.hotel"}}, records[3].Changes)
+	assert.Len(t, store.Records(3), 1)
+	assert.Empty(t, store.Records(4))
+
+	require.NoError(t, store.Close())
+	_, err := store.Load()
+	assert.ErrorIs(t, err, folder.ErrStoreClosed)
+}
+
+func Test_folder_EventStoreClockSetBack(t *testing.T) {
+	t.Parallel()
+	times := []time.Time{monday, monday.Add(2 * time.Hour), monday.Add(time.Hour), monday.Add(3 * time.Hour)}
+	store := folder.NewEventStore(foldertest.MoveData(), folder.EventStoreOptions{Now: func() time.Time {
+		now := times[0]
+		times = times[1:]
+		return now
+	}})
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+	for _, dst := range []string{"delta", "golf", "alpha"} {
+		_, err = d.MoveFolder("bravo", dst)
+		require.NoError(t, err)
+	}
+
+	// the third record is stamped like the second, not an hour before it
+	records := store.Records(0)
+	require.Len(t, records, 3)
+	assert.Equal(t, monday.Add(2*time.Hour), records[1].Time)
+
+	// before the first move
+	at, err := store.AsOf(monday.Add(90 * time.Minute))
+	require.NoError(t, err)
+	assert.Contains(t, paths(at.GetFoldersByOrgID(foldertest.OrgA)), "alpha.bravo")
+	// after both moves stamped at the same time
+	at, err = store.AsOf(monday.Add(2 * time.Hour))
+	require.NoError(t, err)
+	assert.Contains(t, paths(at.GetFoldersByOrgID(foldertest.OrgA)), "golf.bravo")
+}
+
+func Test_folder_EventStoreUndoneDelete(t *testing.T) {
+	t.Parallel()
+	store := folder.NewEventStore(foldertest.MoveData(), folder.EventStoreOptions{})
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+	h, err := folder.NewHistory(d, folder.HistoryOptions{})
+	require.NoError(t, err)
+
+	_, err = h.DeleteFolder(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+
+	// the subtree comes back as one event, and replaying the log gives the same folders
+	records := store.Records(1)
+	require.Len(t, records, 1)
+	assert.Equal(t, folder.FolderCreated, records[0].Type)
+	assert.Equal(t, []folder.Change{{Name: "bravo", NewPath: "alpha.bravo"}, {Name: "charlie", NewPath: "alpha.bravo.charlie"}}, records[0].Changes)
+	at, err := store.AsOfSeq(2)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, d.GetFoldersByOrgID(foldertest.OrgA), at.GetFoldersByOrgID(foldertest.OrgA))
+}
+
+func Test_folder_EventStoreUnknownMutation(t *testing.T) {
+	t.Parallel()
+	store := folder.NewEventStore(foldertest.MoveData(), folder.EventStoreOptions{})
+	assert.ErrorIs(t, store.Apply(folder.Mutation{Op: "copy"}), folder.ErrUnknownMutation)
+	assert.Equal(t, uint64(0), store.Seq())
+}
@@ -0,0 +1,321 @@
+package folder
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// EventType is the kind of change an Event describes.
+type EventType string
+
+const (
+	FolderCreated EventType = "folder.created"
+	FolderMoved   EventType = "folder.moved"
+	FolderRenamed EventType = "folder.renamed"
+	FolderDeleted EventType = "folder.deleted"
+)
+
+// Event is one successful change to the tree of an org.
+type Event struct {
+	Type  EventType `json:"type"`
+	OrgID uuid.UUID `json:"org_id"`
+	// numbers the events of an org from 1, in the order the changes happened
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// the folder the change was made to, by its name before the change
+	Name string `json:"name"`
+	// every affected folder ordered by path, so the folder itself comes first
+	Changes []Change `json:"changes"`
+}
+
+// Change is what happened to one folder. OldPath is empty for a created
+// folder and NewPath for a deleted one.
+type Change struct {
+	Name string `json:"name"`
+	// the name before a rename, empty otherwise
+	OldName string `json:"old_name,omitempty"`
+	OldPath string `json:"old_path,omitempty"`
+	NewPath string `json:"new_path,omitempty"`
+}
+
+// SlowConsumerPolicy is what happens when an event arrives for a
+// subscription whose buffer is full.
+type SlowConsumerPolicy int
+
+const (
+	// DisconnectSlow ends the subscription, its channel is closed and Err
+	// returns ErrSlowConsumer. Changes never wait for subscribers.
+	DisconnectSlow SlowConsumerPolicy = iota
+	// BlockPublisher makes the change wait until there is room in the buffer,
+	// every other subscriber and change waits with it.
+	BlockPublisher
+)
+
+const (
+	// DefaultEventBuffer is the buffer of a subscription that doesn't set one.
+	DefaultEventBuffer = 64
+	// DefaultEventHistory is how many events of each org a bus keeps for replay.
+	DefaultEventHistory = 256
+)
+
+var ErrSlowConsumer = errors.New("Error: Subscriber fell behind and was disconnected")
+
+// SubscribeOptions selects the events of a subscription and how they are buffered.
+type SubscribeOptions struct {
+	// only events of this org, every org when uuid.Nil
+	OrgID uuid.UUID
+	// only events that change a folder at or below this path, every event when empty
+	Subtree string
+	// events held for the subscriber, DefaultEventBuffer when zero
+	Buffer int
+	Policy SlowConsumerPolicy
+	// replay the events of OrgID with a Seq after this one that are still in
+	// the history before the live ones, 0 doesn't replay anything
+	After uint64
+}
+
+func (o SubscribeOptions) match(e Event) bool {
+	if o.OrgID != uuid.Nil && o.OrgID != e.OrgID {
+		return false
+	}
+	if o.Subtree == "" {
+		return true
+	}
+	for _, c := range e.Changes {
+		if inSubtree(c.OldPath, o.Subtree) || inSubtree(c.NewPath, o.Subtree) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortChanges orders changes by path, old paths first as new ones are empty for deletes
+func sortChanges(changes []Change) {
+	path := func(c Change) string {
+		if c.OldPath != "" {
+			return c.OldPath
+		}
+		return c.NewPath
+	}
+	sort.Slice(changes, func(i, j int) bool { return path(changes[i]) < path(changes[j]) })
+}
+
+func inSubtree(path, root string) bool {
+	return path == root || strings.HasPrefix(path, root) && len(path) > len(root) && path[len(root)] == '.'
+}
+
+// Notifier is implemented by drivers that publish an Event for every change.
+type Notifier interface {
+	Subscribe(opts SubscribeOptions) *Subscription
+}
+
+// EventBus numbers events per org and delivers them to subscriptions. Events
+// are delivered in the order they are published, so every subscriber sees
+// the events of an org in Seq order.
+type EventBus struct {
+	opts EventBusOptions
+	// serializes Publish, so events reach every subscription in order even
+	// while a BlockPublisher subscription is waited for without mu
+	pub  sync.Mutex
+	mu   sync.Mutex
+	seq  map[uuid.UUID]uint64
+	subs map[*Subscription]struct{}
+	// the latest events of each org, oldest first
+	history map[uuid.UUID][]Event
+}
+
+type EventBusOptions struct {
+	// events of each org kept for SubscribeOptions.After, DefaultEventHistory when zero
+	History int
+}
+
+func NewEventBus(opts EventBusOptions) *EventBus {
+	if opts.History <= 0 {
+		opts.History = DefaultEventHistory
+	}
+	return &EventBus{
+		opts:    opts,
+		seq:     map[uuid.UUID]uint64{},
+		subs:    map[*Subscription]struct{}{},
+		history: map[uuid.UUID][]Event{},
+	}
+}
+
+// Subscription receives the events that match its options on C until it is
+// closed. C is closed when the subscription ends.
+type Subscription struct {
+	C <-chan Event
+	// some events after SubscribeOptions.After had already left the history,
+	// the subscriber has to reload the folders of the org to catch up
+	Missed bool
+	// the Seq of the latest event of SubscribeOptions.OrgID when the
+	// subscription started, the live events come after it. Subscribing again
+	// with it as After resumes without losing events.
+	Start uint64
+
+	bus  *EventBus
+	opts SubscribeOptions
+	ch   chan Event
+	// closed by Close so a blocked publisher gives up on this subscription
+	done chan struct{}
+	once sync.Once
+	// held by a publisher waiting on a BlockPublisher subscription, Close
+	// takes it before closing ch
+	sending sync.Mutex
+	// why the subscription ended, guarded by bus.mu
+	err error
+}
+
+// Subscribe starts a subscription, it must be closed when no longer needed.
+func (b *EventBus) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultEventBuffer
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// replayed events don't count against the buffer
+	replay := []Event{}
+	missed := false
+	if opts.After > 0 {
+		history := b.history[opts.OrgID]
+		if len(history) > b.opts.History {
+			history = history[len(history)-b.opts.History:]
+		}
+		// the history holds the latest events of the org without gaps
+		last := b.seq[opts.OrgID]
+		first := last - uint64(len(history)) + 1
+		missed = opts.After > last || opts.After+1 < first
+		for _, e := range history {
+			if e.Seq > opts.After && opts.match(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+
+	ch := make(chan Event, opts.Buffer+len(replay))
+	for _, e := range replay {
+		ch <- e
+	}
+	sub := &Subscription{C: ch, Missed: missed, Start: b.seq[opts.OrgID], bus: b, opts: opts, ch: ch, done: make(chan struct{})}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish numbers e within its org, stamps it with the current time when
+// it has none and delivers it to every matching subscription.
+func (b *EventBus) Publish(e Event) Event {
+	b.pub.Lock()
+	defer b.pub.Unlock()
+
+	b.mu.Lock()
+	b.seq[e.OrgID]++
+	e.Seq = b.seq[e.OrgID]
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	// trimmed in batches so publishing doesn't copy the history every time
+	history := append(b.history[e.OrgID], e)
+	if len(history) >= 2*b.opts.History {
+		history = append([]Event{}, history[len(history)-b.opts.History:]...)
+	}
+	b.history[e.OrgID] = history
+
+	blocking := []*Subscription{}
+	for sub := range b.subs {
+		if !sub.opts.match(e) {
+			continue
+		}
+		if sub.opts.Policy == BlockPublisher {
+			blocking = append(blocking, sub)
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			b.end(sub, ErrSlowConsumer)
+		}
+	}
+	b.mu.Unlock()
+
+	// waited for without mu, so Subscribe, Close and Err don't wait for them
+	for _, sub := range blocking {
+		sub.send(e)
+	}
+	return e
+}
+
+// send waits until the subscriber takes e or the subscription is closed
+func (s *Subscription) send(e Event) {
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	select {
+	case <-s.done:
+		// ch may be closed already
+		return
+	default:
+	}
+	select {
+	case s.ch <- e:
+	case <-s.done:
+	}
+}
+
+// end removes sub and closes its channel, the caller holds b.mu
+func (b *EventBus) end(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+// Close ends the subscription. Events still buffered can be read from C.
+func (s *Subscription) Close() {
+	s.once.Do(func() { close(s.done) })
+	// a publisher waiting in send gives up now that done is closed
+	s.sending.Lock()
+	defer s.sending.Unlock()
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.end(s, nil)
+}
+
+// Err returns ErrSlowConsumer when the subscription was ended because it fell
+// behind, nil while it is running or after Close.
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+// Subscribe starts a subscription to the changes made through the driver.
+// Changes are only published once something subscribed, so the Seq of the
+// first event of an org is 1 even when earlier changes were made.
+func (f *driver) Subscribe(opts SubscribeOptions) *Subscription {
+	f.events.CompareAndSwap(nil, NewEventBus(EventBusOptions{}))
+	return f.events.Load().Subscribe(opts)
+}
+
+// notifying reports whether changes have to be published, events aren't
+// built while nothing subscribed
+func (f *driver) notifying() bool {
+	return f.events.Load() != nil
+}
+
+// publish sends the event of a change that has been made
+func (f *driver) publish(e Event) {
+	bus := f.events.Load()
+	if bus == nil {
+		return
+	}
+	sortChanges(e.Changes)
+	bus.Publish(e)
+}
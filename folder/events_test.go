@@ -0,0 +1,277 @@
+package folder_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drain returns the events buffered in sub, without their time
+func drain(sub *folder.Subscription) []folder.Event {
+	res := []folder.Event{}
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return res
+			}
+			e.Time = time.Time{}
+			res = append(res, e)
+		default:
+			return res
+		}
+	}
+}
+
+func Test_folder_DriverEvents(t *testing.T) {
+	t.Parallel()
+	d, e := editor(t)
+	sub := d.(folder.Notifier).Subscribe(folder.SubscribeOptions{})
+	defer sub.Close()
+
+	_, err := d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	_, err = e.CreateFolder(foldertest.OrgB, "hotel", "foxtrot")
+	require.NoError(t, err)
+	_, err = e.RenameFolder(foldertest.OrgA, "delta", "yankee")
+	require.NoError(t, err)
+	_, err = e.DeleteFolder(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	// failed changes have no event
+	_, err = d.MoveFolder("golf", "foxtrot")
+	require.Error(t, err)
+
+	assert.Equal(t, []folder.Event{
+		{Type: folder.FolderMoved, OrgID: foldertest.OrgA, Seq: 1, Name: "bravo", Changes: []folder.Change{
+			{Name: "bravo", OldPath: "alpha.bravo", NewPath: "alpha.delta.bravo"},
+			{Name: "charlie", OldPath: "alpha.bravo.charlie", NewPath: "alpha.delta.bravo.charlie"},
+		}},
+		{Type: folder.FolderCreated, OrgID: foldertest.OrgB, Seq: 1, Name: "hotel", Changes: []folder.Change{
+			{Name: "hotel", NewPath: "foxtrot.hotel"},
+		}},
+		{Type: folder.FolderRenamed, OrgID: foldertest.OrgA, Seq: 2, Name: "delta", Changes: []folder.Change{
+			{Name: "yankee", OldName: "delta", OldPath: "alpha.delta", NewPath: "alpha.yankee"},
+			{Name: "bravo", OldPath: "alpha.delta.bravo", NewPath: "alpha.yankee.bravo"},
+			{Name: "charlie", OldPath: "alpha.delta.bravo.charlie", NewPath: "alpha.yankee.bravo.charlie"},
+			{Name: "echo", OldPath: "alpha.delta.echo", NewPath: "alpha.yankee.echo"},
+		}},
+		{Type: folder.FolderDeleted, OrgID: foldertest.OrgA, Seq: 3, Name: "bravo", Changes: []folder.Change{
+			{Name: "bravo", OldPath: "alpha.yankee.bravo"},
+			{Name: "charlie", OldPath: "alpha.yankee.bravo.charlie"},
+		}},
+	}, drain(sub))
+}
+
+func Test_folder_EventsStartWithFirstSubscriber(t *testing.T) {
+	t.Parallel()
+	d, _ := editor(t)
+	// nothing is published before something subscribed
+	_, err := d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+
+	sub := d.(folder.Notifier).Subscribe(folder.SubscribeOptions{})
+	defer sub.Close()
+	_, err = d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	e := <-sub.C
+	assert.Equal(t, uint64(1), e.Seq)
+	assert.Equal(t, "alpha.delta.bravo", e.Changes[0].OldPath)
+}
+
+func Test_folder_SubscribeFilters(t *testing.T) {
+	t.Parallel()
+	d, e := editor(t)
+	n := d.(folder.Notifier)
+	orgB := n.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgB})
+	delta := n.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, Subtree: "alpha.delta"})
+	golf := n.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, Subtree: "golf"})
+	defer orgB.Close()
+	defer delta.Close()
+	defer golf.Close()
+
+	// leaves alpha.delta, enters golf
+	_, err := d.MoveFolder("echo", "golf")
+	require.NoError(t, err)
+	// "alpha.de" is not a subtree of "alpha.delta"
+	_, err = e.CreateFolder(foldertest.OrgA, "de", "alpha")
+	require.NoError(t, err)
+	_, err = e.CreateFolder(foldertest.OrgB, "hotel", "")
+	require.NoError(t, err)
+
+	types := func(events []folder.Event) []string {
+		res := []string{}
+		for _, e := range events {
+			res = append(res, string(e.Type)+" "+e.Name)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"folder.created hotel"}, types(drain(orgB)))
+	assert.Equal(t, []string{"folder.moved echo"}, types(drain(delta)))
+	assert.Equal(t, []string{"folder.moved echo"}, types(drain(golf)))
+}
+
+func Test_folder_SlowConsumerIsDisconnected(t *testing.T) {
+	t.Parallel()
+	bus := folder.NewEventBus(folder.EventBusOptions{})
+	slow := bus.Subscribe(folder.SubscribeOptions{Buffer: 2})
+	fast := bus.Subscribe(folder.SubscribeOptions{Buffer: 10})
+	defer fast.Close()
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(folder.Event{Type: folder.FolderCreated, OrgID: foldertest.OrgA})
+	}
+
+	// the buffered events are still delivered, then the channel is closed
+	assert.Len(t, drain(slow), 2)
+	_, open := <-slow.C
+	assert.False(t, open)
+	assert.ErrorIs(t, slow.Err(), folder.ErrSlowConsumer)
+	slow.Close()
+
+	assert.Len(t, drain(fast), 3)
+	assert.NoError(t, fast.Err())
+}
+
+func Test_folder_BlockingSubscriber(t *testing.T) {
+	t.Parallel()
+	bus := folder.NewEventBus(folder.EventBusOptions{})
+	sub := bus.Subscribe(folder.SubscribeOptions{Buffer: 1, Policy: folder.BlockPublisher})
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 5; i++ {
+			bus.Publish(folder.Event{Type: folder.FolderCreated, OrgID: foldertest.OrgA})
+		}
+	}()
+
+	// every event arrives, in order, however slowly they are read
+	for want := uint64(1); want <= 5; want++ {
+		time.Sleep(time.Millisecond)
+		e := <-sub.C
+		assert.Equal(t, want, e.Seq)
+	}
+	<-published
+	assert.NoError(t, sub.Err())
+
+	// closing a subscription releases a publisher waiting on it
+	bus.Publish(folder.Event{OrgID: foldertest.OrgA})
+	done := make(chan struct{})
+	go func() {
+		bus.Publish(folder.Event{OrgID: foldertest.OrgA})
+		close(done)
+	}()
+	time.Sleep(time.Millisecond)
+	// while it waits, other subscriptions can start and end
+	other := bus.Subscribe(folder.SubscribeOptions{})
+	other.Close()
+	assert.NoError(t, sub.Err())
+	sub.Close()
+	<-done
+}
+
+func Test_folder_EventsAreOrderedPerOrg(t *testing.T) {
+	t.Parallel()
+	bus := folder.NewEventBus(folder.EventBusOptions{})
+	sub := bus.Subscribe(folder.SubscribeOptions{Buffer: 1000})
+	defer sub.Close()
+
+	var wg sync.WaitGroup
+	for _, orgID := range []uuid.UUID{foldertest.OrgA, foldertest.OrgB} {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: orgID})
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	last := map[string]uint64{}
+	for _, e := range drain(sub) {
+		assert.Equal(t, last[e.OrgID.String()]+1, e.Seq)
+		last[e.OrgID.String()] = e.Seq
+	}
+	assert.Equal(t, map[string]uint64{foldertest.OrgA.String(): 200, foldertest.OrgB.String(): 200}, last)
+}
+
+func Test_folder_SubscribeReplay(t *testing.T) {
+	t.Parallel()
+	bus := folder.NewEventBus(folder.EventBusOptions{History: 3})
+	for i := 0; i < 5; i++ {
+		bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgA})
+	}
+	bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgB})
+
+	tests := [...]struct {
+		after  uint64
+		want   []uint64
+		missed bool
+	}{
+		{after: 0, want: []uint64{}},
+		{after: 2, want: []uint64{3, 4, 5}},
+		{after: 4, want: []uint64{5}},
+		{after: 5, want: []uint64{}},
+		// seq 2 is gone from the history
+		{after: 1, want: []uint64{3, 4, 5}, missed: true},
+		// from a history the bus never had, e.g. before a restart
+		{after: 9, want: []uint64{}, missed: true},
+	}
+	for _, tt := range tests {
+		sub := bus.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, After: tt.after})
+		seqs := []uint64{}
+		for _, e := range drain(sub) {
+			seqs = append(seqs, e.Seq)
+		}
+		assert.Equal(t, tt.want, seqs, "after %d", tt.after)
+		assert.Equal(t, tt.missed, sub.Missed, "after %d", tt.after)
+		sub.Close()
+	}
+
+	// replayed events come before live ones
+	sub := bus.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, After: 4, Buffer: 1})
+	defer sub.Close()
+	bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgA})
+	seqs := []uint64{}
+	for _, e := range drain(sub) {
+		seqs = append(seqs, e.Seq)
+	}
+	assert.Equal(t, []uint64{5, 6}, seqs)
+}
+
+func Test_folder_SubscribeStart(t *testing.T) {
+	t.Parallel()
+	bus := folder.NewEventBus(folder.EventBusOptions{})
+	sub := bus.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA})
+	assert.Equal(t, uint64(0), sub.Start)
+	sub.Close()
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgA})
+	}
+	sub = bus.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, Buffer: 1})
+	assert.Equal(t, uint64(3), sub.Start)
+	// the second event overflows the buffer before any is read
+	for i := 0; i < 2; i++ {
+		bus.Publish(folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgA})
+	}
+	require.ErrorIs(t, sub.Err(), folder.ErrSlowConsumer)
+
+	// resuming from the start of the first subscription loses nothing
+	again := bus.Subscribe(folder.SubscribeOptions{OrgID: foldertest.OrgA, After: sub.Start})
+	defer again.Close()
+	seqs := []uint64{}
+	for _, e := range drain(again) {
+		seqs = append(seqs, e.Seq)
+	}
+	assert.Equal(t, []uint64{4, 5}, seqs)
+}
@@ -0,0 +1,259 @@
+package folder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileStore is a Store kept in a directory with two files:
+//
+//	snapshot.json  the full folder set as of a journal sequence number, replaced atomically
+//	journal.ndjson one record per mutation applied after the snapshot
+//
+// Every record is fsynced before Apply returns. On open the snapshot is loaded and
+// the journal replayed on top of it. A crash while appending can only leave a torn
+// final record, which is detected by its checksum and cut off. Records already
+// covered by the snapshot (a crash between writing the snapshot and emptying the
+// journal) are skipped by sequence number.
+type FileStore struct {
+	mu      sync.Mutex
+	dir     string
+	opts    FileStoreOptions
+	journal *os.File
+	// journal size after the last good record
+	offset int64
+
+	folders []Folder
+	index   map[folderKey]int
+	// sequence number of the last applied mutation and of the snapshot
+	seq         uint64
+	snapshotSeq uint64
+	closed      bool
+}
+
+type FileStoreOptions struct {
+	// write a new snapshot and empty the journal after this many records, 0 never compacts automatically
+	CompactEvery int
+}
+
+const (
+	snapshotFile = "snapshot.json"
+	journalFile  = "journal.ndjson"
+)
+
+var ErrCorruptJournal = errors.New("Error: Journal is corrupt")
+
+type snapshot struct {
+	Seq     uint64   `json:"seq"`
+	Folders []Folder `json:"folders"`
+}
+
+type journalRecord struct {
+	Seq      uint64          `json:"seq"`
+	CRC      uint32          `json:"crc"`
+	Mutation json.RawMessage `json:"mutation"`
+}
+
+// OpenFileStore opens the store in dir, creating it from seed when dir has no snapshot yet.
+func OpenFileStore(dir string, seed []Folder, opts FileStoreOptions) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir, opts: opts}
+
+	snap, err := readSnapshot(filepath.Join(dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		snap = snapshot{Folders: append([]Folder{}, seed...)}
+		err = s.writeSnapshot(snap)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.folders = snap.Folders
+	s.index = indexFolders(s.folders)
+	s.seq, s.snapshotSeq = snap.Seq, snap.Seq
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_RDWR|os.O_CREATE, FileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replay(journal); err != nil {
+		journal.Close()
+		return nil, err
+	}
+	s.journal = journal
+	return s, nil
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return snapshot{}, fmt.Errorf("Error: reading snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+func (s *FileStore) writeSnapshot(snap snapshot) error {
+	return writeAtomic(filepath.Join(s.dir, snapshotFile), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snap)
+	})
+}
+
+// replay applies every journal record after the snapshot and cuts off a torn final record
+func (s *FileStore) replay(journal *os.File) error {
+	r := bufio.NewReader(journal)
+	var offset int64
+
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// anything without a trailing newline is a write that never finished
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		m, seq, ok := decodeRecord(line)
+		if !ok {
+			// only the last record can be torn, anything after it means real corruption
+			if _, err := r.Peek(1); err != io.EOF {
+				return fmt.Errorf("%w: bad record at offset %d", ErrCorruptJournal, offset)
+			}
+			break
+		}
+
+		if seq > s.seq {
+			if seq != s.seq+1 {
+				return fmt.Errorf("%w: expected sequence %d, found %d", ErrCorruptJournal, s.seq+1, seq)
+			}
+			s.folders = applyMutation(s.folders, s.index, m)
+			s.seq = seq
+		}
+		offset += int64(len(line))
+	}
+
+	s.offset = offset
+	if err := journal.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := journal.Seek(offset, io.SeekStart)
+	return err
+}
+
+func decodeRecord(line []byte) (Mutation, uint64, bool) {
+	var rec journalRecord
+	if err := json.Unmarshal(bytes.TrimSpace(line), &rec); err != nil {
+		return Mutation{}, 0, false
+	}
+	if crc32.ChecksumIEEE(rec.Mutation) != rec.CRC {
+		return Mutation{}, 0, false
+	}
+	var m Mutation
+	if err := json.Unmarshal(rec.Mutation, &m); err != nil {
+		return Mutation{}, 0, false
+	}
+	return m, rec.Seq, true
+}
+
+func (s *FileStore) Load() ([]Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	return append([]Folder{}, s.folders...), nil
+}
+
+func (s *FileStore) Apply(m Mutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(journalRecord{Seq: s.seq + 1, CRC: crc32.ChecksumIEEE(raw), Mutation: raw})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := s.journal.Write(line); err != nil {
+		// drop whatever part of the record made it out so the journal stays clean
+		s.rewind()
+		return err
+	}
+	if err := s.journal.Sync(); err != nil {
+		s.rewind()
+		return err
+	}
+	s.offset += int64(len(line))
+	s.seq++
+	s.folders = applyMutation(s.folders, s.index, m)
+
+	if s.opts.CompactEvery > 0 && s.seq-s.snapshotSeq >= uint64(s.opts.CompactEvery) {
+		// the record is durable and applied already, a failed compaction keeps
+		// the journal and is tried again by the next Apply
+		_ = s.compact()
+	}
+	return nil
+}
+
+func (s *FileStore) rewind() {
+	if s.journal.Truncate(s.offset) == nil {
+		s.journal.Seek(s.offset, io.SeekStart)
+	}
+}
+
+// Compact writes a snapshot of the current folders and empties the journal.
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	return s.compact()
+}
+
+func (s *FileStore) compact() error {
+	if err := s.writeSnapshot(snapshot{Seq: s.seq, Folders: s.folders}); err != nil {
+		return err
+	}
+	s.snapshotSeq = s.seq
+
+	// a crash before this point leaves records the snapshot already covers, replay skips them
+	if err := s.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.offset = 0
+	return s.journal.Sync()
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.journal.Close()
+}
@@ -1,6 +1,10 @@
 package folder
 
-import "github.com/gofrs/uuid"
+import (
+	"sync/atomic"
+
+	"github.com/gofrs/uuid"
+)
 
 type IDriver interface {
 	// GetFoldersByOrgID returns all folders that belong to a specific orgID.
@@ -23,11 +27,67 @@ type driver struct {
 
 	// example: feel free to change the data structure, if slice is not what you want
 	folders []Folder
+
+	// indexes of every folder with a given name, in the order they appear in folders
+	// this turns the existence checks into a map lookup instead of a full scan
+	byName map[string][]int
+
+	// where changes are persisted, nil when the driver only lives in memory
+	store Store
+
+	// publishes an Event for every change, created by the first Subscribe
+	events atomic.Pointer[EventBus]
 }
 
 func NewDriver(folders []Folder) IDriver {
-	return &driver{
+	return newDriver(folders)
+}
+
+func newDriver(folders []Folder) *driver {
+	f := &driver{
 		// initialize attributes here
 		folders: folders,
+		byName:  make(map[string][]int, len(folders)),
 	}
+	f.reindex()
+	return f
+}
+
+// reindex rebuilds byName after folders were removed or renamed
+func (f *driver) reindex() {
+	clear(f.byName)
+	for i, folder := range f.folders {
+		f.byName[folder.Name] = append(f.byName[folder.Name], i)
+	}
+}
+
+// add appends a folder and indexes it
+func (f *driver) add(folder Folder) {
+	f.byName[folder.Name] = append(f.byName[folder.Name], len(f.folders))
+	f.folders = append(f.folders, folder)
+}
+
+// Builder builds a driver one folder at a time so the indexes are
+// populated while the data is still being read, see LoadDriver.
+type Builder struct {
+	d *driver
+}
+
+func NewBuilder() *Builder {
+	return &Builder{d: newDriver(nil)}
+}
+
+// Add appends a folder to the driver being built.
+func (b *Builder) Add(folder Folder) {
+	b.d.add(folder)
+}
+
+// Len returns how many folders have been added so far.
+func (b *Builder) Len() int {
+	return len(b.d.folders)
+}
+
+// Driver returns the built driver. The builder should not be used afterwards.
+func (b *Builder) Driver() IDriver {
+	return b.d
 }
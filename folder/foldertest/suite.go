@@ -0,0 +1,323 @@
+// Package foldertest is a conformance suite for folder.IDriver implementations.
+//
+//	func TestMyDriver(t *testing.T) {
+//		foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+//			return mydriver.New(folders)
+//		})
+//	}
+//
+// Every README scenario plus edge cases around children, moves, errors and
+// org isolation is run against a fresh driver built by the factory. Folder
+// order in results is not part of the contract, so results are compared as sets.
+package foldertest
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns a driver holding exactly folders. It is called once per
+// sub test so implementations with shared state must return a fresh copy.
+// Each call gets its own copy of the folders, a driver may keep and change it.
+type Factory func(t *testing.T, folders []folder.Folder) folder.IDriver
+
+var (
+	OrgA = uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	OrgB = uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")
+	// an org with no folders
+	OrgEmpty = uuid.FromStringOrNil("9b4cdb0d-5c4e-4f2a-9b1f-0d0c6f0b1a11")
+)
+
+// ChildrenData is the data from the component 1 README example.
+func ChildrenData() []folder.Folder {
+	return []folder.Folder{
+		{Name: "alpha", OrgId: OrgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: OrgA, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: OrgA, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: OrgA, Paths: "alpha.delta"},
+		{Name: "echo", OrgId: OrgA, Paths: "echo"},
+		{Name: "foxtrot", OrgId: OrgB, Paths: "foxtrot"},
+	}
+}
+
+// MoveData is the data from the component 2 README example.
+func MoveData() []folder.Folder {
+	return []folder.Folder{
+		{Name: "alpha", OrgId: OrgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: OrgA, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: OrgA, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: OrgA, Paths: "alpha.delta"},
+		{Name: "echo", OrgId: OrgA, Paths: "alpha.delta.echo"},
+		{Name: "foxtrot", OrgId: OrgB, Paths: "foxtrot"},
+		{Name: "golf", OrgId: OrgA, Paths: "golf"},
+	}
+}
+
+// EdgeData has folders whose names share prefixes, deep chains and an org
+// with a path that also exists in another org.
+func EdgeData() []folder.Folder {
+	return []folder.Folder{
+		{Name: "alpha", OrgId: OrgA, Paths: "alpha"},
+		{Name: "alphabet", OrgId: OrgA, Paths: "alphabet"},
+		{Name: "soup", OrgId: OrgA, Paths: "alphabet.soup"},
+		{Name: "one", OrgId: OrgA, Paths: "alpha.one"},
+		{Name: "two", OrgId: OrgA, Paths: "alpha.one.two"},
+		{Name: "three", OrgId: OrgA, Paths: "alpha.one.two.three"},
+		{Name: "four", OrgId: OrgA, Paths: "alpha.one.two.three.four"},
+		{Name: "beta", OrgId: OrgB, Paths: "alpha"},
+		{Name: "gamma", OrgId: OrgB, Paths: "alpha.gamma"},
+	}
+}
+
+// RunDriverSuite runs the whole conformance suite.
+func RunDriverSuite(t *testing.T, factory Factory) {
+	t.Run("GetFoldersByOrgID", func(t *testing.T) { runGetFoldersByOrgID(t, factory) })
+	t.Run("GetAllChildFolders", func(t *testing.T) { runGetAllChildFolders(t, factory) })
+	t.Run("MoveFolder", func(t *testing.T) { runMoveFolder(t, factory) })
+	t.Run("MoveFolderErrors", func(t *testing.T) { runMoveFolderErrors(t, factory) })
+	t.Run("MoveFolderSequence", func(t *testing.T) { runMoveFolderSequence(t, factory) })
+}
+
+func runGetFoldersByOrgID(t *testing.T, factory Factory) {
+	tests := []struct {
+		name    string
+		folders []folder.Folder
+		orgID   uuid.UUID
+		want    []folder.Folder
+	}{
+		{name: "org with many folders", folders: ChildrenData(), orgID: OrgA, want: ChildrenData()[:5]},
+		{name: "org with one folder", folders: ChildrenData(), orgID: OrgB, want: ChildrenData()[5:]},
+		{name: "unknown org", folders: ChildrenData(), orgID: OrgEmpty, want: []folder.Folder{}},
+		{name: "nil org", folders: ChildrenData(), orgID: uuid.Nil, want: []folder.Folder{}},
+		{name: "no folders at all", folders: []folder.Folder{}, orgID: OrgA, want: []folder.Folder{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := factory(t, append([]folder.Folder{}, tt.folders...)).GetFoldersByOrgID(tt.orgID)
+			require.NotNil(t, got, "an empty result must be an empty slice, not nil")
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func runGetAllChildFolders(t *testing.T, factory Factory) {
+	tests := []struct {
+		name       string
+		folders    []folder.Folder
+		orgID      uuid.UUID
+		folderName string
+		want       []folder.Folder
+		wantErr    error
+	}{
+		{
+			name: "README alpha", folders: ChildrenData(), orgID: OrgA, folderName: "alpha",
+			want: ChildrenData()[1:4],
+		},
+		{
+			name: "README bravo", folders: ChildrenData(), orgID: OrgA, folderName: "bravo",
+			want: ChildrenData()[2:3],
+		},
+		{name: "README charlie", folders: ChildrenData(), orgID: OrgA, folderName: "charlie", want: []folder.Folder{}},
+		{name: "README echo", folders: ChildrenData(), orgID: OrgA, folderName: "echo", want: []folder.Folder{}},
+		{
+			name: "README invalid folder", folders: ChildrenData(), orgID: OrgA, folderName: "invalid_folder",
+			wantErr: folder.ErrFolderNotFound,
+		},
+		{
+			name: "README folder in another org", folders: ChildrenData(), orgID: OrgA, folderName: "foxtrot",
+			wantErr: folder.ErrFolderNotInOrg,
+		},
+		{
+			name: "empty name", folders: ChildrenData(), orgID: OrgA, folderName: "",
+			wantErr: folder.ErrFolderNotFound,
+		},
+		{
+			name: "unknown org", folders: ChildrenData(), orgID: OrgEmpty, folderName: "alpha",
+			wantErr: folder.ErrFolderNotInOrg,
+		},
+		{
+			name: "names sharing a prefix are not children", folders: EdgeData(), orgID: OrgA, folderName: "alpha",
+			want: EdgeData()[3:7],
+		},
+		{
+			name: "deep chain", folders: EdgeData(), orgID: OrgA, folderName: "two",
+			want: EdgeData()[5:7],
+		},
+		{
+			name: "same path in another org is isolated", folders: EdgeData(), orgID: OrgB, folderName: "beta",
+			want: EdgeData()[8:9],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := factory(t, append([]folder.Folder{}, tt.folders...)).GetAllChildFolders(tt.orgID, tt.folderName)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, got, "an empty result must be an empty slice, not nil")
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+// withPaths returns a copy of folders with the named folders moved to new paths
+func withPaths(folders []folder.Folder, paths map[string]string) []folder.Folder {
+	res := make([]folder.Folder, len(folders))
+	for i, f := range folders {
+		if p, ok := paths[f.Name]; ok {
+			f.Paths = p
+		}
+		res[i] = f
+	}
+	return res
+}
+
+func runMoveFolder(t *testing.T, factory Factory) {
+	tests := []struct {
+		name    string
+		folders []folder.Folder
+		src     string
+		dst     string
+		want    []folder.Folder
+	}{
+		{
+			name: "README bravo to delta", folders: MoveData(), src: "bravo", dst: "delta",
+			want: withPaths(MoveData(), map[string]string{"bravo": "alpha.delta.bravo", "charlie": "alpha.delta.bravo.charlie"}),
+		},
+		{
+			name: "README bravo to golf", folders: MoveData(), src: "bravo", dst: "golf",
+			want: withPaths(MoveData(), map[string]string{"bravo": "golf.bravo", "charlie": "golf.bravo.charlie"}),
+		},
+		{
+			name: "root with subtree", folders: MoveData(), src: "alpha", dst: "golf",
+			want: withPaths(MoveData(), map[string]string{
+				"alpha": "golf.alpha", "bravo": "golf.alpha.bravo", "charlie": "golf.alpha.bravo.charlie",
+				"delta": "golf.alpha.delta", "echo": "golf.alpha.delta.echo",
+			}),
+		},
+		{
+			name: "leaf into a sibling subtree", folders: MoveData(), src: "charlie", dst: "echo",
+			want: withPaths(MoveData(), map[string]string{"charlie": "alpha.delta.echo.charlie"}),
+		},
+		{
+			name: "up to a shallower folder", folders: MoveData(), src: "echo", dst: "alpha",
+			want: withPaths(MoveData(), map[string]string{"echo": "alpha.echo"}),
+		},
+		{
+			name: "to its current parent", folders: MoveData(), src: "bravo", dst: "alpha",
+			want: MoveData(),
+		},
+		{
+			name: "prefix sibling is not dragged along", folders: EdgeData(), src: "alpha", dst: "soup",
+			want: withPaths(EdgeData(), map[string]string{
+				"alpha": "alphabet.soup.alpha", "one": "alphabet.soup.alpha.one", "two": "alphabet.soup.alpha.one.two",
+				"three": "alphabet.soup.alpha.one.two.three", "four": "alphabet.soup.alpha.one.two.three.four",
+			}),
+		},
+		{
+			name: "same path in another org is untouched", folders: EdgeData(), src: "one", dst: "soup",
+			want: withPaths(EdgeData(), map[string]string{
+				"one": "alphabet.soup.one", "two": "alphabet.soup.one.two",
+				"three": "alphabet.soup.one.two.three", "four": "alphabet.soup.one.two.three.four",
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := factory(t, append([]folder.Folder{}, tt.folders...))
+			got, err := d.MoveFolder(tt.src, tt.dst)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+
+			// the move sticks
+			for _, orgID := range []uuid.UUID{OrgA, OrgB} {
+				want := []folder.Folder{}
+				for _, f := range tt.want {
+					if f.OrgId == orgID {
+						want = append(want, f)
+					}
+				}
+				assert.ElementsMatch(t, want, d.GetFoldersByOrgID(orgID))
+			}
+		})
+	}
+}
+
+func runMoveFolderErrors(t *testing.T, factory Factory) {
+	tests := []struct {
+		name    string
+		folders []folder.Folder
+		src     string
+		dst     string
+		wantErr error
+	}{
+		{name: "README to own child", folders: MoveData(), src: "bravo", dst: "charlie", wantErr: folder.ErrMoveToDescendant},
+		{name: "to deep descendant", folders: MoveData(), src: "alpha", dst: "charlie", wantErr: folder.ErrMoveToDescendant},
+		{name: "README to itself", folders: MoveData(), src: "bravo", dst: "bravo", wantErr: folder.ErrMoveToSelf},
+		{name: "README to another org", folders: MoveData(), src: "bravo", dst: "foxtrot", wantErr: folder.ErrMoveToOtherOrg},
+		{name: "README invalid source", folders: MoveData(), src: "invalid_folder", dst: "delta", wantErr: folder.ErrSourceNotFound},
+		{name: "README invalid destination", folders: MoveData(), src: "bravo", dst: "invalid_folder", wantErr: folder.ErrDestinationNotFound},
+		{name: "both invalid reports the source", folders: MoveData(), src: "nope", dst: "nada", wantErr: folder.ErrSourceNotFound},
+		{name: "empty source", folders: MoveData(), src: "", dst: "charlie", wantErr: folder.ErrSourceNotFound},
+		{name: "empty destination", folders: MoveData(), src: "bravo", dst: "", wantErr: folder.ErrDestinationNotFound},
+		{name: "no folders", folders: []folder.Folder{}, src: "bravo", dst: "alpha", wantErr: folder.ErrSourceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := factory(t, append([]folder.Folder{}, tt.folders...))
+			_, err := d.MoveFolder(tt.src, tt.dst)
+			assert.ErrorIs(t, err, tt.wantErr)
+
+			// a failed move changes nothing
+			for _, orgID := range []uuid.UUID{OrgA, OrgB} {
+				want := []folder.Folder{}
+				for _, f := range tt.folders {
+					if f.OrgId == orgID {
+						want = append(want, f)
+					}
+				}
+				assert.ElementsMatch(t, want, d.GetFoldersByOrgID(orgID))
+			}
+		})
+	}
+}
+
+func runMoveFolderSequence(t *testing.T, factory Factory) {
+	d := factory(t, MoveData())
+
+	steps := []struct {
+		src, dst string
+		wantErr  error
+	}{
+		{src: "bravo", dst: "golf"},
+		{src: "delta", dst: "charlie"},
+		// delta now lives under bravo
+		{src: "bravo", dst: "echo", wantErr: folder.ErrMoveToDescendant},
+		{src: "golf", dst: "alpha"},
+		{src: "bravo", dst: "alpha"},
+	}
+	for _, step := range steps {
+		_, err := d.MoveFolder(step.src, step.dst)
+		if step.wantErr != nil {
+			require.ErrorIs(t, err, step.wantErr, "%s -> %s", step.src, step.dst)
+			continue
+		}
+		require.NoError(t, err, "%s -> %s", step.src, step.dst)
+	}
+
+	got, err := d.GetAllChildFolders(OrgA, "alpha")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []folder.Folder{
+		{Name: "bravo", OrgId: OrgA, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: OrgA, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: OrgA, Paths: "alpha.bravo.charlie.delta"},
+		{Name: "echo", OrgId: OrgA, Paths: "alpha.bravo.charlie.delta.echo"},
+		{Name: "golf", OrgId: OrgA, Paths: "alpha.golf"},
+	}, got)
+}
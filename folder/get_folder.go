@@ -1,11 +1,10 @@
 package folder
 
 import (
-	"errors"
 	"github.com/gofrs/uuid"
 )
 
-func GetAllFolders() []Folder {
+func GetAllFolders() ([]Folder, error) {
 	return GetSampleData()
 }
 
@@ -27,12 +26,12 @@ func (f *driver) GetAllChildFolders(orgID uuid.UUID, name string) ([]Folder, err
 
 	exists := f.CheckFolderExists(name)
 	if !exists {
-		return nil, errors.New("Error: Folder does not exist")
+		return nil, ErrFolderNotFound
 	}
 
 	existsOrg := f.CheckFolderExistsWithinOrg(orgID, name)
 	if !existsOrg {
-		return nil, errors.New("Error: Folder does not exist in the specified organization")
+		return nil, ErrFolderNotInOrg
 	}
 
 	rootPath := ""
@@ -51,7 +50,8 @@ func (f *driver) GetAllChildFolders(orgID uuid.UUID, name string) ([]Folder, err
 
 	children := []Folder{}
 	for _, folder := range f.folders {
-		if IsChildFolder(folder, rootPath) {
+		// paths are only unique within an org
+		if folder.OrgId == orgID && IsChildFolder(folder, rootPath) {
 			children = append(children, folder)
 		}
 	}
@@ -61,20 +61,13 @@ func (f *driver) GetAllChildFolders(orgID uuid.UUID, name string) ([]Folder, err
 
 // Checks whether a folder exists regardless of org
 func (f *driver) CheckFolderExists(name string) bool {
-	for _, folder := range f.folders {
-		if folder.Name == name {
-			return true
-		}
-	}
-	return false
+	return len(f.byName[name]) > 0
 }
 
 // Checks whether a folder exists within a specific org
 func (f *driver) CheckFolderExistsWithinOrg(orgID uuid.UUID, name string) bool {
-	folders := f.GetFoldersByOrgID(orgID)
-
-	for _, folder := range folders {
-		if folder.Name == name {
+	for _, i := range f.byName[name] {
+		if f.folders[i].OrgId == orgID {
 			return true
 		}
 	}
@@ -90,7 +83,9 @@ func IsChildFolder(folder Folder, rootPath string) bool {
 	// :len(rootPath) uses string splicing very similar to python
 	// x := "bravo.charlie"
 	// fmt.Println(x[:len(root)]) -> bravo
-	if folder.Paths != rootPath && folder.Paths[:len(rootPath)] == rootPath {
+	// the next character has to be the separator, otherwise "alpha" would
+	// count "alphabet" as one of its children
+	if folder.Paths[:len(rootPath)] == rootPath && folder.Paths[len(rootPath)] == '.' {
 		return true
 	}
 	return false
@@ -98,10 +93,8 @@ func IsChildFolder(folder Folder, rootPath string) bool {
 
 // Returns a folder orgID as uuid
 func (f *driver) GetFolderOrgID(name string) uuid.UUID {
-	for _, folder := range f.folders {
-		if (folder.Name == name) {
-			return folder.OrgId
-		}
+	if idx := f.byName[name]; len(idx) > 0 {
+		return f.folders[idx[0]].OrgId
 	}
 	return uuid.UUID{}
 }
@@ -160,6 +160,32 @@ func Test_folder_GetAllChildFolders(t *testing.T) {
 			want:           []folder.Folder{},
 			wantErr:        errors.New("Error: Folder does not exist in the specified organization"),
 		},
+		{
+			name_of_test:   "Folders sharing a name prefix are not children",
+			orgID:          orgID,
+			name_of_folder: "alpha",
+			folders: []folder.Folder{
+				{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+				{Name: "alphabet", OrgId: orgID, Paths: "alphabet"},
+				{Name: "soup", OrgId: orgID, Paths: "alphabet.soup"},
+				{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+			},
+			want: []folder.Folder{
+				{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+			},
+			wantErr: nil,
+		},
+		{
+			name_of_test:   "Same path in another org is not a child",
+			orgID:          orgID,
+			name_of_folder: "alpha",
+			folders: []folder.Folder{
+				{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+				{Name: "other", OrgId: uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a"), Paths: "alpha.other"},
+			},
+			want:    []folder.Folder{},
+			wantErr: nil,
+		},
 		{
 			name_of_test:   "Given folder os an empty string",
 			orgID:          orgID,
@@ -189,3 +215,26 @@ func Test_folder_GetAllChildFolders(t *testing.T) {
 		})
 	}
 }
+
+func Test_folder_IsChildFolder(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name     string
+		path     string
+		rootPath string
+		want     bool
+	}{
+		{name: "Direct child", path: "alpha.bravo", rootPath: "alpha", want: true},
+		{name: "Deeper descendant", path: "alpha.bravo.charlie", rootPath: "alpha", want: true},
+		{name: "The folder itself", path: "alpha", rootPath: "alpha", want: false},
+		{name: "Sibling sharing a name prefix", path: "alphabet", rootPath: "alpha", want: false},
+		{name: "Child of a sibling sharing a name prefix", path: "alphabet.soup", rootPath: "alpha", want: false},
+		{name: "Root path ending in the separator", path: "alpha.bravo", rootPath: "alpha.", want: false},
+		{name: "Ancestor", path: "alpha", rootPath: "alpha.bravo", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, folder.IsChildFolder(folder.Folder{Name: "x", Paths: tt.path}, tt.rootPath))
+		})
+	}
+}
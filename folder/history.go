@@ -0,0 +1,369 @@
+package folder
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// DefaultHistoryDepth is how many changes of each org a History can undo.
+const DefaultHistoryDepth = 100
+
+var (
+	ErrNothingToUndo       = errors.New("Error: Nothing to undo")
+	ErrNothingToRedo       = errors.New("Error: Nothing to redo")
+	ErrHistoryNotSupported = errors.New("Error: Driver does not support undo")
+)
+
+type HistoryOptions struct {
+	// changes of each org that can be undone, DefaultHistoryDepth when zero.
+	// The oldest ones are forgotten first.
+	Depth int
+}
+
+// History wraps a driver and records the inverse of every change made through
+// it, so changes can be undone and redone. Each org has its own history: Undo
+// reverts the latest change of that org and never touches another one. A new
+// change clears what could be redone in its org.
+//
+// Changes made to the driver without going through the History aren't
+// recorded, and an undo that conflicts with them fails with the driver's error
+// and stays in the history. Like drivers a History is not safe for concurrent use.
+type History struct {
+	d    historyDriver
+	opts HistoryOptions
+	// newest last
+	undo map[uuid.UUID][]historyEntry
+	redo map[uuid.UUID][]historyEntry
+}
+
+// historyDriver is what a History needs of the driver it wraps: the changes
+// of an Editor to undo them, and the org of a folder to know whose history a
+// move goes to
+type historyDriver interface {
+	IDriver
+	Editor
+	GetFolderOrgID(name string) uuid.UUID
+}
+
+// rootMover is implemented by the drivers of this package, MoveFolder can't
+// move a folder to the root of its org
+type rootMover interface {
+	moveToRoot(orgID uuid.UUID, name string) ([]Folder, error)
+}
+
+// restorer is implemented by the drivers of this package, they add back a
+// deleted subtree as a single change
+type restorer interface {
+	restore(orgID uuid.UUID, folders []Folder) ([]Folder, error)
+}
+
+// historyEntry is one change, undo reverts it and redo makes it again
+type historyEntry struct {
+	undo func() ([]Folder, error)
+	redo func() ([]Folder, error)
+}
+
+var (
+	_ IDriver   = (*History)(nil)
+	_ Editor    = (*History)(nil)
+	_ Notifier  = (*History)(nil)
+	_ rootMover = (*History)(nil)
+)
+
+// NewHistory wraps d, which has to be an Editor with a GetFolderOrgID method
+// like the drivers of this package and History. Undoing a move from the root
+// needs a driver of this package below, the undo fails with
+// ErrHistoryNotSupported otherwise.
+func NewHistory(d IDriver, opts HistoryOptions) (*History, error) {
+	h, ok := d.(historyDriver)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = DefaultHistoryDepth
+	}
+	return &History{
+		d:    h,
+		opts: opts,
+		undo: map[uuid.UUID][]historyEntry{},
+		redo: map[uuid.UUID][]historyEntry{},
+	}, nil
+}
+
+func (h *History) GetFoldersByOrgID(orgID uuid.UUID) []Folder {
+	return h.d.GetFoldersByOrgID(orgID)
+}
+
+func (h *History) GetAllChildFolders(orgID uuid.UUID, name string) ([]Folder, error) {
+	return h.d.GetAllChildFolders(orgID, name)
+}
+
+// CheckFolderExists reports whether a folder called name exists in any org.
+func (h *History) CheckFolderExists(name string) bool {
+	return h.d.GetFolderOrgID(name) != uuid.Nil
+}
+
+// GetFolderOrgID returns the org of the folder called name, uuid.Nil when there is none.
+func (h *History) GetFolderOrgID(name string) uuid.UUID {
+	return h.d.GetFolderOrgID(name)
+}
+
+// Subscribe subscribes to the events of the wrapped driver. When it doesn't
+// publish any the subscription is closed right away.
+func (h *History) Subscribe(opts SubscribeOptions) *Subscription {
+	if n, ok := h.d.(Notifier); ok {
+		return n.Subscribe(opts)
+	}
+	sub := NewEventBus(EventBusOptions{}).Subscribe(opts)
+	sub.Close()
+	return sub
+}
+
+func (h *History) MoveFolder(name string, dst string) ([]Folder, error) {
+	return h.move(name, dst, func() ([]Folder, error) { return h.d.MoveFolder(name, dst) })
+}
+
+// moveToRoot moves a folder to the root of its org, like the drivers of this package
+func (h *History) moveToRoot(orgID uuid.UUID, name string) ([]Folder, error) {
+	return h.move(name, "", func() ([]Folder, error) { return h.moveToRootBelow(orgID, name) })
+}
+
+// move records the move of the folder called name to dst, "" being the root,
+// made by do
+func (h *History) move(name string, dst string, do func() ([]Folder, error)) ([]Folder, error) {
+	// where the folder was, read before the move changes it
+	orgID := h.d.GetFolderOrgID(name)
+	parent := parentPath(h.pathOf(orgID, name))
+
+	folders, err := do()
+	if err != nil {
+		return nil, err
+	}
+	h.record(orgID, historyEntry{
+		undo: func() ([]Folder, error) {
+			if parent == "" {
+				return h.moveToRootBelow(orgID, name)
+			}
+			return h.d.MoveFolder(name, lastLabel(parent))
+		},
+		redo: do,
+	})
+	return folders, nil
+}
+
+// moveToRootBelow moves a folder to the root with the wrapped driver
+func (h *History) moveToRootBelow(orgID uuid.UUID, name string) ([]Folder, error) {
+	m, ok := h.d.(rootMover)
+	if !ok {
+		return nil, ErrHistoryNotSupported
+	}
+	return m.moveToRoot(orgID, name)
+}
+
+// pathOf returns the path of the folder called name in orgID, empty when there is none
+func (h *History) pathOf(orgID uuid.UUID, name string) string {
+	for _, f := range h.d.GetFoldersByOrgID(orgID) {
+		if f.Name == name {
+			return f.Paths
+		}
+	}
+	return ""
+}
+
+func (h *History) CreateFolder(orgID uuid.UUID, name string, parent string) (Folder, error) {
+	created, err := h.d.CreateFolder(orgID, name, parent)
+	if err != nil {
+		return Folder{}, err
+	}
+	h.record(orgID, historyEntry{
+		undo: func() ([]Folder, error) { return h.d.DeleteFolder(orgID, name) },
+		redo: func() ([]Folder, error) {
+			created, err := h.d.CreateFolder(orgID, name, parent)
+			if err != nil {
+				return nil, err
+			}
+			return []Folder{created}, nil
+		},
+	})
+	return created, nil
+}
+
+func (h *History) RenameFolder(orgID uuid.UUID, name string, newName string) ([]Folder, error) {
+	renamed, err := h.d.RenameFolder(orgID, name, newName)
+	if err != nil {
+		return nil, err
+	}
+	h.record(orgID, historyEntry{
+		undo: func() ([]Folder, error) { return h.d.RenameFolder(orgID, newName, name) },
+		redo: func() ([]Folder, error) { return h.d.RenameFolder(orgID, name, newName) },
+	})
+	return renamed, nil
+}
+
+func (h *History) DeleteFolder(orgID uuid.UUID, name string) ([]Folder, error) {
+	deleted, err := h.d.DeleteFolder(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+	deleted = append([]Folder{}, deleted...)
+	h.record(orgID, historyEntry{
+		undo: func() ([]Folder, error) { return recreate(h.d, orgID, deleted) },
+		redo: func() ([]Folder, error) { return h.d.DeleteFolder(orgID, name) },
+	})
+	return deleted, nil
+}
+
+// recreate adds back folders DeleteFolder removed, as a single change when d
+// can, else by creating them one by one. It is shared by History and Apply.
+func recreate(d Editor, orgID uuid.UUID, folders []Folder) ([]Folder, error) {
+	if r, ok := d.(restorer); ok {
+		return r.restore(orgID, folders)
+	}
+	folders = append([]Folder{}, folders...)
+	// parents sort before their children
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Paths < folders[j].Paths })
+	if orgs, ok := d.(interface{ GetFolderOrgID(name string) uuid.UUID }); ok {
+		for _, folder := range folders {
+			if orgs.GetFolderOrgID(folder.Name) != uuid.Nil {
+				return nil, ErrFolderExists
+			}
+		}
+	}
+	for _, folder := range folders {
+		// path labels are folder names
+		if _, err := d.CreateFolder(orgID, folder.Name, lastLabel(parentPath(folder.Paths))); err != nil {
+			return nil, err
+		}
+	}
+	return folders, nil
+}
+
+// Undo reverts the latest change of orgID that hasn't been undone and returns
+// the folders the reverting change returned, like the method that made it.
+func (h *History) Undo(orgID uuid.UUID) ([]Folder, error) {
+	undo := h.undo[orgID]
+	if len(undo) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	entry := undo[len(undo)-1]
+	folders, err := entry.undo()
+	if err != nil {
+		return nil, err
+	}
+	h.undo[orgID] = undo[:len(undo)-1]
+	h.redo[orgID] = append(h.redo[orgID], entry)
+	return folders, nil
+}
+
+// Redo makes the latest change of orgID that was undone again.
+func (h *History) Redo(orgID uuid.UUID) ([]Folder, error) {
+	redo := h.redo[orgID]
+	if len(redo) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	entry := redo[len(redo)-1]
+	folders, err := entry.redo()
+	if err != nil {
+		return nil, err
+	}
+	h.redo[orgID] = redo[:len(redo)-1]
+	h.undo[orgID] = append(h.undo[orgID], entry)
+	return folders, nil
+}
+
+// Len returns how many changes of orgID can be undone and redone.
+func (h *History) Len(orgID uuid.UUID) (undo int, redo int) {
+	return len(h.undo[orgID]), len(h.redo[orgID])
+}
+
+// record adds a change that was just made
+func (h *History) record(orgID uuid.UUID, entry historyEntry) {
+	undo := append(h.undo[orgID], entry)
+	if len(undo) > h.opts.Depth {
+		undo = append([]historyEntry{}, undo[len(undo)-h.opts.Depth:]...)
+	}
+	h.undo[orgID] = undo
+	delete(h.redo, orgID)
+}
+
+// The inverses of a move from the root and of a delete can't be made with the
+// public methods, these make them as a single change.
+
+// moveToRoot moves a folder and its subtree to the root of its org
+func (f *driver) moveToRoot(orgID uuid.UUID, name string) ([]Folder, error) {
+	i, err := f.indexInOrg(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+	oldRoot := f.folders[i].Paths
+	subtree := f.subtree(i)
+	newPaths := make([]string, len(subtree))
+	for j, k := range subtree {
+		newPaths[j] = name + f.folders[k].Paths[len(oldRoot):]
+	}
+
+	if f.store != nil {
+		mutation := Mutation{Op: OpMove, Args: []string{name, ""}}
+		for j, k := range subtree {
+			mutation.Changed = append(mutation.Changed, Folder{Name: f.folders[k].Name, OrgId: orgID, Paths: newPaths[j]})
+		}
+		if err := f.store.Apply(mutation); err != nil {
+			return nil, err
+		}
+	}
+
+	event := Event{Type: FolderMoved, OrgID: orgID, Name: name}
+	for j, k := range subtree {
+		event.Changes = append(event.Changes, Change{Name: f.folders[k].Name, OldPath: f.folders[k].Paths, NewPath: newPaths[j]})
+		f.folders[k].Paths = newPaths[j]
+	}
+	f.publish(event)
+	return f.folders, nil
+}
+
+// restore adds back a deleted folder and its descendants, as DeleteFolder returned them
+func (f *driver) restore(orgID uuid.UUID, folders []Folder) ([]Folder, error) {
+	folders = append([]Folder{}, folders...)
+	// parents sort before their children
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Paths < folders[j].Paths })
+	for _, folder := range folders {
+		if f.CheckFolderExists(folder.Name) {
+			return nil, ErrFolderExists
+		}
+	}
+	root := folders[0]
+	parent := ""
+	if path := parentPath(root.Paths); path != "" {
+		parent = lastLabel(path)
+		i, err := f.indexInOrg(orgID, parent)
+		if err != nil {
+			return nil, err
+		}
+		if f.folders[i].Paths != path {
+			return nil, ErrFolderNotFound
+		}
+	}
+
+	if f.store != nil {
+		mutation := Mutation{Op: OpCreate, Args: []string{orgID.String(), root.Name, parent}, Changed: folders}
+		if err := f.store.Apply(mutation); err != nil {
+			return nil, err
+		}
+	}
+
+	event := Event{Type: FolderCreated, OrgID: orgID, Name: root.Name}
+	for _, folder := range folders {
+		f.add(folder)
+		event.Changes = append(event.Changes, Change{Name: folder.Name, NewPath: folder.Paths})
+	}
+	f.publish(event)
+	return folders, nil
+}
+
+// lastLabel is the name of the folder at path, path labels being folder names
+func lastLabel(path string) string {
+	return path[strings.LastIndex(path, ".")+1:]
+}
@@ -0,0 +1,230 @@
+package folder_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// history returns a History over the move data, persisted to the returned store
+func history(t *testing.T, opts folder.HistoryOptions) (*folder.History, *folder.MemoryStore) {
+	t.Helper()
+	store := folder.NewMemoryStore(foldertest.MoveData())
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+	h, err := folder.NewHistory(d, opts)
+	require.NoError(t, err)
+	return h, store
+}
+
+// assertState checks the driver and the store both hold want
+func assertState(t *testing.T, h *folder.History, store *folder.MemoryStore, want []folder.Folder) {
+	t.Helper()
+	got := append(h.GetFoldersByOrgID(foldertest.OrgA), h.GetFoldersByOrgID(foldertest.OrgB)...)
+	assert.ElementsMatch(t, want, got)
+	stored, err := store.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, want, stored)
+}
+
+func withPaths(folders []folder.Folder, paths map[string]string) []folder.Folder {
+	for i := range folders {
+		if p, ok := paths[folders[i].Name]; ok {
+			folders[i].Paths = p
+		}
+	}
+	return folders
+}
+
+func Test_folder_HistoryMoves(t *testing.T) {
+	t.Parallel()
+	h, store := history(t, folder.HistoryOptions{})
+
+	_, err := h.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	// from the root, the undo moves it back there
+	_, err = h.MoveFolder("golf", "echo")
+	require.NoError(t, err)
+	moved := withPaths(foldertest.MoveData(), map[string]string{
+		"bravo": "alpha.delta.bravo", "charlie": "alpha.delta.bravo.charlie", "golf": "alpha.delta.echo.golf",
+	})
+	assertState(t, h, store, moved)
+
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	assertState(t, h, store, withPaths(foldertest.MoveData(), map[string]string{
+		"bravo": "alpha.delta.bravo", "charlie": "alpha.delta.bravo.charlie",
+	}))
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	assertState(t, h, store, foldertest.MoveData())
+	_, err = h.Undo(foldertest.OrgA)
+	assert.ErrorIs(t, err, folder.ErrNothingToUndo)
+
+	_, err = h.Redo(foldertest.OrgA)
+	require.NoError(t, err)
+	_, err = h.Redo(foldertest.OrgA)
+	require.NoError(t, err)
+	assertState(t, h, store, moved)
+	_, err = h.Redo(foldertest.OrgA)
+	assert.ErrorIs(t, err, folder.ErrNothingToRedo)
+}
+
+func Test_folder_HistoryEdits(t *testing.T) {
+	t.Parallel()
+	h, store := history(t, folder.HistoryOptions{})
+
+	_, err := h.CreateFolder(foldertest.OrgA, "hotel", "charlie")
+	require.NoError(t, err)
+	_, err = h.RenameFolder(foldertest.OrgA, "bravo", "india")
+	require.NoError(t, err)
+	_, err = h.DeleteFolder(foldertest.OrgA, "india")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "alpha.delta", "alpha.delta.echo", "golf"}, paths(h.GetFoldersByOrgID(foldertest.OrgA)))
+
+	// the whole subtree comes back as one change
+	sub := h.Subscribe(folder.SubscribeOptions{})
+	defer sub.Close()
+	restored, err := h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha.india", "alpha.india.charlie", "alpha.india.charlie.hotel"}, paths(restored))
+	events := drain(sub)
+	require.Len(t, events, 1)
+	assert.Equal(t, folder.FolderCreated, events[0].Type)
+	assert.Equal(t, "india", events[0].Name)
+	assert.Len(t, events[0].Changes, 3)
+
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	assertState(t, h, store, foldertest.MoveData())
+
+	for range 3 {
+		_, err = h.Redo(foldertest.OrgA)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"alpha", "alpha.delta", "alpha.delta.echo", "golf"}, paths(h.GetFoldersByOrgID(foldertest.OrgA)))
+	stored, err := store.Load()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alpha", "delta", "echo", "golf", "foxtrot"}, names(stored))
+}
+
+func Test_folder_HistoryPerOrg(t *testing.T) {
+	t.Parallel()
+	h, store := history(t, folder.HistoryOptions{})
+
+	_, err := h.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	_, err = h.CreateFolder(foldertest.OrgB, "hotel", "foxtrot")
+	require.NoError(t, err)
+	_, err = h.MoveFolder("echo", "alpha")
+	require.NoError(t, err)
+
+	// only the change of org B is undone, though org A changed after it
+	_, err = h.Undo(foldertest.OrgB)
+	require.NoError(t, err)
+	_, err = h.Undo(foldertest.OrgB)
+	assert.ErrorIs(t, err, folder.ErrNothingToUndo)
+	assertState(t, h, store, withPaths(foldertest.MoveData(), map[string]string{
+		"bravo": "golf.bravo", "charlie": "golf.bravo.charlie", "echo": "alpha.echo",
+	}))
+
+	undo, redo := h.Len(foldertest.OrgA)
+	assert.Equal(t, 2, undo)
+	assert.Zero(t, redo)
+	undo, redo = h.Len(foldertest.OrgB)
+	assert.Zero(t, undo)
+	assert.Equal(t, 1, redo)
+
+	// a new change in org A leaves what org B can redo alone
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	_, err = h.MoveFolder("delta", "golf")
+	require.NoError(t, err)
+	_, err = h.Redo(foldertest.OrgA)
+	assert.ErrorIs(t, err, folder.ErrNothingToRedo)
+	_, err = h.Redo(foldertest.OrgB)
+	assert.NoError(t, err)
+}
+
+func Test_folder_HistoryDepth(t *testing.T) {
+	t.Parallel()
+	h, _ := history(t, folder.HistoryOptions{Depth: 2})
+
+	for _, dst := range []string{"delta", "golf", "echo"} {
+		_, err := h.MoveFolder("bravo", dst)
+		require.NoError(t, err)
+	}
+	undo, _ := h.Len(foldertest.OrgA)
+	assert.Equal(t, 2, undo)
+
+	for range 2 {
+		_, err := h.Undo(foldertest.OrgA)
+		require.NoError(t, err)
+	}
+	_, err := h.Undo(foldertest.OrgA)
+	assert.ErrorIs(t, err, folder.ErrNothingToUndo)
+	// the first move is forgotten
+	f, err := h.GetAllChildFolders(foldertest.OrgA, "delta")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alpha.delta.echo", "alpha.delta.bravo", "alpha.delta.bravo.charlie"}, paths(f))
+}
+
+func Test_folder_HistoryConflict(t *testing.T) {
+	t.Parallel()
+	d := folder.NewDriver(foldertest.MoveData())
+	h, err := folder.NewHistory(d, folder.HistoryOptions{})
+	require.NoError(t, err)
+
+	_, err = h.DeleteFolder(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	// made without the history, so the delete can't be undone
+	_, err = d.(folder.Editor).CreateFolder(foldertest.OrgA, "charlie", "")
+	require.NoError(t, err)
+
+	_, err = h.Undo(foldertest.OrgA)
+	assert.ErrorIs(t, err, folder.ErrFolderExists)
+	undo, _ := h.Len(foldertest.OrgA)
+	assert.Equal(t, 1, undo, "the change stays in the history")
+
+	_, err = d.(folder.Editor).DeleteFolder(foldertest.OrgA, "charlie")
+	require.NoError(t, err)
+	_, err = h.Undo(foldertest.OrgA)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, paths(foldertest.MoveData()), paths(append(h.GetFoldersByOrgID(foldertest.OrgA), h.GetFoldersByOrgID(foldertest.OrgB)...)))
+}
+
+func Test_folder_HistoryNotSupported(t *testing.T) {
+	t.Parallel()
+	// a wrapped driver hides the methods the history needs
+	wrapped := struct{ folder.IDriver }{folder.NewDriver(foldertest.MoveData())}
+	_, err := folder.NewHistory(wrapped, folder.HistoryOptions{})
+	assert.ErrorIs(t, err, folder.ErrHistoryNotSupported)
+}
+
+func Test_folder_HistoryOfHistory(t *testing.T) {
+	t.Parallel()
+	inner, err := folder.NewHistory(folder.NewDriver(foldertest.MoveData()), folder.HistoryOptions{})
+	require.NoError(t, err)
+	h, err := folder.NewHistory(inner, folder.HistoryOptions{})
+	require.NoError(t, err)
+
+	_, err = h.DeleteFolder(foldertest.OrgA, "bravo")
+	require.NoError(t, err)
+	_, err = h.MoveFolder("golf", "alpha")
+	require.NoError(t, err)
+
+	// back to the root, through the inner history
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	// created again one by one, each recorded by the inner history
+	_, err = h.Undo(foldertest.OrgA)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, paths(foldertest.MoveData()), paths(append(h.GetFoldersByOrgID(foldertest.OrgA), h.GetFoldersByOrgID(foldertest.OrgB)...)))
+	undo, _ := inner.Len(foldertest.OrgA)
+	assert.Equal(t, 5, undo)
+}
@@ -0,0 +1,97 @@
+package folder
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// sample.json is compiled into the binary so the default data does not
+// depend on where the source tree lives at runtime.
+//
+//go:embed sample.json
+var embeddedSample []byte
+
+// permissions used for folder files written by this package
+const FileMode os.FileMode = 0o644
+
+// LoadFolders reads every folder from r, either a JSON array or NDJSON.
+func LoadFolders(r io.Reader) ([]Folder, error) {
+	folders := []Folder{}
+	err := StreamFolders(r, StreamOptions{}, func(folder Folder) error {
+		folders = append(folders, folder)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// LoadFoldersFromFile reads every folder from the file at path.
+func LoadFoldersFromFile(path string) ([]Folder, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadFolders(file)
+}
+
+// GetSampleData returns the folders from the embedded sample.json.
+func GetSampleData() ([]Folder, error) {
+	return LoadFolders(bytes.NewReader(embeddedSample))
+}
+
+// WriteSampleData writes folders to path as an indented JSON array, the same
+// layout as sample.json. The write is atomic, see WriteFileAtomic.
+func WriteSampleData(path string, folders []Folder) error {
+	b, err := json.MarshalIndent(folders, "", "\t")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, b)
+}
+
+// WriteFileAtomic writes data to a temp file next to path and renames it into
+// place, so readers only ever see the old or the new content, never a partial file.
+func WriteFileAtomic(path string, data []byte) error {
+	return writeAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeAtomic is WriteFileAtomic for content that is produced by streaming into a writer
+func writeAtomic(path string, write func(io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// clean up the temp file on any failure, the rename below makes this a no-op on success
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(FileMode); err != nil {
+		return err
+	}
+	// flush to disk before the rename so a crash can't leave an empty file behind
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,65 @@
+package folder_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_GetSampleData(t *testing.T) {
+	t.Parallel()
+	folders, err := folder.GetSampleData()
+	require.NoError(t, err)
+	assert.NotEmpty(t, folders)
+
+	// the embedded copy must match the file on disk
+	fromFile, err := folder.LoadFoldersFromFile("sample.json")
+	require.NoError(t, err)
+	assert.Equal(t, fromFile, folders)
+}
+
+func Test_folder_LoadFolders(t *testing.T) {
+	t.Parallel()
+	got, err := folder.LoadFolders(strings.NewReader("[]"))
+	require.NoError(t, err)
+	assert.Equal(t, []folder.Folder{}, got)
+
+	_, err = folder.LoadFolders(strings.NewReader(`[{"name": 1}]`))
+	assert.Error(t, err)
+
+	_, err = folder.LoadFoldersFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func Test_folder_WriteSampleData(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "folders.json")
+
+	require.NoError(t, os.WriteFile(path, []byte("old content"), 0o600))
+	require.NoError(t, folder.WriteSampleData(path, GetTestingSampleData2()))
+
+	got, err := folder.LoadFoldersFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, GetTestingSampleData2(), got)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, folder.FileMode, info.Mode().Perm())
+
+	// no temp files left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_folder_WriteSampleData_MissingDir(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "missing", "folders.json")
+	assert.Error(t, folder.WriteSampleData(path, GetTestingSampleData2()))
+}
@@ -0,0 +1,270 @@
+package folder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A matcher for Postgres lquery patterns, so paths can be searched the same way
+// as `path ~ $1::lquery` without a database:
+//
+//	foo            a label equal to foo
+//	foo*           a label starting with foo
+//	foo@           case-insensitive
+//	foo_bar%       the label's _ separated words start with foo and bar
+//	a|b            either a or b
+//	!a|b           any label but a and b
+//	*              any number of labels, *{n}, *{n,}, *{,m} and *{n,m} bound it
+//	foo{n,m}       n to m labels that each match foo, same bounds as *
+//
+// items are separated by "." and the whole path has to match, e.g. *.bravo.*
+// finds bravo anywhere and alpha.*{1} the grandchildren of alpha.
+
+var ErrInvalidLQuery = errors.New("Error: Invalid lquery")
+
+// LQuery is a parsed lquery pattern.
+type LQuery struct {
+	pattern string
+	items   []lqueryItem
+}
+
+type lqueryItem struct {
+	// any label, the * item
+	any      bool
+	not      bool
+	variants []lqueryVariant
+	min, max int
+}
+
+type lqueryVariant struct {
+	label                   string
+	prefix, fold, wordMatch bool
+}
+
+// no upper bound on the number of labels
+const lqueryUnbounded = -1
+
+// ParseLQuery parses an lquery pattern.
+func ParseLQuery(pattern string) (*LQuery, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: empty pattern", ErrInvalidLQuery)
+	}
+	q := &LQuery{pattern: pattern}
+	for i, part := range strings.Split(pattern, ".") {
+		item, err := parseLQueryItem(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d %q: %s", ErrInvalidLQuery, i+1, part, err)
+		}
+		q.items = append(q.items, item)
+	}
+	return q, nil
+}
+
+// MustParseLQuery is ParseLQuery for patterns known to be valid, it panics otherwise.
+func MustParseLQuery(pattern string) *LQuery {
+	q, err := ParseLQuery(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *LQuery) String() string {
+	return q.pattern
+}
+
+func parseLQueryItem(s string) (lqueryItem, error) {
+	item := lqueryItem{min: 1, max: 1}
+
+	// the {n,m} quantifier at the end
+	if open := strings.IndexByte(s, '{'); open >= 0 {
+		if !strings.HasSuffix(s, "}") {
+			return item, errors.New("unterminated {")
+		}
+		min, max, err := parseLQueryBounds(s[open+1 : len(s)-1])
+		if err != nil {
+			return item, err
+		}
+		item.min, item.max = min, max
+		s = s[:open]
+	} else if s == "*" {
+		item.min, item.max = 0, lqueryUnbounded
+	}
+
+	if s == "*" {
+		item.any = true
+		return item, nil
+	}
+	if strings.HasPrefix(s, "!") {
+		item.not = true
+		s = s[1:]
+	}
+	for _, v := range strings.Split(s, "|") {
+		variant, err := parseLQueryVariant(v)
+		if err != nil {
+			return item, err
+		}
+		item.variants = append(item.variants, variant)
+	}
+	return item, nil
+}
+
+func parseLQueryBounds(s string) (int, int, error) {
+	parse := func(s string, def int) (int, error) {
+		if s == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad bound %q", s)
+		}
+		return n, nil
+	}
+
+	lo, hi, ranged := strings.Cut(s, ",")
+	min, err := parse(lo, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ranged {
+		if lo == "" {
+			return 0, 0, errors.New("empty {}")
+		}
+		return min, min, nil
+	}
+	max, err := parse(hi, lqueryUnbounded)
+	if err != nil {
+		return 0, 0, err
+	}
+	if max != lqueryUnbounded && max < min {
+		return 0, 0, fmt.Errorf("upper bound %d is below lower bound %d", max, min)
+	}
+	return min, max, nil
+}
+
+func parseLQueryVariant(s string) (lqueryVariant, error) {
+	v := lqueryVariant{}
+	// flags come after the label in any order
+	for len(s) > 0 {
+		switch s[len(s)-1] {
+		case '*':
+			v.prefix = true
+		case '@':
+			v.fold = true
+		case '%':
+			v.wordMatch = true
+		default:
+			v.label = s
+			s = ""
+			continue
+		}
+		s = s[:len(s)-1]
+	}
+	if v.label == "" {
+		return v, errors.New("empty label")
+	}
+	for _, r := range v.label {
+		if !isLabelRune(r) {
+			return v, fmt.Errorf("%q is not allowed in a label", r)
+		}
+	}
+	return v, nil
+}
+
+// isLabelRune reports whether r may appear in an ltree label, see LabelPattern
+func isLabelRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-')
+}
+
+// Match reports whether the whole path matches the pattern.
+func (q *LQuery) Match(path string) bool {
+	labels := strings.Split(path, ".")
+	// memo[i][j] caches whether items[i:] match labels[j:], 0 unknown, 1 yes, 2 no
+	memo := make([][]uint8, len(q.items)+1)
+	for i := range memo {
+		memo[i] = make([]uint8, len(labels)+1)
+	}
+
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		if i == len(q.items) {
+			return j == len(labels)
+		}
+		if memo[i][j] != 0 {
+			return memo[i][j] == 1
+		}
+
+		item := q.items[i]
+		ok := false
+		// take n labels for this item, every one of them has to match it
+		for n := 0; j+n <= len(labels) && (item.max == lqueryUnbounded || n <= item.max); n++ {
+			if n > 0 && !item.matchLabel(labels[j+n-1]) {
+				break
+			}
+			if n >= item.min && match(i+1, j+n) {
+				ok = true
+				break
+			}
+		}
+
+		memo[i][j] = 2
+		if ok {
+			memo[i][j] = 1
+		}
+		return ok
+	}
+	return match(0, 0)
+}
+
+func (item lqueryItem) matchLabel(label string) bool {
+	if item.any {
+		return true
+	}
+	for _, v := range item.variants {
+		if v.match(label) {
+			return !item.not
+		}
+	}
+	return item.not
+}
+
+func (v lqueryVariant) match(label string) bool {
+	if !v.wordMatch {
+		return matchLQueryWord(v.label, label, v.prefix, v.fold)
+	}
+	// every word of the pattern against the label's words in order, the label may have more
+	want, have := strings.Split(v.label, "_"), strings.Split(label, "_")
+	if len(have) < len(want) {
+		return false
+	}
+	for i := range want {
+		if !matchLQueryWord(want[i], have[i], v.prefix, v.fold) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLQueryWord(want, have string, prefix, fold bool) bool {
+	if fold {
+		want, have = strings.ToLower(want), strings.ToLower(have)
+	}
+	if prefix {
+		return strings.HasPrefix(have, want)
+	}
+	return want == have
+}
+
+// Filter returns the folders whose path matches, in their original order.
+func (q *LQuery) Filter(folders []Folder) []Folder {
+	res := []Folder{}
+	for _, f := range folders {
+		if q.Match(f.Paths) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
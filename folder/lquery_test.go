@@ -0,0 +1,76 @@
+package folder_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_LQuery_Match(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "alpha", path: "alpha", want: true},
+		{pattern: "alpha", path: "alpha.bravo", want: false},
+		{pattern: "alpha.*", path: "alpha", want: true},
+		{pattern: "alpha.*", path: "alpha.bravo.charlie", want: true},
+		{pattern: "*.charlie", path: "alpha.bravo.charlie", want: true},
+		{pattern: "*.bravo.*", path: "alpha.bravo.charlie", want: true},
+		{pattern: "*.bravo.*", path: "bravo", want: true},
+		{pattern: "*.bravo.*", path: "alpha.bravos", want: false},
+		{pattern: "alpha.*{1}", path: "alpha.bravo", want: true},
+		{pattern: "alpha.*{1}", path: "alpha.bravo.charlie", want: false},
+		{pattern: "alpha.*{1,}", path: "alpha", want: false},
+		{pattern: "alpha.*{,1}", path: "alpha", want: true},
+		{pattern: "alpha.*{1,2}", path: "alpha.bravo.charlie", want: true},
+		{pattern: "alpha.*{1,2}", path: "alpha.bravo.charlie.delta", want: false},
+		{pattern: "alp*", path: "alpha", want: true},
+		{pattern: "alp*", path: "alp", want: true},
+		{pattern: "alp*", path: "al", want: false},
+		{pattern: "ALPHA@", path: "alpha", want: true},
+		{pattern: "ALP@*", path: "alpha", want: true},
+		{pattern: "ALPHA", path: "alpha", want: false},
+		{pattern: "bravo|alpha", path: "alpha", want: true},
+		{pattern: "!bravo|charlie", path: "alpha", want: true},
+		{pattern: "!alpha", path: "alpha", want: false},
+		{pattern: "*.!bravo.charlie", path: "alpha.bravo.charlie", want: false},
+		{pattern: "*.!bravo.charlie", path: "bravo.delta.charlie", want: true},
+		{pattern: "foo_bar%", path: "foo_bar_baz", want: true},
+		{pattern: "foo_bar%", path: "foo_barbaz", want: false},
+		{pattern: "foo_bar%*", path: "foo1_bar2_baz", want: true},
+		{pattern: "a*{2}.c", path: "ab.ac.c", want: true},
+		{pattern: "a*{2}.c", path: "ab.c", want: false},
+		{pattern: "noble-vixen.*", path: "noble-vixen.nearby-secret", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			t.Parallel()
+			q, err := folder.ParseLQuery(tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, q.Match(tt.path))
+		})
+	}
+}
+
+func Test_folder_LQuery_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, pattern := range []string{"", "alpha.", ".alpha", "a|", "!", "a{", "a{x}", "a{}", "*{3,1}", "a b", "@", "a..b"} {
+		_, err := folder.ParseLQuery(pattern)
+		assert.ErrorIs(t, err, folder.ErrInvalidLQuery, pattern)
+	}
+}
+
+func Test_folder_LQuery_Filter(t *testing.T) {
+	t.Parallel()
+	got := folder.MustParseLQuery("*.bravo.*").Filter(GetTestingSampleData1())
+	names := []string{}
+	for _, f := range got {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"bravo", "charlie"}, names)
+}
@@ -0,0 +1,187 @@
+package ltreesql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// Executor runs statements, *sql.DB and *sql.Tx can be adapted with DB.
+type Executor interface {
+	Query(ctx context.Context, stmt Statement) (Rows, error)
+	Exec(ctx context.Context, stmt Statement) (int64, error)
+}
+
+// Rows is the part of *sql.Rows the driver needs.
+type Rows interface {
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+	Close() error
+}
+
+// sqlConn is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type sqlConn interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type dbExecutor struct {
+	conn sqlConn
+}
+
+// DB adapts a database/sql connection to an Executor.
+func DB(conn sqlConn) Executor {
+	return dbExecutor{conn: conn}
+}
+
+func (e dbExecutor) Query(ctx context.Context, stmt Statement) (Rows, error) {
+	return e.conn.QueryContext(ctx, stmt.SQL, stmt.Args...)
+}
+
+func (e dbExecutor) Exec(ctx context.Context, stmt Statement) (int64, error) {
+	res, err := e.conn.ExecContext(ctx, stmt.SQL, stmt.Args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Driver runs the folder driver operations against a Postgres ltree table,
+// or any table stmts is written for. It returns the same errors as the
+// in-memory driver.
+type Driver struct {
+	exec  Executor
+	stmts Statements
+}
+
+var _ folder.IDriver = (*Driver)(nil)
+
+func NewDriver(exec Executor, stmts Statements) *Driver {
+	return &Driver{exec: exec, stmts: stmts}
+}
+
+func (d *Driver) query(ctx context.Context, stmt Statement) ([]folder.Folder, error) {
+	rows, err := d.exec.Query(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []folder.Folder{}
+	for rows.Next() {
+		var f folder.Folder
+		if err := rows.Scan(&f.Name, &f.OrgId, &f.Paths); err != nil {
+			return nil, err
+		}
+		folders = append(folders, f)
+	}
+	return folders, rows.Err()
+}
+
+// queryOne returns the first folder the statement selects, or false if there is none
+func (d *Driver) queryOne(ctx context.Context, stmt Statement) (folder.Folder, bool, error) {
+	folders, err := d.query(ctx, stmt)
+	if err != nil || len(folders) == 0 {
+		return folder.Folder{}, false, err
+	}
+	return folders[0], true, nil
+}
+
+// ListFolders is GetFoldersByOrgID with a context and an error.
+func (d *Driver) ListFolders(ctx context.Context, orgID uuid.UUID) ([]folder.Folder, error) {
+	return d.query(ctx, d.stmts.FoldersByOrgID(orgID))
+}
+
+// GetFoldersByOrgID returns an empty slice when the query fails, use ListFolders to see the error.
+func (d *Driver) GetFoldersByOrgID(orgID uuid.UUID) []folder.Folder {
+	folders, err := d.ListFolders(context.Background(), orgID)
+	if err != nil {
+		return []folder.Folder{}
+	}
+	return folders
+}
+
+// ChildFolders is GetAllChildFolders with a context.
+func (d *Driver) ChildFolders(ctx context.Context, orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	if _, ok, err := d.queryOne(ctx, d.stmts.FolderByName(name)); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, folder.ErrFolderNotFound
+	}
+
+	parent, ok, err := d.queryOne(ctx, d.stmts.FolderByOrgAndName(orgID, name))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, folder.ErrFolderNotInOrg
+	}
+
+	return d.query(ctx, d.stmts.ChildFolders(orgID, parent.Paths))
+}
+
+func (d *Driver) GetAllChildFolders(orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	return d.ChildFolders(context.Background(), orgID, name)
+}
+
+// Move is MoveFolder with a context.
+func (d *Driver) Move(ctx context.Context, name string, dst string) ([]folder.Folder, error) {
+	src, ok, err := d.queryOne(ctx, d.stmts.FolderByName(name))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, folder.ErrSourceNotFound
+	}
+
+	target, ok, err := d.queryOne(ctx, d.stmts.FolderByName(dst))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, folder.ErrDestinationNotFound
+	}
+
+	if name == dst {
+		return nil, folder.ErrMoveToSelf
+	}
+	if src.OrgId != target.OrgId {
+		return nil, folder.ErrMoveToOtherOrg
+	}
+	if strings.HasPrefix(target.Paths, src.Paths+".") {
+		return nil, folder.ErrMoveToDescendant
+	}
+
+	if _, err := d.exec.Exec(ctx, d.stmts.MoveFolder(src.OrgId, src.Paths, target.Paths, src.Name)); err != nil {
+		return nil, err
+	}
+
+	return d.query(ctx, d.stmts.All())
+}
+
+func (d *Driver) MoveFolder(name string, dst string) ([]folder.Folder, error) {
+	return d.Move(context.Background(), name, dst)
+}
+
+// DeleteFolder deletes a folder and all of its descendants within an org and
+// returns how many folders were removed.
+func (d *Driver) DeleteFolder(ctx context.Context, orgID uuid.UUID, name string) (int64, error) {
+	target, ok, err := d.queryOne(ctx, d.stmts.FolderByOrgAndName(orgID, name))
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		if _, exists, err := d.queryOne(ctx, d.stmts.FolderByName(name)); err != nil {
+			return 0, err
+		} else if exists {
+			return 0, folder.ErrFolderNotInOrg
+		}
+		return 0, folder.ErrFolderNotFound
+	}
+
+	return d.exec.Exec(ctx, d.stmts.DeleteFolder(orgID, target.Paths))
+}
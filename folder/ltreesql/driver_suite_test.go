@@ -0,0 +1,15 @@
+package ltreesql_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/ltreesql"
+)
+
+func Test_ltreesql_DriverSuite(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		return ltreesql.NewDriver(ltreesql.NewFakeExecutor(ltreesql.New(), folders), ltreesql.New())
+	})
+}
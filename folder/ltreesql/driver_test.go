@@ -0,0 +1,112 @@
+package ltreesql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/ltreesql"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestingData() []folder.Folder {
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	return []folder.Folder{
+		{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: orgID, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: orgID, Paths: "alpha.delta"},
+		{Name: "echo", OrgId: orgID, Paths: "alpha.delta.echo"},
+		{Name: "foxtrot", OrgId: uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a"), Paths: "foxtrot"},
+		{Name: "golf", OrgId: orgID, Paths: "golf"},
+	}
+}
+
+func Test_ltreesql_Driver_MatchesMemoryDriver(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	moves := [][2]string{
+		{"bravo", "delta"},
+		{"bravo", "golf"},
+		{"alpha", "golf"},
+		{"bravo", "charlie"},
+		{"bravo", "bravo"},
+		{"bravo", "foxtrot"},
+		{"invalid_folder", "delta"},
+		{"bravo", "invalid_folder"},
+	}
+	for _, move := range moves {
+		t.Run(move[0]+"->"+move[1], func(t *testing.T) {
+			memory := folder.NewDriver(getTestingData())
+			sql := ltreesql.NewDriver(ltreesql.NewFakeExecutor(ltreesql.New(), getTestingData()), ltreesql.New())
+
+			want, wantErr := memory.MoveFolder(move[0], move[1])
+			got, err := sql.MoveFolder(move[0], move[1])
+			assert.Equal(t, wantErr, err)
+			assert.Equal(t, want, got)
+
+			for _, name := range []string{"alpha", "golf", "foxtrot", "missing"} {
+				want, wantErr := memory.GetAllChildFolders(orgID, name)
+				got, err := sql.GetAllChildFolders(orgID, name)
+				assert.Equal(t, wantErr, err, name)
+				assert.ElementsMatch(t, want, got, name)
+			}
+			assert.Equal(t, memory.GetFoldersByOrgID(orgID), sql.GetFoldersByOrgID(orgID))
+		})
+	}
+}
+
+func Test_ltreesql_Driver_Statements(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	b := ltreesql.New()
+	exec := ltreesql.NewFakeExecutor(b, getTestingData())
+	d := ltreesql.NewDriver(exec, b)
+
+	_, err := d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	assert.Equal(t, []ltreesql.Statement{
+		b.FolderByName("bravo"),
+		b.FolderByName("delta"),
+		b.MoveFolder(orgID, "alpha.bravo", "alpha.delta", "bravo"),
+		b.All(),
+	}, exec.Calls())
+}
+
+func Test_ltreesql_Driver_DeleteFolder(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	exec := ltreesql.NewFakeExecutor(ltreesql.New(), getTestingData())
+	d := ltreesql.NewDriver(exec, ltreesql.New())
+
+	n, err := d.DeleteFolder(context.Background(), orgID, "bravo")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Len(t, exec.Rows(), 5)
+
+	_, err = d.DeleteFolder(context.Background(), orgID, "foxtrot")
+	assert.ErrorIs(t, err, folder.ErrFolderNotInOrg)
+	_, err = d.DeleteFolder(context.Background(), orgID, "bravo")
+	assert.ErrorIs(t, err, folder.ErrFolderNotFound)
+}
+
+func Test_ltreesql_Driver_ExecutorErrors(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	exec := ltreesql.NewFakeExecutor(ltreesql.New(), getTestingData())
+	d := ltreesql.NewDriver(exec, ltreesql.New())
+
+	boom := errors.New("connection refused")
+	exec.Err = boom
+
+	_, err := d.MoveFolder("bravo", "delta")
+	assert.ErrorIs(t, err, boom)
+	_, err = d.GetAllChildFolders(orgID, "alpha")
+	assert.ErrorIs(t, err, boom)
+	_, err = d.ListFolders(context.Background(), orgID)
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, []folder.Folder{}, d.GetFoldersByOrgID(orgID))
+}
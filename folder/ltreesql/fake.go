@@ -0,0 +1,186 @@
+package ltreesql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// ErrUnknownStatement is returned by FakeExecutor for SQL it can't emulate.
+var ErrUnknownStatement = errors.New("Error: Unknown statement")
+
+// FakeExecutor is an in-memory Executor for tests. It understands exactly the
+// statements a Builder produces and emulates the ltree operators they use, so
+// a Driver can be exercised without a database. Every statement is recorded.
+type FakeExecutor struct {
+	mu      sync.Mutex
+	builder Builder
+	rows    []folder.Folder
+	calls   []Statement
+
+	// when set, returned for every statement instead of running it
+	Err error
+}
+
+var _ Executor = (*FakeExecutor)(nil)
+
+// NewFakeExecutor returns an executor whose table holds a copy of rows, in id order.
+func NewFakeExecutor(builder Builder, rows []folder.Folder) *FakeExecutor {
+	return &FakeExecutor{builder: builder, rows: append([]folder.Folder{}, rows...)}
+}
+
+// Calls returns every statement run so far.
+func (e *FakeExecutor) Calls() []Statement {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Statement{}, e.calls...)
+}
+
+// Rows returns the current content of the table.
+func (e *FakeExecutor) Rows() []folder.Folder {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]folder.Folder{}, e.rows...)
+}
+
+// statement kinds, matched on the SQL a Builder generates
+func (e *FakeExecutor) kind(sql string) string {
+	b := e.builder
+	none := uuid.Nil
+	switch sql {
+	case b.All().SQL:
+		return "all"
+	case b.FoldersByOrgID(none).SQL:
+		return "by-org"
+	case b.FolderByName("").SQL:
+		return "by-name"
+	case b.FolderByOrgAndName(none, "").SQL:
+		return "by-org-name"
+	case b.ChildFolders(none, "").SQL:
+		return "children"
+	case b.MoveFolder(none, "", "", "").SQL:
+		return "move"
+	case b.DeleteFolder(none, "").SQL:
+		return "delete"
+	}
+	return ""
+}
+
+func (e *FakeExecutor) Query(_ context.Context, stmt Statement) (Rows, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, stmt)
+	if e.Err != nil {
+		return nil, e.Err
+	}
+
+	var match func(folder.Folder) bool
+	limit := -1
+	switch e.kind(stmt.SQL) {
+	case "all":
+		match = func(folder.Folder) bool { return true }
+	case "by-org":
+		org := stmt.Args[0].(string)
+		match = func(f folder.Folder) bool { return f.OrgId.String() == org }
+	case "by-name":
+		name := stmt.Args[0].(string)
+		match = func(f folder.Folder) bool { return f.Name == name }
+		limit = 1
+	case "by-org-name":
+		org, name := stmt.Args[0].(string), stmt.Args[1].(string)
+		match = func(f folder.Folder) bool { return f.OrgId.String() == org && f.Name == name }
+		limit = 1
+	case "children":
+		path, org := stmt.Args[0].(string), stmt.Args[1].(string)
+		match = func(f folder.Folder) bool {
+			return isDescendant(f.Paths, path) && f.Paths != path && f.OrgId.String() == org
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStatement, stmt.SQL)
+	}
+
+	result := []folder.Folder{}
+	for _, f := range e.rows {
+		if limit >= 0 && len(result) == limit {
+			break
+		}
+		if match(f) {
+			result = append(result, f)
+		}
+	}
+	return &fakeRows{rows: result, pos: -1}, nil
+}
+
+func (e *FakeExecutor) Exec(_ context.Context, stmt Statement) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, stmt)
+	if e.Err != nil {
+		return 0, e.Err
+	}
+
+	var affected int64
+	switch e.kind(stmt.SQL) {
+	case "move":
+		newPath, oldPath, org := stmt.Args[0].(string), stmt.Args[1].(string), stmt.Args[2].(string)
+		// path = $new || subpath(path, nlevel($old))
+		for i, f := range e.rows {
+			if isDescendant(f.Paths, oldPath) && f.OrgId.String() == org {
+				e.rows[i].Paths = newPath + f.Paths[len(oldPath):]
+				affected++
+			}
+		}
+	case "delete":
+		path, org := stmt.Args[0].(string), stmt.Args[1].(string)
+		kept := e.rows[:0]
+		for _, f := range e.rows {
+			if isDescendant(f.Paths, path) && f.OrgId.String() == org {
+				affected++
+				continue
+			}
+			kept = append(kept, f)
+		}
+		e.rows = kept
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownStatement, stmt.SQL)
+	}
+	return affected, nil
+}
+
+// isDescendant is ltree's path <@ ancestor, which includes the ancestor itself
+func isDescendant(path string, ancestor string) bool {
+	return path == ancestor || strings.HasPrefix(path, ancestor+".")
+}
+
+type fakeRows struct {
+	rows []folder.Folder
+	pos  int
+}
+
+func (r *fakeRows) Next() bool {
+	r.pos++
+	return r.pos < len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if len(dest) != 3 {
+		return fmt.Errorf("Error: expected 3 scan destinations, got %d", len(dest))
+	}
+	f := r.rows[r.pos]
+	name, ok1 := dest[0].(*string)
+	org, ok2 := dest[1].(*uuid.UUID)
+	path, ok3 := dest[2].(*string)
+	if !ok1 || !ok2 || !ok3 {
+		return errors.New("Error: unsupported scan destination")
+	}
+	*name, *org, *path = f.Name, f.OrgId, f.Paths
+	return nil
+}
+
+func (r *fakeRows) Err() error   { return nil }
+func (r *fakeRows) Close() error { return nil }
@@ -0,0 +1,122 @@
+// Package ltreesql turns folder driver operations into parameterized SQL for a
+// PostgreSQL table using the ltree extension:
+//
+//	CREATE EXTENSION IF NOT EXISTS ltree;
+//	CREATE TABLE folders (
+//		id     bigserial PRIMARY KEY,
+//		name   text  NOT NULL,
+//		org_id uuid  NOT NULL,
+//		path   ltree NOT NULL
+//	);
+//	CREATE INDEX folders_path_gist ON folders USING GIST (path);
+//	CREATE INDEX folders_org_name ON folders (org_id, name);
+//
+// The statements mirror the in-memory driver in the folder package, a
+// descendant of p is any row with path <@ p other than p itself. Folder names
+// with hyphens, which folder.IsValidLabel accepts, need PostgreSQL 16 or later.
+package ltreesql
+
+import (
+	"fmt"
+
+	"github.com/gofrs/uuid"
+)
+
+// DefaultTable is the table name used by New.
+const DefaultTable = "folders"
+
+// Statement is a query and its positional arguments, ready for database/sql.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// Statements builds the statements a Driver runs. Builder writes them for
+// Postgres ltree, other databases can provide their own with the same meaning.
+type Statements interface {
+	All() Statement
+	FoldersByOrgID(orgID uuid.UUID) Statement
+	FolderByName(name string) Statement
+	FolderByOrgAndName(orgID uuid.UUID, name string) Statement
+	ChildFolders(orgID uuid.UUID, path string) Statement
+	MoveFolder(orgID uuid.UUID, oldPath string, dstPath string, name string) Statement
+	DeleteFolder(orgID uuid.UUID, path string) Statement
+}
+
+// Builder builds statements against one folders table.
+type Builder struct {
+	table string
+}
+
+var _ Statements = Builder{}
+
+// New returns a builder for DefaultTable.
+func New() Builder {
+	return NewForTable(DefaultTable)
+}
+
+// NewForTable returns a builder for the given table, the name is used as is
+// so it must come from configuration and never from user input.
+func NewForTable(table string) Builder {
+	return Builder{table: table}
+}
+
+// columns selected by every query, in the order Scan expects them
+const columns = "name, org_id, path"
+
+// All selects every folder.
+func (b Builder) All() Statement {
+	return Statement{
+		SQL: fmt.Sprintf("SELECT %s FROM %s ORDER BY id", columns, b.table),
+	}
+}
+
+// FoldersByOrgID selects every folder of an org, see IDriver.GetFoldersByOrgID.
+func (b Builder) FoldersByOrgID(orgID uuid.UUID) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("SELECT %s FROM %s WHERE org_id = $1 ORDER BY id", columns, b.table),
+		Args: []any{orgID.String()},
+	}
+}
+
+// FolderByName selects the first folder with the given name in any org, used
+// for the existence and org checks the driver does before a read or a move.
+func (b Builder) FolderByName(name string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("SELECT %s FROM %s WHERE name = $1 ORDER BY id LIMIT 1", columns, b.table),
+		Args: []any{name},
+	}
+}
+
+// FolderByOrgAndName selects the folder with the given name within an org.
+func (b Builder) FolderByOrgAndName(orgID uuid.UUID, name string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("SELECT %s FROM %s WHERE org_id = $1 AND name = $2 ORDER BY id LIMIT 1", columns, b.table),
+		Args: []any{orgID.String(), name},
+	}
+}
+
+// ChildFolders selects every descendant of the folder at path, see IDriver.GetAllChildFolders.
+func (b Builder) ChildFolders(orgID uuid.UUID, path string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("SELECT %s FROM %s WHERE path <@ $1::ltree AND path <> $1::ltree AND org_id = $2 ORDER BY id", columns, b.table),
+		Args: []any{path, orgID.String()},
+	}
+}
+
+// MoveFolder rewrites the folder at oldPath and all of its descendants to live
+// under dstPath, in a single statement so the subtree is never half moved.
+func (b Builder) MoveFolder(orgID uuid.UUID, oldPath string, dstPath string, name string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("UPDATE %s SET path = $1::ltree || subpath(path, nlevel($2::ltree)) WHERE path <@ $2::ltree AND org_id = $3", b.table),
+		Args: []any{dstPath + "." + name, oldPath, orgID.String()},
+	}
+}
+
+// DeleteFolder deletes the folder at path and all of its descendants.
+func (b Builder) DeleteFolder(orgID uuid.UUID, path string) Statement {
+	return Statement{
+		SQL:  fmt.Sprintf("DELETE FROM %s WHERE path <@ $1::ltree AND org_id = $2", b.table),
+		Args: []any{path, orgID.String()},
+	}
+}
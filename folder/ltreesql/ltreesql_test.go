@@ -0,0 +1,55 @@
+package ltreesql_test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder/ltreesql"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run `go test ./folder/ltreesql -update` to rewrite the golden files after an intended change
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func Test_ltreesql_Statements(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	b := ltreesql.New()
+	tests := [...]struct {
+		name string
+		stmt ltreesql.Statement
+	}{
+		{name: "all", stmt: b.All()},
+		{name: "folders_by_org_id", stmt: b.FoldersByOrgID(orgID)},
+		{name: "folder_by_name", stmt: b.FolderByName("bravo")},
+		{name: "folder_by_org_and_name", stmt: b.FolderByOrgAndName(orgID, "bravo")},
+		{name: "child_folders", stmt: b.ChildFolders(orgID, "alpha.bravo")},
+		{name: "move_folder", stmt: b.MoveFolder(orgID, "alpha.bravo", "alpha.delta", "bravo")},
+		{name: "delete_folder", stmt: b.DeleteFolder(orgID, "alpha.bravo")},
+		{name: "custom_table", stmt: ltreesql.NewForTable("site_folders").FoldersByOrgID(orgID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the golden file holds the SQL followed by one line per argument
+			var got strings.Builder
+			got.WriteString(tt.stmt.SQL + "\n")
+			for i, arg := range tt.stmt.Args {
+				fmt.Fprintf(&got, "-- $%d = %v\n", i+1, arg)
+			}
+
+			path := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(path, []byte(got.String()), 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got.String())
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package folder
 
 import (
-	"errors"
-	"fmt"
 	"strings"
 )
 
@@ -11,22 +9,22 @@ func (f *driver) MoveFolder(name string, dst string) ([]Folder, error) {
 	dstExists := f.CheckFolderExists(dst)
 
 	if !nameExists {
-		return nil, errors.New("Error: Source folder does not exist")
+		return nil, ErrSourceNotFound
 	}
 
 	if !dstExists {
-		return nil, errors.New("Error: Destination folder does not exist")
+		return nil, ErrDestinationNotFound
 	}
 
 	if name == dst {
-		return nil, errors.New("Error: Cannot move a folder to itself")
+		return nil, ErrMoveToSelf
 	}
 
 	nameOrg := f.GetFolderOrgID(name)
 	dstOrg := f.GetFolderOrgID(dst)
 
 	if nameOrg != dstOrg {
-		return nil, errors.New("Error: Cannot move a folder to a different organization")
+		return nil, ErrMoveToOtherOrg
 	}
 
 	// nameOrg and dstOrg can be used interchangeably now
@@ -39,18 +37,18 @@ func (f *driver) MoveFolder(name string, dst string) ([]Folder, error) {
 	// This will work for both immediate connections but also deep connections
 	for _, folder := range nameChildFolders {
 		if folder.Name == dst {
-			return nil, errors.New("Error: Cannot move a folder to a child of itself")
+			return nil, ErrMoveToDescendant
 		}
 	}
 
 	// Finished Error handling
 
-	// This is really trivial as we are stated in the spec to not persist state
 	folders := f.folders
 	dstPath := ""
 
+	// names can repeat across orgs, only the folders of the org being moved in count
 	for _, folder := range folders {
-		if folder.Name == dst {
+		if folder.Name == dst && folder.OrgId == dstOrg {
 			dstPath = folder.Paths
 		}
 	}
@@ -58,13 +56,41 @@ func (f *driver) MoveFolder(name string, dst string) ([]Folder, error) {
 	// rewrite prefix
 	prefix := dstPath + "." + name
 
+	// work out every new path first so a store can reject the move before anything changes
+	changed := []int{}
+	newPaths := []string{}
 	for i := range folders {
+		if folders[i].OrgId != nameOrg {
+			continue
+		}
 		if folders[i].Name == name {
-			folders[i].Paths = dstPath + "." + name
+			changed = append(changed, i)
+			newPaths = append(newPaths, dstPath+"."+name)
 		} else if isInChildren(folders[i].Name, nameChildFolders) {
-			folders[i].Paths = concatPaths(folders[i].Paths, prefix)
+			changed = append(changed, i)
+			newPaths = append(newPaths, concatPaths(folders[i].Paths, prefix))
+		}
+	}
+
+	if f.store != nil {
+		mutation := Mutation{Op: OpMove, Args: []string{name, dst}}
+		for j, i := range changed {
+			mutation.Changed = append(mutation.Changed, Folder{Name: folders[i].Name, OrgId: folders[i].OrgId, Paths: newPaths[j]})
+		}
+		if err := f.store.Apply(mutation); err != nil {
+			return nil, err
+		}
+	}
+
+	notify := f.notifying()
+	event := Event{Type: FolderMoved, OrgID: nameOrg, Name: name}
+	for j, i := range changed {
+		if notify {
+			event.Changes = append(event.Changes, Change{Name: folders[i].Name, OldPath: folders[i].Paths, NewPath: newPaths[j]})
 		}
+		folders[i].Paths = newPaths[j]
 	}
+	f.publish(event)
 
 	return folders, nil
 }
@@ -95,7 +121,6 @@ func concatPaths(str string, prefix string) string {
 			stoppingIndex = i
 		}
 	}
-	fmt.Println(str, strSplit, prefix, prefixSplit)
 
 	result := strings.Join(prefixSplit, ".") + "." + strings.Join(strSplit[stoppingIndex+1:], ".")
 	return result
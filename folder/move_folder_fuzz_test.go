@@ -0,0 +1,289 @@
+package folder_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// Property and fuzz tests for MoveFolder. Random trees are grown the same way
+// as generateTree in static.go, then random moves are applied and the
+// invariants below are checked after every one of them.
+//
+// run the fuzzer with `go test ./folder -run '^$' -fuzz FuzzMoveFolder`, any
+// failing input lands in testdata/fuzz/FuzzMoveFolder and is replayed by a
+// plain `go test` from then on.
+
+var fuzzOrgs = []uuid.UUID{
+	uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7"),
+	uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a"),
+	uuid.FromStringOrNil("9b4cdb0d-5c4e-4f2a-9b1f-0d0c6f0b1a11"),
+}
+
+// randomTree grows up to roots trees per org, each node gets 0..fanout
+// children down to depth levels. Names are unique within an org, but every
+// org counts from "f1", so the same names show up in other orgs at other
+// paths. Some of them share prefixes ("f1", "f10", ...) to catch naive prefix
+// matching.
+func randomTree(rng *rand.Rand, orgs int, roots int, fanout int, depth int) []folder.Folder {
+	folders := []folder.Folder{}
+	next := 0
+	newName := func() string {
+		next++
+		return fmt.Sprintf("f%d", next)
+	}
+
+	var grow func(parent folder.Folder, level int)
+	grow = func(parent folder.Folder, level int) {
+		if level >= depth {
+			return
+		}
+		for i := rng.Intn(fanout + 1); i > 0; i-- {
+			name := newName()
+			child := folder.Folder{Name: name, OrgId: parent.OrgId, Paths: parent.Paths + "." + name}
+			folders = append(folders, child)
+			grow(child, level+1)
+		}
+	}
+
+	for o := 0; o < orgs; o++ {
+		next = 0
+		for r := 0; r < roots; r++ {
+			name := newName()
+			root := folder.Folder{Name: name, OrgId: fuzzOrgs[o], Paths: name}
+			folders = append(folders, root)
+			grow(root, 1)
+		}
+	}
+	return folders
+}
+
+func copyFolders(folders []folder.Folder) []folder.Folder {
+	return append([]folder.Folder{}, folders...)
+}
+
+// fuzzKey identifies a folder, names only are unique within an org
+type fuzzKey struct {
+	org  uuid.UUID
+	name string
+}
+
+func keyOf(f folder.Folder) fuzzKey {
+	return fuzzKey{f.OrgId, f.Name}
+}
+
+func byKey(folders []folder.Folder) map[fuzzKey]folder.Folder {
+	m := make(map[fuzzKey]folder.Folder, len(folders))
+	for _, f := range folders {
+		m[keyOf(f)] = f
+	}
+	return m
+}
+
+// first returns the folder MoveFolder picks for a name, the first one with
+// that name in any org
+func first(folders []folder.Folder, name string) (folder.Folder, bool) {
+	for _, f := range folders {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return folder.Folder{}, false
+}
+
+func subtreeSize(folders []folder.Folder, root folder.Folder) int {
+	n := 0
+	for _, f := range folders {
+		if f.OrgId == root.OrgId && strings.HasPrefix(f.Paths, root.Paths+".") {
+			n++
+		}
+	}
+	return n
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// expectedMoveErr is what MoveFolder should return, worked out from the paths alone
+func expectedMoveErr(before []folder.Folder, src, dst string) error {
+	s, ok := first(before, src)
+	if !ok {
+		return folder.ErrSourceNotFound
+	}
+	d, ok := first(before, dst)
+	if !ok {
+		return folder.ErrDestinationNotFound
+	}
+	switch {
+	case src == dst:
+		return folder.ErrMoveToSelf
+	case s.OrgId != d.OrgId:
+		return folder.ErrMoveToOtherOrg
+	case strings.HasPrefix(d.Paths, s.Paths+"."):
+		return folder.ErrMoveToDescendant
+	}
+	return nil
+}
+
+// checkTree checks the invariants that hold for any valid folder set
+func checkTree(folders []folder.Folder) error {
+	paths := map[uuid.UUID]map[string]bool{}
+	for _, f := range folders {
+		if paths[f.OrgId] == nil {
+			paths[f.OrgId] = map[string]bool{}
+		}
+		paths[f.OrgId][f.Paths] = true
+	}
+	for _, f := range folders {
+		labels := strings.Split(f.Paths, ".")
+		if labels[len(labels)-1] != f.Name {
+			return fmt.Errorf("%s: last label of %q is not its name", f.Name, f.Paths)
+		}
+		if parent := parentPath(f.Paths); parent != "" && !paths[f.OrgId][parent] {
+			return fmt.Errorf("%s: parent %q does not exist in its org", f.Name, parent)
+		}
+	}
+	return nil
+}
+
+// checkMove applies one move and checks it against the state before it
+func checkMove(d folder.IDriver, before []folder.Folder, src, dst string) ([]folder.Folder, error) {
+	old := byKey(before)
+	got, err := d.MoveFolder(src, dst)
+
+	want := expectedMoveErr(before, src, dst)
+	if !errors.Is(err, want) || (want == nil && err != nil) {
+		return nil, fmt.Errorf("move %s -> %s: got error %v, want %v", src, dst, err, want)
+	}
+	if err != nil {
+		return before, nil
+	}
+
+	if len(got) != len(before) {
+		return nil, fmt.Errorf("move %s -> %s: folder count changed from %d to %d", src, dst, len(before), len(got))
+	}
+	if err := checkTree(got); err != nil {
+		return nil, fmt.Errorf("move %s -> %s: %w", src, dst, err)
+	}
+
+	now := byKey(got)
+	for k := range old {
+		if _, ok := now[k]; !ok {
+			return nil, fmt.Errorf("move %s -> %s: %s left org %s", src, dst, k.name, k.org)
+		}
+	}
+	moved, _ := first(before, src)
+	target, _ := first(before, dst)
+	if a, b := subtreeSize(before, moved), subtreeSize(got, now[keyOf(moved)]); a != b {
+		return nil, fmt.Errorf("move %s -> %s: subtree size changed from %d to %d", src, dst, a, b)
+	}
+	if want := target.Paths + "." + src; now[keyOf(moved)].Paths != want {
+		return nil, fmt.Errorf("move %s -> %s: moved folder is at %q, want %q", src, dst, now[keyOf(moved)].Paths, want)
+	}
+	// folders outside the moved subtree stay where they were, in every org
+	for k, f := range old {
+		inSubtree := k == keyOf(moved) || (f.OrgId == moved.OrgId && strings.HasPrefix(f.Paths, moved.Paths+"."))
+		if !inSubtree && now[k].Paths != f.Paths {
+			return nil, fmt.Errorf("move %s -> %s: %s of org %s moved from %q to %q", src, dst, k.name, k.org, f.Paths, now[k].Paths)
+		}
+	}
+	return copyFolders(got), nil
+}
+
+// runMoves applies moves picked from ops, two bytes per move, and moves every
+// non-root folder back to its original parent to check the tree is restored
+func runMoves(folders []folder.Folder, ops []byte) error {
+	if err := checkTree(folders); err != nil {
+		return fmt.Errorf("generated tree: %w", err)
+	}
+	if len(folders) == 0 {
+		return nil
+	}
+
+	d := folder.NewDriver(copyFolders(folders))
+	state := copyFolders(folders)
+	for i := 0; i+1 < len(ops); i += 2 {
+		src := folders[int(ops[i])%len(folders)].Name
+		dst := folders[int(ops[i+1])%len(folders)].Name
+
+		moved, _ := first(state, src)
+		next, err := checkMove(d, state, src, dst)
+		if err != nil {
+			return err
+		}
+
+		// moving straight back to the old parent restores the previous tree.
+		// Roots can't be moved back, and neither can folders whose parent's
+		// name comes first in another org.
+		parent := parentPath(moved.Paths)
+		back := parent[strings.LastIndex(parent, ".")+1:]
+		if expectedMoveErr(state, src, dst) == nil && parent != "" && expectedMoveErr(next, src, back) == nil {
+			restored, err := checkMove(d, next, src, back)
+			if err != nil {
+				return fmt.Errorf("moving back: %w", err)
+			}
+			if !equalFolders(restored, state) {
+				return fmt.Errorf("move %s -> %s and back did not restore the tree", src, dst)
+			}
+			// and forward again to carry on from the moved state
+			if next, err = checkMove(d, restored, src, dst); err != nil {
+				return err
+			}
+		}
+		state = next
+	}
+	return nil
+}
+
+func equalFolders(a, b []folder.Folder) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzMoveFolder(f *testing.F) {
+	f.Add(int64(1), uint8(2), uint8(2), uint8(3), uint8(4), []byte{1, 5, 3, 0, 7, 2})
+	f.Add(int64(42), uint8(1), uint8(1), uint8(1), uint8(6), []byte{0, 5, 5, 0, 2, 4})
+	f.Add(int64(7), uint8(3), uint8(3), uint8(4), uint8(3), []byte{10, 11, 12, 13, 1, 30, 2, 40})
+
+	f.Fuzz(func(t *testing.T, seed int64, orgs, roots, fanout, depth uint8, ops []byte) {
+		// keep trees small enough that every input runs fast
+		rng := rand.New(rand.NewSource(seed))
+		folders := randomTree(rng, int(orgs%3)+1, int(roots%3)+1, int(fanout%4)+1, int(depth%6)+1)
+		if len(ops) > 64 {
+			ops = ops[:64]
+		}
+		if err := runMoves(folders, ops); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_folder_MoveFolder_Properties(t *testing.T) {
+	t.Parallel()
+	for seed := int64(0); seed < 200; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		folders := randomTree(rng, 1+rng.Intn(3), 1+rng.Intn(3), 1+rng.Intn(4), 1+rng.Intn(6))
+		ops := make([]byte, 2*(1+rng.Intn(20)))
+		rng.Read(ops)
+
+		if err := runMoves(folders, ops); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+	}
+}
@@ -224,3 +224,33 @@ func Test_folder_MoveFolder(t *testing.T) {
 		})
 	}
 }
+
+func Test_folder_MoveFolder_NamesInOtherOrgs(t *testing.T) {
+	t.Parallel()
+	orgA := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	orgB := uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")
+	// golf, bravo and charlie also exist in orgB, the move only changes orgA
+	f := folder.NewDriver([]folder.Folder{
+		{Name: "alpha", OrgId: orgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgA, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: orgA, Paths: "alpha.bravo.charlie"},
+		{Name: "golf", OrgId: orgA, Paths: "golf"},
+		{Name: "india", OrgId: orgB, Paths: "india"},
+		{Name: "golf", OrgId: orgB, Paths: "india.golf"},
+		{Name: "bravo", OrgId: orgB, Paths: "india.golf.bravo"},
+		{Name: "charlie", OrgId: orgB, Paths: "india.golf.bravo.charlie"},
+	})
+
+	got, err := f.MoveFolder("bravo", "golf")
+	assert.NoError(t, err)
+	assert.Equal(t, []folder.Folder{
+		{Name: "alpha", OrgId: orgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgA, Paths: "golf.bravo"},
+		{Name: "charlie", OrgId: orgA, Paths: "golf.bravo.charlie"},
+		{Name: "golf", OrgId: orgA, Paths: "golf"},
+		{Name: "india", OrgId: orgB, Paths: "india"},
+		{Name: "golf", OrgId: orgB, Paths: "india.golf"},
+		{Name: "bravo", OrgId: orgB, Paths: "india.golf.bravo"},
+		{Name: "charlie", OrgId: orgB, Paths: "india.golf.bravo.charlie"},
+	}, got)
+}
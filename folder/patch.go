@@ -0,0 +1,431 @@
+package folder
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+var (
+	ErrPatchConflict     = errors.New("Error: Patch conflicts with the folders")
+	ErrPathChanged       = errors.New("Error: Folder is not where the patch expects it")
+	ErrUnknownPatchOp    = errors.New("Error: Unknown patch operation")
+	ErrUnpatchable       = errors.New("Error: Changes cannot be expressed as a patch")
+	ErrPatchNotSupported = errors.New("Error: Driver does not support this patch operation")
+	ErrPatchRollback     = errors.New("Error: Patch failed and could not be undone")
+)
+
+// Patch is a list of operations that turn one folder set into another, made
+// by NewPatch and applied by Apply. It is plain JSON so it can be stored and
+// applied elsewhere, e.g. to promote a staging org to production:
+//
+//	[
+//		{"op": "rename", "org_id": "...", "name": "delta", "path": "alpha.delta", "new_name": "yankee"},
+//		{"op": "move", "org_id": "...", "name": "bravo", "path": "alpha.bravo", "parent": "echo", "parent_path": "alpha.yankee.echo"}
+//	]
+type Patch []PatchOp
+
+// PatchOp is one operation of a Patch, Op is OpCreate, OpMove, OpRename or OpDelete.
+type PatchOp struct {
+	Op    string    `json:"op"`
+	OrgID uuid.UUID `json:"org_id"`
+	Name  string    `json:"name"`
+	// where a moved, renamed or deleted folder is expected to be, not checked when empty
+	Path string `json:"path,omitempty"`
+	// the parent of a created or moved folder, empty for the root of the org
+	Parent string `json:"parent,omitempty"`
+	// where Parent is expected to be, not checked when empty
+	ParentPath string `json:"parent_path,omitempty"`
+	// the new name of a renamed folder
+	NewName string `json:"new_name,omitempty"`
+}
+
+// NewPatch returns the operations that turn before into after, found with
+// Diff. Renames go first, then creates, moves and deletes, and a folder that
+// changed org is deleted and created again in its new org with its subtree.
+// Every operation records the paths its folders are at when it runs, so Apply
+// can tell when the folders have changed since.
+func NewPatch(before, after []Folder) (Patch, error) {
+	entries := Diff(before, after)
+	scratch := newDriver(append([]Folder{}, before...))
+	cur := foldersByName(after)
+	patch := Patch{}
+
+	add := func(op PatchOp) error {
+		if op.Op != OpCreate {
+			op.Path = scratch.pathOf(op.OrgID, op.Name)
+		}
+		if op.Parent != "" {
+			op.ParentPath = scratch.pathOf(op.OrgID, op.Parent)
+		}
+		if _, err := applyPatchOp(scratch, op); err != nil {
+			return fmt.Errorf("%w: %s %s: %w", ErrUnpatchable, op.Op, op.Name, err)
+		}
+		patch = append(patch, op)
+		return nil
+	}
+	// the subtrees of the folders that changed org are created last, after
+	// they were deleted from their old org
+	later := map[string]bool{}
+	for _, e := range entries {
+		if e.Kind == DiffOrgChanged {
+			later[e.Name] = true
+			for _, f := range after {
+				if f.OrgId == e.OrgID && IsChildFolder(f, e.NewPath) {
+					later[f.Name] = true
+				}
+			}
+		}
+	}
+	// folders that didn't exist under any name, the ones moved below them move by themselves
+	old := foldersByName(before)
+	renamedTo := map[string]bool{}
+	for _, e := range entries {
+		if e.Kind == DiffRenamed {
+			renamedTo[e.Name] = true
+		}
+	}
+	isNew := func(name string) bool {
+		_, ok := old[name]
+		return !ok && !renamedTo[name] && !later[name]
+	}
+	// create adds the folder at path and the folders below it in after that
+	// include selects, parents first
+	created := map[string]bool{}
+	create := func(orgID uuid.UUID, path string, include func(name string) bool) error {
+		subtree := []Folder{cur[lastLabel(path)]}
+		for _, f := range after {
+			if f.OrgId == orgID && IsChildFolder(f, path) {
+				subtree = append(subtree, f)
+			}
+		}
+		sort.Slice(subtree, func(i, j int) bool { return subtree[i].Paths < subtree[j].Paths })
+		for _, f := range subtree {
+			if created[f.Name] || !include(f.Name) {
+				continue
+			}
+			created[f.Name] = true
+			parent := ""
+			if p := parentPath(f.Paths); p != "" {
+				parent = lastLabel(p)
+			}
+			if err := add(PatchOp{Op: OpCreate, OrgID: orgID, Name: f.Name, Parent: parent}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// entries are ordered by path, so parents are renamed and created before their children
+	for _, e := range entries {
+		if e.Kind == DiffRenamed {
+			if err := add(PatchOp{Op: OpRename, OrgID: e.OrgID, Name: e.OldName, NewName: e.Name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, e := range entries {
+		if e.Kind == DiffAdded && isNew(e.Name) {
+			if err := create(e.OrgID, e.NewPath, isNew); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// the shallowest destinations first, so a folder moving out from under
+	// another one does so before that one moves below it
+	moves := byDepth(entries, DiffMoved, func(e DiffEntry) string { return e.NewPath })
+	for _, e := range moves {
+		parent := ""
+		if p := parentPath(e.NewPath); p != "" {
+			parent = lastLabel(p)
+		}
+		if err := add(PatchOp{Op: OpMove, OrgID: e.OrgID, Name: e.Name, Parent: parent}); err != nil {
+			return nil, err
+		}
+	}
+	deletes := append(byDepth(entries, DiffRemoved, func(e DiffEntry) string { return e.OldPath }),
+		byDepth(entries, DiffOrgChanged, func(e DiffEntry) string { return e.OldPath })...)
+	for _, e := range deletes {
+		orgID := e.OrgID
+		if e.Kind == DiffOrgChanged {
+			orgID = e.OldOrgID
+		}
+		// gone with an ancestor already
+		if !scratch.CheckFolderExistsWithinOrg(orgID, e.Name) {
+			continue
+		}
+		if err := add(PatchOp{Op: OpDelete, OrgID: orgID, Name: e.Name}); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range byDepth(entries, DiffOrgChanged, func(e DiffEntry) string { return e.NewPath }) {
+		if err := create(e.OrgID, e.NewPath, func(name string) bool { return later[name] }); err != nil {
+			return nil, err
+		}
+	}
+
+	if left := Diff(scratch.folders, after); len(left) > 0 {
+		return nil, fmt.Errorf("%w: %d changes left", ErrUnpatchable, len(left))
+	}
+	return patch, nil
+}
+
+// byDepth returns the entries of kind ordered by the depth of path, then by path
+func byDepth(entries []DiffEntry, kind DiffKind, path func(DiffEntry) string) []DiffEntry {
+	res := []DiffEntry{}
+	for _, e := range entries {
+		if e.Kind == kind {
+			res = append(res, e)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := path(res[i]), path(res[j])
+		if da, db := strings.Count(a, "."), strings.Count(b, "."); da != db {
+			return da < db
+		}
+		return a < b
+	})
+	return res
+}
+
+// PatchMode is what Apply does with the operations that conflict.
+type PatchMode int
+
+const (
+	// Strict applies nothing when any operation conflicts.
+	Strict PatchMode = iota
+	// BestEffort skips the operations that conflict and applies the rest.
+	BestEffort
+)
+
+type ApplyOptions struct {
+	Mode PatchMode
+	// applies every operation to this org instead of its own when set, e.g.
+	// to promote the changes of a staging org to production
+	OrgID uuid.UUID
+}
+
+// Conflict is an operation that can't be applied to the folders as they are.
+type Conflict struct {
+	// the position of the operation in the patch
+	Index int
+	Op    PatchOp
+	Err   error
+}
+
+func (c Conflict) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %v", c.Index, c.Op.Op, c.Op.Name, c.Err)
+}
+
+func (c Conflict) Unwrap() error {
+	return c.Err
+}
+
+type ApplyResult struct {
+	// the number of operations made
+	Applied int
+	// ordered like the patch
+	Conflicts []Conflict
+}
+
+// Apply makes the operations of patch with d, in order. An operation
+// conflicts when its folder or parent doesn't exist in the org, isn't at the
+// path the patch expects, or when the driver rejects it, e.g. because the name
+// is taken. In Strict mode the whole patch is first checked on a copy of the
+// folders of the orgs it names, and when anything conflicts nothing is applied
+// and the error is ErrPatchConflict. The real folders can still differ from
+// the copy, they may have changed since or a name may also be used in another
+// org. When an operation fails anyway, the ones already made are undone in
+// reverse order and the error is its Conflict, or wraps ErrPatchRollback when
+// undoing fails too. In BestEffort mode conflicting operations are skipped,
+// which usually makes the ones that depend on them conflict too.
+//
+// Moves are made with MoveFolder, so any driver can apply them. Creates,
+// renames and deletes need an Editor, and moves to the root a driver of this
+// package or a History; without them these operations conflict with
+// ErrPatchNotSupported.
+func Apply(d IDriver, patch Patch, opts ApplyOptions) (ApplyResult, error) {
+	if opts.OrgID != uuid.Nil {
+		promoted := make(Patch, len(patch))
+		for i, op := range patch {
+			op.OrgID = opts.OrgID
+			promoted[i] = op
+		}
+		patch = promoted
+	}
+
+	if opts.Mode == Strict {
+		seen := map[uuid.UUID]bool{}
+		folders := []Folder{}
+		for _, op := range patch {
+			if !seen[op.OrgID] {
+				seen[op.OrgID] = true
+				folders = append(folders, d.GetFoldersByOrgID(op.OrgID)...)
+			}
+		}
+		dry := newDriver(folders)
+		conflicts := []Conflict{}
+		for i, op := range patch {
+			err := supports(d, op)
+			if err == nil {
+				_, err = applyPatchOp(dry, op)
+			}
+			if err != nil {
+				conflicts = append(conflicts, Conflict{Index: i, Op: op, Err: err})
+			}
+		}
+		if len(conflicts) > 0 {
+			return ApplyResult{Conflicts: conflicts},
+				fmt.Errorf("%w: %d of %d operations", ErrPatchConflict, len(conflicts), len(patch))
+		}
+	}
+
+	res := ApplyResult{Conflicts: []Conflict{}}
+	// how to undo each operation made, in Strict mode
+	undos := []func() error{}
+	for i, op := range patch {
+		undo, err := applyPatchOp(d, op)
+		if err != nil {
+			conflict := Conflict{Index: i, Op: op, Err: err}
+			res.Conflicts = append(res.Conflicts, conflict)
+			if opts.Mode == Strict {
+				return res, rollback(&res, undos, conflict)
+			}
+			continue
+		}
+		undos = append(undos, undo)
+		res.Applied++
+	}
+	return res, nil
+}
+
+// rollback undoes the operations made before conflict, newest first
+func rollback(res *ApplyResult, undos []func() error, conflict Conflict) error {
+	for i := len(undos) - 1; i >= 0; i-- {
+		if err := undos[i](); err != nil {
+			return fmt.Errorf("%w: %w, undoing operation %d: %w", ErrPatchRollback, conflict, i, err)
+		}
+		res.Applied--
+	}
+	return conflict
+}
+
+// supports returns an error when d can't make op at all
+func supports(d IDriver, op PatchOp) error {
+	switch op.Op {
+	case OpCreate, OpRename, OpDelete:
+		if _, ok := d.(Editor); !ok {
+			return ErrPatchNotSupported
+		}
+	case OpMove:
+		if _, ok := d.(rootMover); op.Parent == "" && !ok {
+			return ErrPatchNotSupported
+		}
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownPatchOp, op.Op)
+	}
+	return nil
+}
+
+// applyPatchOp checks that the folders of op are where it expects them, makes
+// it and returns how to undo it
+func applyPatchOp(d IDriver, op PatchOp) (func() error, error) {
+	if err := supports(d, op); err != nil {
+		return nil, err
+	}
+	from := ""
+	if op.Op != OpCreate {
+		path, err := checkPatchPath(d, op.OrgID, op.Name, op.Path, ErrSourceNotFound)
+		if err != nil {
+			return nil, err
+		}
+		from = parentPath(path)
+	}
+	if (op.Op == OpCreate || op.Op == OpMove) && op.Parent != "" {
+		if _, err := checkPatchPath(d, op.OrgID, op.Parent, op.ParentPath, ErrDestinationNotFound); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case OpCreate:
+		e := d.(Editor)
+		if _, err := e.CreateFolder(op.OrgID, op.Name, op.Parent); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := e.DeleteFolder(op.OrgID, op.Name)
+			return err
+		}, nil
+	case OpMove:
+		var err error
+		if op.Parent == "" {
+			_, err = d.(rootMover).moveToRoot(op.OrgID, op.Name)
+		} else {
+			_, err = d.MoveFolder(op.Name, op.Parent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			var err error
+			if from == "" {
+				m, ok := d.(rootMover)
+				if !ok {
+					return ErrPatchNotSupported
+				}
+				_, err = m.moveToRoot(op.OrgID, op.Name)
+			} else {
+				_, err = d.MoveFolder(op.Name, lastLabel(from))
+			}
+			return err
+		}, nil
+	case OpRename:
+		e := d.(Editor)
+		if _, err := e.RenameFolder(op.OrgID, op.Name, op.NewName); err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := e.RenameFolder(op.OrgID, op.NewName, op.Name)
+			return err
+		}, nil
+	default:
+		e := d.(Editor)
+		deleted, err := e.DeleteFolder(op.OrgID, op.Name)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append([]Folder{}, deleted...)
+		return func() error {
+			_, err := recreate(e, op.OrgID, deleted)
+			return err
+		}, nil
+	}
+}
+
+// checkPatchPath checks that name is in orgID at path and returns where it is,
+// notFound is returned when it isn't in the org
+func checkPatchPath(d IDriver, orgID uuid.UUID, name string, path string, notFound error) (string, error) {
+	for _, f := range d.GetFoldersByOrgID(orgID) {
+		if f.Name != name {
+			continue
+		}
+		if path != "" && f.Paths != path {
+			return "", fmt.Errorf("%w: %s is at %s, not %s", ErrPathChanged, name, f.Paths, path)
+		}
+		return f.Paths, nil
+	}
+	return "", notFound
+}
+
+// pathOf returns the path of the folder called name in orgID, empty when there is none
+func (f *driver) pathOf(orgID uuid.UUID, name string) string {
+	i, err := f.indexInOrg(orgID, name)
+	if err != nil {
+		return ""
+	}
+	return f.folders[i].Paths
+}
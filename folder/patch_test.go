@@ -0,0 +1,310 @@
+package folder_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// allFolders returns the folders of the test orgs
+func allFolders(d folder.IDriver) []folder.Folder {
+	return append(d.GetFoldersByOrgID(foldertest.OrgA), d.GetFoldersByOrgID(foldertest.OrgB)...)
+}
+
+func Test_folder_NewPatch(t *testing.T) {
+	t.Parallel()
+	a, b := foldertest.OrgA, foldertest.OrgB
+	after := edited(t, func(d folder.IDriver, e folder.Editor) {
+		_, err := e.RenameFolder(a, "delta", "yankee")
+		require.NoError(t, err)
+		_, err = d.MoveFolder("bravo", "echo")
+		require.NoError(t, err)
+		_, err = e.DeleteFolder(a, "golf")
+		require.NoError(t, err)
+		_, err = e.CreateFolder(b, "hotel", "foxtrot")
+		require.NoError(t, err)
+		_, err = e.CreateFolder(b, "india", "hotel")
+		require.NoError(t, err)
+	})
+
+	patch, err := folder.NewPatch(foldertest.MoveData(), after)
+	require.NoError(t, err)
+	assert.Equal(t, folder.Patch{
+		{Op: folder.OpRename, OrgID: a, Name: "delta", Path: "alpha.delta", NewName: "yankee"},
+		{Op: folder.OpCreate, OrgID: b, Name: "hotel", Parent: "foxtrot", ParentPath: "foxtrot"},
+		{Op: folder.OpCreate, OrgID: b, Name: "india", Parent: "hotel", ParentPath: "foxtrot.hotel"},
+		{Op: folder.OpMove, OrgID: a, Name: "bravo", Path: "alpha.bravo", Parent: "echo", ParentPath: "alpha.yankee.echo"},
+		{Op: folder.OpDelete, OrgID: a, Name: "golf", Path: "golf"},
+	}, patch)
+
+	d := folder.NewDriver(foldertest.MoveData())
+	res, err := folder.Apply(d, patch, folder.ApplyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, res.Applied)
+	assert.Empty(t, res.Conflicts)
+	assert.ElementsMatch(t, after, allFolders(d))
+}
+
+func Test_folder_NewPatch_RoundTrip(t *testing.T) {
+	t.Parallel()
+	a, b := foldertest.OrgA, foldertest.OrgB
+	tests := [...]struct {
+		name  string
+		after []folder.Folder
+	}{
+		{name: "nothing", after: foldertest.MoveData()},
+		{
+			name: "swapped parent and child",
+			after: []folder.Folder{
+				{Name: "bravo", OrgId: a, Paths: "bravo"},
+				{Name: "alpha", OrgId: a, Paths: "bravo.alpha"},
+				{Name: "charlie", OrgId: a, Paths: "bravo.charlie"},
+				{Name: "delta", OrgId: a, Paths: "bravo.alpha.delta"},
+				{Name: "echo", OrgId: a, Paths: "bravo.alpha.delta.echo"},
+				{Name: "foxtrot", OrgId: b, Paths: "foxtrot"},
+				{Name: "golf", OrgId: a, Paths: "golf"},
+			},
+		},
+		{
+			name: "org changed with a new child",
+			after: []folder.Folder{
+				{Name: "alpha", OrgId: a, Paths: "alpha"},
+				{Name: "delta", OrgId: a, Paths: "alpha.delta"},
+				{Name: "echo", OrgId: a, Paths: "alpha.delta.echo"},
+				{Name: "foxtrot", OrgId: b, Paths: "foxtrot"},
+				{Name: "bravo", OrgId: b, Paths: "foxtrot.bravo"},
+				{Name: "charlie", OrgId: b, Paths: "foxtrot.bravo.charlie"},
+				{Name: "kilo", OrgId: b, Paths: "foxtrot.bravo.kilo"},
+				{Name: "golf", OrgId: a, Paths: "golf"},
+			},
+		},
+		{
+			name: "existing folder moved below a new one",
+			after: []folder.Folder{
+				{Name: "alpha", OrgId: a, Paths: "alpha"},
+				{Name: "bravo", OrgId: a, Paths: "alpha.bravo"},
+				{Name: "charlie", OrgId: a, Paths: "alpha.bravo.charlie"},
+				{Name: "delta", OrgId: a, Paths: "alpha.delta"},
+				{Name: "echo", OrgId: a, Paths: "alpha.delta.echo"},
+				{Name: "foxtrot", OrgId: b, Paths: "foxtrot"},
+				{Name: "hotel", OrgId: a, Paths: "alpha.hotel"},
+				{Name: "golf", OrgId: a, Paths: "alpha.hotel.golf"},
+			},
+		},
+		{
+			name: "everything gone",
+			after: []folder.Folder{
+				{Name: "foxtrot", OrgId: b, Paths: "foxtrot"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			patch, err := folder.NewPatch(foldertest.MoveData(), tt.after)
+			require.NoError(t, err)
+
+			// through JSON, like a patch stored in a file
+			data, err := json.Marshal(patch)
+			require.NoError(t, err)
+			var decoded folder.Patch
+			require.NoError(t, json.Unmarshal(data, &decoded))
+
+			d := folder.NewDriver(foldertest.MoveData())
+			_, err = folder.Apply(d, decoded, folder.ApplyOptions{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.after, allFolders(d))
+		})
+	}
+}
+
+func Test_folder_Apply_Conflicts(t *testing.T) {
+	t.Parallel()
+	a := foldertest.OrgA
+	patch := folder.Patch{
+		{Op: folder.OpMove, OrgID: a, Name: "bravo", Path: "alpha.bravo", Parent: "echo", ParentPath: "alpha.delta.echo"},
+		{Op: folder.OpCreate, OrgID: a, Name: "hotel", Parent: "golf", ParentPath: "golf"},
+		{Op: folder.OpRename, OrgID: a, Name: "charlie", Path: "alpha.delta.echo.bravo.charlie", NewName: "zulu"},
+	}
+	tests := [...]struct {
+		name string
+		// changes the folders after the patch was made
+		change  func(t *testing.T, d folder.IDriver, e folder.Editor)
+		mode    folder.PatchMode
+		err     error
+		applied int
+		// the index and error of every conflict
+		conflicts map[int]error
+	}{
+		{
+			name:    "no conflicts",
+			change:  func(t *testing.T, d folder.IDriver, e folder.Editor) {},
+			applied: 3,
+		},
+		{
+			name: "source gone, strict",
+			change: func(t *testing.T, d folder.IDriver, e folder.Editor) {
+				_, err := e.DeleteFolder(a, "bravo")
+				require.NoError(t, err)
+			},
+			err: folder.ErrPatchConflict,
+			// charlie went with bravo
+			conflicts: map[int]error{0: folder.ErrSourceNotFound, 2: folder.ErrSourceNotFound},
+		},
+		{
+			name: "destination moved, strict",
+			change: func(t *testing.T, d folder.IDriver, e folder.Editor) {
+				_, err := d.MoveFolder("echo", "golf")
+				require.NoError(t, err)
+			},
+			err: folder.ErrPatchConflict,
+			// charlie isn't where the patch moves it either
+			conflicts: map[int]error{0: folder.ErrPathChanged, 2: folder.ErrPathChanged},
+		},
+		{
+			name: "destination moved, best effort",
+			change: func(t *testing.T, d folder.IDriver, e folder.Editor) {
+				_, err := d.MoveFolder("echo", "golf")
+				require.NoError(t, err)
+			},
+			mode:      folder.BestEffort,
+			applied:   1,
+			conflicts: map[int]error{0: folder.ErrPathChanged, 2: folder.ErrPathChanged},
+		},
+		{
+			name: "name taken, best effort",
+			change: func(t *testing.T, d folder.IDriver, e folder.Editor) {
+				_, err := e.CreateFolder(a, "hotel", "alpha")
+				require.NoError(t, err)
+			},
+			mode:      folder.BestEffort,
+			applied:   2,
+			conflicts: map[int]error{1: folder.ErrFolderExists},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d, e := editor(t)
+			tt.change(t, d, e)
+			before := append([]folder.Folder{}, allFolders(d)...)
+
+			res, err := folder.Apply(d, patch, folder.ApplyOptions{Mode: tt.mode})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.applied, res.Applied)
+			got := map[int]error{}
+			for _, c := range res.Conflicts {
+				assert.Equal(t, patch[c.Index], c.Op)
+				got[c.Index] = c.Err
+			}
+			require.Len(t, got, len(tt.conflicts))
+			for i, want := range tt.conflicts {
+				assert.ErrorIs(t, got[i], want, "operation %d", i)
+			}
+			if tt.applied == 0 {
+				assert.Equal(t, before, allFolders(d))
+			}
+		})
+	}
+}
+
+func Test_folder_Apply_StrictRollback(t *testing.T) {
+	t.Parallel()
+	a := foldertest.OrgA
+	// india is also the name of a folder of org B, listed first, so MoveFolder
+	// finds that one. The dry run only sees org A and misses it.
+	folders := append([]folder.Folder{{Name: "india", OrgId: foldertest.OrgB, Paths: "india"}}, foldertest.MoveData()...)
+	folders = append(folders, folder.Folder{Name: "india", OrgId: a, Paths: "golf.india"})
+	d := folder.NewDriver(folders)
+	before := append([]folder.Folder{}, allFolders(d)...)
+
+	patch := folder.Patch{
+		{Op: folder.OpMove, OrgID: a, Name: "echo", Path: "alpha.delta.echo", Parent: "golf", ParentPath: "golf"},
+		{Op: folder.OpCreate, OrgID: a, Name: "hotel", Parent: "alpha", ParentPath: "alpha"},
+		{Op: folder.OpRename, OrgID: a, Name: "delta", Path: "alpha.delta", NewName: "yankee"},
+		{Op: folder.OpDelete, OrgID: a, Name: "bravo", Path: "alpha.bravo"},
+		{Op: folder.OpMove, OrgID: a, Name: "india", Path: "golf.india", Parent: "alpha", ParentPath: "alpha"},
+	}
+	res, err := folder.Apply(d, patch, folder.ApplyOptions{})
+	assert.ErrorIs(t, err, folder.ErrMoveToOtherOrg)
+	assert.NotErrorIs(t, err, folder.ErrPatchRollback)
+	require.Len(t, res.Conflicts, 1)
+	assert.Equal(t, 4, res.Conflicts[0].Index)
+	// everything made before it was undone
+	assert.Equal(t, 0, res.Applied)
+	assert.ElementsMatch(t, before, allFolders(d))
+}
+
+func Test_folder_Apply_OtherOrg(t *testing.T) {
+	t.Parallel()
+	staging := []folder.Folder{
+		{Name: "alpha", OrgId: foldertest.OrgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: foldertest.OrgA, Paths: "alpha.bravo"},
+	}
+	promoted := []folder.Folder{
+		{Name: "alpha", OrgId: foldertest.OrgA, Paths: "alpha"},
+		{Name: "bravo", OrgId: foldertest.OrgA, Paths: "bravo"},
+		{Name: "charlie", OrgId: foldertest.OrgA, Paths: "bravo.charlie"},
+	}
+	patch, err := folder.NewPatch(staging, promoted)
+	require.NoError(t, err)
+
+	production := []folder.Folder{
+		{Name: "alpha", OrgId: foldertest.OrgEmpty, Paths: "alpha"},
+		{Name: "bravo", OrgId: foldertest.OrgEmpty, Paths: "alpha.bravo"},
+	}
+	d, err := folder.NewDriverWithStore(folder.NewMemoryStore(production))
+	require.NoError(t, err)
+	// the patch names the staging org
+	_, err = folder.Apply(d, patch, folder.ApplyOptions{})
+	assert.ErrorIs(t, err, folder.ErrPatchConflict)
+
+	res, err := folder.Apply(d, patch, folder.ApplyOptions{OrgID: foldertest.OrgEmpty})
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Applied)
+	assert.Equal(t, []string{"alpha", "bravo", "bravo.charlie"}, paths(d.GetFoldersByOrgID(foldertest.OrgEmpty)))
+}
+
+func Test_folder_Apply_PlainDriver(t *testing.T) {
+	t.Parallel()
+	a := foldertest.OrgA
+	// hides everything but IDriver, e.g. a wrapper like audit.Driver
+	d := struct{ folder.IDriver }{folder.NewDriver(foldertest.MoveData())}
+	moves := folder.Patch{{Op: folder.OpMove, OrgID: a, Name: "bravo", Path: "alpha.bravo", Parent: "golf", ParentPath: "golf"}}
+	res, err := folder.Apply(d, moves, folder.ApplyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Applied)
+	assert.ElementsMatch(t, []string{"alpha", "alpha.delta", "alpha.delta.echo", "golf", "golf.bravo", "golf.bravo.charlie"}, paths(d.GetFoldersByOrgID(a)))
+
+	// creates need an Editor and moves to the root a driver of this package
+	edits := folder.Patch{
+		{Op: folder.OpMove, OrgID: a, Name: "delta", Path: "alpha.delta", Parent: "golf", ParentPath: "golf"},
+		{Op: folder.OpCreate, OrgID: a, Name: "hotel", Parent: "golf", ParentPath: "golf"},
+		{Op: folder.OpMove, OrgID: a, Name: "bravo", Path: "golf.bravo"},
+	}
+	res, err = folder.Apply(d, edits, folder.ApplyOptions{})
+	assert.ErrorIs(t, err, folder.ErrPatchConflict)
+	require.Len(t, res.Conflicts, 2)
+	assert.ErrorIs(t, res.Conflicts[0], folder.ErrPatchNotSupported)
+	assert.Equal(t, 1, res.Conflicts[0].Index)
+	assert.ErrorIs(t, res.Conflicts[1], folder.ErrPatchNotSupported)
+	assert.Equal(t, 0, res.Applied)
+	assert.Contains(t, paths(d.GetFoldersByOrgID(a)), "alpha.delta")
+}
+
+func Test_folder_Apply_UnknownOp(t *testing.T) {
+	t.Parallel()
+	res, err := folder.Apply(folder.NewDriver(nil), folder.Patch{{Op: "copy", Name: "alpha"}}, folder.ApplyOptions{Mode: folder.BestEffort})
+	require.NoError(t, err)
+	require.Len(t, res.Conflicts, 1)
+	assert.ErrorIs(t, res.Conflicts[0], folder.ErrUnknownPatchOp)
+	assert.Equal(t, `operation 0 (copy alpha): Error: Unknown patch operation "copy"`, res.Conflicts[0].Error())
+}
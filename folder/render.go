@@ -0,0 +1,212 @@
+package folder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Renderers that turn folders into Graphviz DOT or Mermaid flowchart text,
+// mostly useful for debugging reorganisations and pasting into reviews.
+//
+//	dot, err := folder.RenderDOT(folders, folder.RenderOptions{ColorByOrg: true})
+//	// dot -Tsvg -o tree.svg
+
+// fill colours handed out to orgs in the order they are first seen
+var orgPalette = []string{
+	"#a6cee3", "#b2df8a", "#fdbf6f", "#cab2d6", "#fb9a99", "#ffff99", "#8dd3c7", "#d9d9d9",
+}
+
+// colour used for highlighted folders
+const highlightColor = "#e31a1c"
+
+type RenderOptions struct {
+	// only render folders of this org when set
+	OrgID uuid.UUID
+	// only render this folder and its descendants when set
+	Root string
+	// give each org its own fill colour
+	ColorByOrg bool
+	// names of folders to draw with a highlighted border, see HighlightMoved
+	Highlight map[string]bool
+}
+
+// HighlightMoved returns the names of folders whose path differs between
+// before and after, ready to be used as RenderOptions.Highlight.
+// MoveFolder rewrites paths in place so before must be a copy taken before the move.
+func HighlightMoved(before, after []Folder) map[string]bool {
+	old := make(map[string]string, len(before))
+	for _, folder := range before {
+		old[folder.Name] = folder.Paths
+	}
+
+	moved := map[string]bool{}
+	for _, folder := range after {
+		if path, ok := old[folder.Name]; !ok || path != folder.Paths {
+			moved[folder.Name] = true
+		}
+	}
+	return moved
+}
+
+// renderNode is a folder with the ids needed to draw it
+type renderNode struct {
+	id     string
+	parent string
+	folder Folder
+	color  string
+}
+
+// renderNodes filters folders by the options and links every folder to its parent
+func renderNodes(folders []Folder, opts RenderOptions) ([]renderNode, error) {
+	selected := folders
+	if opts.OrgID != uuid.Nil {
+		selected = []Folder{}
+		for _, folder := range folders {
+			if folder.OrgId == opts.OrgID {
+				selected = append(selected, folder)
+			}
+		}
+	}
+
+	if opts.Root != "" {
+		rootPath := ""
+		var rootOrg uuid.UUID
+		for _, folder := range selected {
+			if folder.Name == opts.Root {
+				rootPath, rootOrg = folder.Paths, folder.OrgId
+				break
+			}
+		}
+		if rootPath == "" {
+			return nil, ErrFolderNotFound
+		}
+
+		subtree := []Folder{}
+		for _, folder := range selected {
+			if folder.OrgId == rootOrg && (folder.Paths == rootPath || IsChildFolder(folder, rootPath)) {
+				subtree = append(subtree, folder)
+			}
+		}
+		selected = subtree
+	}
+
+	colors := map[uuid.UUID]string{}
+	ids := make(map[string]string, len(selected))
+	key := func(orgID uuid.UUID, path string) string { return orgID.String() + "/" + path }
+
+	nodes := make([]renderNode, 0, len(selected))
+	for i, folder := range selected {
+		id := fmt.Sprintf("n%d", i)
+		ids[key(folder.OrgId, folder.Paths)] = id
+
+		color := ""
+		if opts.ColorByOrg {
+			if _, ok := colors[folder.OrgId]; !ok {
+				colors[folder.OrgId] = orgPalette[len(colors)%len(orgPalette)]
+			}
+			color = colors[folder.OrgId]
+		}
+		nodes = append(nodes, renderNode{id: id, folder: folder, color: color})
+	}
+
+	for i := range nodes {
+		path := nodes[i].folder.Paths
+		if dot := strings.LastIndex(path, "."); dot >= 0 {
+			nodes[i].parent = ids[key(nodes[i].folder.OrgId, path[:dot])]
+		}
+	}
+
+	return nodes, nil
+}
+
+// RenderDOT renders folders as a Graphviz digraph, edges point from parent to child.
+func RenderDOT(folders []Folder, opts RenderOptions) (string, error) {
+	nodes, err := renderNodes(folders, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph folders {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=folder, fontname=\"Helvetica\"];\n")
+
+	for _, n := range nodes {
+		attrs := []string{fmt.Sprintf("label=%s", dotQuote(n.folder.Name)), fmt.Sprintf("tooltip=%s", dotQuote(n.folder.Paths))}
+		if n.color != "" {
+			attrs = append(attrs, "style=filled", fmt.Sprintf("fillcolor=%s", dotQuote(n.color)))
+		}
+		if opts.Highlight[n.folder.Name] {
+			attrs = append(attrs, fmt.Sprintf("color=%s", dotQuote(highlightColor)), "penwidth=3")
+		}
+		fmt.Fprintf(&b, "\t%s [%s];\n", n.id, strings.Join(attrs, ", "))
+	}
+	for _, n := range nodes {
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s -> %s;\n", n.parent, n.id)
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// RenderMermaid renders folders as a Mermaid flowchart, edges point from parent to child.
+func RenderMermaid(folders []Folder, opts RenderOptions) (string, error) {
+	nodes, err := renderNodes(folders, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for _, n := range nodes {
+		fmt.Fprintf(&b, "\t%s[%s]\n", n.id, mermaidQuote(n.folder.Name))
+	}
+	for _, n := range nodes {
+		if n.parent != "" {
+			fmt.Fprintf(&b, "\t%s --> %s\n", n.parent, n.id)
+		}
+	}
+
+	// mermaid styles nodes through classes, one per colour in the order they are first used
+	colors := []string{}
+	classes := map[string][]string{}
+	for _, n := range nodes {
+		if n.color == "" {
+			continue
+		}
+		if _, ok := classes[n.color]; !ok {
+			colors = append(colors, n.color)
+		}
+		classes[n.color] = append(classes[n.color], n.id)
+	}
+	for i, color := range colors {
+		fmt.Fprintf(&b, "\tclassDef org%d fill:%s\n", i, color)
+		fmt.Fprintf(&b, "\tclass %s org%d\n", strings.Join(classes[color], ","), i)
+	}
+
+	highlighted := []string{}
+	for _, n := range nodes {
+		if opts.Highlight[n.folder.Name] {
+			highlighted = append(highlighted, n.id)
+		}
+	}
+	if len(highlighted) > 0 {
+		fmt.Fprintf(&b, "\tclassDef highlight stroke:%s,stroke-width:3px\n", highlightColor)
+		fmt.Fprintf(&b, "\tclass %s highlight\n", strings.Join(highlighted, ","))
+	}
+
+	return b.String(), nil
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,125 @@
+package folder_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_RenderDOT(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name    string
+		opts    folder.RenderOptions
+		want    string
+		wantErr string
+	}{
+		{
+			name: "Subtree of one folder",
+			opts: folder.RenderOptions{Root: "bravo"},
+			want: `digraph folders {
+	rankdir=LR;
+	node [shape=folder, fontname="Helvetica"];
+	n0 [label="bravo", tooltip="alpha.bravo"];
+	n1 [label="charlie", tooltip="alpha.bravo.charlie"];
+	n0 -> n1;
+}
+`,
+		},
+		{
+			name: "Coloured by org",
+			opts: folder.RenderOptions{ColorByOrg: true, Root: "delta"},
+			want: `digraph folders {
+	rankdir=LR;
+	node [shape=folder, fontname="Helvetica"];
+	n0 [label="delta", tooltip="alpha.delta", style=filled, fillcolor="#a6cee3"];
+	n1 [label="echo", tooltip="alpha.delta.echo", style=filled, fillcolor="#a6cee3"];
+	n0 -> n1;
+}
+`,
+		},
+		{
+			name: "One org only",
+			opts: folder.RenderOptions{OrgID: uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")},
+			want: `digraph folders {
+	rankdir=LR;
+	node [shape=folder, fontname="Helvetica"];
+	n0 [label="foxtrot", tooltip="foxtrot"];
+}
+`,
+		},
+		{
+			name:    "Root does not exist",
+			opts:    folder.RenderOptions{Root: "invalid_folder"},
+			wantErr: "Error: Folder does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := folder.RenderDOT(GetTestingSampleData2(), tt.opts)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_folder_RenderMermaid_HighlightMove(t *testing.T) {
+	t.Parallel()
+	before := GetTestingSampleData2()
+	after, err := folder.NewDriver(GetTestingSampleData2()).MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+
+	highlight := folder.HighlightMoved(before, after)
+	assert.Equal(t, map[string]bool{"bravo": true, "charlie": true}, highlight)
+
+	got, err := folder.RenderMermaid(after, folder.RenderOptions{
+		OrgID:      uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7"),
+		ColorByOrg: true,
+		Highlight:  highlight,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `flowchart LR
+	n0["alpha"]
+	n1["bravo"]
+	n2["charlie"]
+	n3["delta"]
+	n4["echo"]
+	n5["golf"]
+	n5 --> n1
+	n1 --> n2
+	n0 --> n3
+	n3 --> n4
+	classDef org0 fill:#a6cee3
+	class n0,n1,n2,n3,n4,n5 org0
+	classDef highlight stroke:#e31a1c,stroke-width:3px
+	class n1,n2 highlight
+`, got)
+}
+
+func Test_folder_RenderDOT_PrefixSibling(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	folders := []folder.Folder{
+		{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+		{Name: "alphabet", OrgId: orgID, Paths: "alphabet"},
+		{Name: "soup", OrgId: orgID, Paths: "alphabet.soup"},
+	}
+	got, err := folder.RenderDOT(folders, folder.RenderOptions{Root: "alpha"})
+	require.NoError(t, err)
+	assert.Equal(t, `digraph folders {
+	rankdir=LR;
+	node [shape=folder, fontname="Helvetica"];
+	n0 [label="alpha", tooltip="alpha"];
+	n1 [label="bravo", tooltip="alpha.bravo"];
+	n0 -> n1;
+}
+`, got)
+}
@@ -0,0 +1,295 @@
+package folder
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/lucasepe/codename"
+)
+
+// Generator for production sized data sets, tens of millions of folders.
+//
+// Every root tree gets its own rng seeded from the config seed and the root's
+// index, so trees are generated on all cores at once and still come out the
+// same on every run. Finished trees are written in root order as soon as they
+// are ready, nothing but a small window of trees is ever held in memory.
+
+// ScaleConfig is a GeneratorConfig plus the knobs that only matter at scale.
+type ScaleConfig struct {
+	GeneratorConfig
+	// stop growing a root's tree once it has this many folders, 0 means no limit
+	MaxFoldersPerRoot int
+	// number of trees generated in parallel, defaults to GOMAXPROCS
+	Workers int
+	// output format, NDJSON is the better choice for very large sets
+	Format Format
+}
+
+// ScaleStats describes what GenerateStream wrote.
+type ScaleStats struct {
+	Folders int64
+	Bytes   int64
+}
+
+// Zipf is a skewed Distribution: small numbers are very common and large ones
+// rare, P(k) is proportional to 1/(k+1)^s for k from 0 to max.
+// It matches real folder trees far better than Uniform, most folders have no
+// or few children while a handful have hundreds.
+type Zipf struct {
+	// cumulative probabilities of 0..max
+	cdf []float64
+}
+
+// NewZipf returns a Zipf distribution over 0..max with exponent s, s > 1 gives a heavier skew.
+func NewZipf(s float64, max int) *Zipf {
+	if max < 0 {
+		max = 0
+	}
+	cdf := make([]float64, max+1)
+	total := 0.0
+	for k := range cdf {
+		total += 1 / math.Pow(float64(k+1), s)
+		cdf[k] = total
+	}
+	for k := range cdf {
+		cdf[k] /= total
+	}
+	return &Zipf{cdf: cdf}
+}
+
+func (z *Zipf) Sample(rng *rand.Rand) int {
+	u := rng.Float64()
+	return sort.SearchFloat64s(z.cdf, u)
+}
+
+// treeSeed derives an independent seed for a root from the config seed (splitmix64)
+func treeSeed(seed int64, root int) int64 {
+	z := uint64(seed) + uint64(root+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
+// scaleChunk is one encoded root tree
+type scaleChunk struct {
+	data    []byte
+	folders int64
+}
+
+// GenerateStream generates folders as described by cfg and writes them to w.
+// The output only depends on cfg, not on Workers or scheduling.
+func GenerateStream(w io.Writer, cfg ScaleConfig) (ScaleStats, error) {
+	if cfg.Depth == nil {
+		cfg.Depth = Fixed(MaxDepth)
+	}
+	if cfg.FanOut == nil {
+		cfg.FanOut = Uniform{Min: 1, Max: MaxChild}
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	g := &generator{cfg: cfg.GeneratorConfig, rng: rand.New(rand.NewSource(cfg.Seed))}
+	orgs := g.orgIDs()
+	roots := len(orgs) * cfg.RootsPerOrg
+
+	type job struct {
+		root int
+		out  chan scaleChunk
+	}
+	jobs := make(chan job)
+	// results are collected in root order through a bounded queue of per-root channels
+	pending := make(chan chan scaleChunk, workers*4)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.out <- generateScaleTree(cfg, orgs[j.root/cfg.RootsPerOrg], j.root)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for root := 0; root < roots; root++ {
+			out := make(chan scaleChunk, 1)
+			select {
+			case pending <- out:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job{root: root, out: out}:
+			case <-done:
+				// nobody will fill this one, closing it unblocks the drain below
+				close(out)
+				return
+			}
+		}
+	}()
+
+	stats, err := writeScaleChunks(w, cfg.Format, pending)
+	close(done)
+	// drain so the dispatcher and the workers can exit
+	for out := range pending {
+		<-out
+	}
+	wg.Wait()
+	return stats, err
+}
+
+func writeScaleChunks(w io.Writer, format Format, pending <-chan chan scaleChunk) (ScaleStats, error) {
+	bw := bufio.NewWriterSize(w, 1<<20)
+	stats := ScaleStats{}
+	write := func(b []byte) error {
+		n, err := bw.Write(b)
+		stats.Bytes += int64(n)
+		return err
+	}
+
+	if format == FormatJSON {
+		if err := write([]byte("[")); err != nil {
+			return stats, err
+		}
+	}
+	for out := range pending {
+		chunk := <-out
+		if chunk.folders == 0 {
+			continue
+		}
+		if format == FormatJSON {
+			sep := ",\n\t"
+			if stats.Folders == 0 {
+				sep = "\n\t"
+			}
+			if err := write([]byte(sep)); err != nil {
+				return stats, err
+			}
+		}
+		if err := write(chunk.data); err != nil {
+			return stats, err
+		}
+		stats.Folders += chunk.folders
+	}
+	if format == FormatJSON {
+		end := "\n]\n"
+		if stats.Folders == 0 {
+			end = "]\n"
+		}
+		if err := write([]byte(end)); err != nil {
+			return stats, err
+		}
+	}
+	return stats, bw.Flush()
+}
+
+// generateScaleTree builds and encodes one root tree, depth first like generateTree
+func generateScaleTree(cfg ScaleConfig, orgID uuid.UUID, root int) scaleChunk {
+	rng := rand.New(rand.NewSource(treeSeed(cfg.Seed, root)))
+	org := orgID.String()
+	sep := "\n"
+	if cfg.Format == FormatJSON {
+		sep = ",\n\t"
+	}
+
+	chunk := scaleChunk{}
+	local := 0
+	// names get the root and a per tree counter so they are unique without any shared state
+	name := func() string {
+		n := codename.Generate(rng, 0)
+		if cfg.UniqueNames {
+			n += "-" + strconv.FormatInt(int64(root), 36) + "-" + strconv.FormatInt(int64(local), 36)
+		}
+		local++
+		return n
+	}
+	emit := func(name, path string) {
+		if chunk.folders > 0 {
+			chunk.data = append(chunk.data, sep...)
+		}
+		chunk.data = appendFolderJSON(chunk.data, name, org, path)
+		chunk.folders++
+	}
+	full := func() bool {
+		return cfg.MaxFoldersPerRoot > 0 && chunk.folders >= int64(cfg.MaxFoldersPerRoot)
+	}
+
+	maxDepth := cfg.Depth.Sample(rng)
+	rootName := name()
+	emit(rootName, rootName)
+
+	// explicit stack instead of recursion, trees can be very deep
+	type frame struct {
+		path  string
+		depth int
+		left  int
+	}
+	stack := []frame{{path: rootName, depth: 1, left: childCount(cfg, rng, 1, maxDepth)}}
+	for len(stack) > 0 && !full() {
+		top := &stack[len(stack)-1]
+		if top.left == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		top.left--
+
+		n := name()
+		path := top.path + "." + n
+		emit(n, path)
+		stack = append(stack, frame{path: path, depth: top.depth + 1, left: childCount(cfg, rng, top.depth+1, maxDepth)})
+	}
+
+	if cfg.Format == FormatNDJSON && chunk.folders > 0 {
+		chunk.data = append(chunk.data, '\n')
+	}
+	return chunk
+}
+
+func childCount(cfg ScaleConfig, rng *rand.Rand, depth int, maxDepth int) int {
+	if depth >= maxDepth {
+		return 0
+	}
+	return cfg.FanOut.Sample(rng)
+}
+
+// appendFolderJSON encodes a folder like encoding/json does for the ASCII names
+// the generator makes, without the cost of reflection
+func appendFolderJSON(b []byte, name, org, path string) []byte {
+	b = append(b, `{"name":`...)
+	b = appendJSONString(b, name)
+	b = append(b, `,"org_id":"`...)
+	b = append(b, org...)
+	b = append(b, `","paths":`...)
+	b = appendJSONString(b, path)
+	return append(b, '}')
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20 || c == '<' || c == '>' || c == '&':
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
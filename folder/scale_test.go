@@ -0,0 +1,125 @@
+package folder_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scaleConfig() folder.ScaleConfig {
+	return folder.ScaleConfig{
+		GeneratorConfig: folder.GeneratorConfig{
+			Seed:        99,
+			Orgs:        3,
+			RootsPerOrg: 20,
+			Depth:       folder.Uniform{Min: 2, Max: 8},
+			FanOut:      folder.NewZipf(1.2, 30),
+			UniqueNames: true,
+		},
+		MaxFoldersPerRoot: 500,
+	}
+}
+
+func Test_folder_GenerateStream_Deterministic(t *testing.T) {
+	t.Parallel()
+	for _, format := range []folder.Format{folder.FormatJSON, folder.FormatNDJSON} {
+		var outputs []string
+		for _, workers := range []int{1, 3, 16} {
+			cfg := scaleConfig()
+			cfg.Workers = workers
+			cfg.Format = format
+
+			var buf bytes.Buffer
+			stats, err := folder.GenerateStream(&buf, cfg)
+			require.NoError(t, err)
+			assert.Equal(t, int64(buf.Len()), stats.Bytes)
+			outputs = append(outputs, buf.String())
+		}
+		assert.Equal(t, outputs[0], outputs[1], "output must not depend on the number of workers")
+		assert.Equal(t, outputs[0], outputs[2], "output must not depend on the number of workers")
+	}
+}
+
+func Test_folder_GenerateStream_Valid(t *testing.T) {
+	t.Parallel()
+	for _, format := range []folder.Format{folder.FormatJSON, folder.FormatNDJSON} {
+		cfg := scaleConfig()
+		cfg.Format = format
+
+		var buf bytes.Buffer
+		stats, err := folder.GenerateStream(&buf, cfg)
+		require.NoError(t, err)
+
+		folders, err := folder.LoadFolders(&buf)
+		require.NoError(t, err)
+		require.Equal(t, stats.Folders, int64(len(folders)))
+		require.NoError(t, checkTree(folders))
+
+		seen := map[string]bool{}
+		perRoot := map[string]int{}
+		for _, f := range folders {
+			require.False(t, seen[f.Name], "duplicate name %s", f.Name)
+			seen[f.Name] = true
+			root, _, _ := strings.Cut(f.Paths, ".")
+			perRoot[root]++
+		}
+		assert.Len(t, perRoot, 60)
+		for _, n := range perRoot {
+			assert.LessOrEqual(t, n, 500)
+		}
+	}
+}
+
+func Test_folder_GenerateStream_Empty(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	stats, err := folder.GenerateStream(&buf, folder.ScaleConfig{Format: folder.FormatJSON})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Folders)
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n <= 0 {
+		return 0, errors.New("disk full")
+	}
+	w.n--
+	return len(p), nil
+}
+
+func Test_folder_GenerateStream_WriteError(t *testing.T) {
+	t.Parallel()
+	cfg := scaleConfig()
+	cfg.RootsPerOrg = 500
+	_, err := folder.GenerateStream(&failingWriter{n: 1}, cfg)
+	assert.EqualError(t, err, "disk full")
+}
+
+func Test_folder_Zipf(t *testing.T) {
+	t.Parallel()
+	z := folder.NewZipf(1.5, 10)
+	rng := rand.New(rand.NewSource(1))
+	counts := make([]int, 11)
+	for i := 0; i < 10000; i++ {
+		k := z.Sample(rng)
+		require.GreaterOrEqual(t, k, 0)
+		require.LessOrEqual(t, k, 10)
+		counts[k]++
+	}
+	// heavily skewed to small numbers
+	assert.Greater(t, counts[0], counts[1])
+	assert.Greater(t, counts[1], counts[5])
+	assert.Greater(t, counts[0], 10000/3)
+
+	assert.Equal(t, 0, folder.NewZipf(2, 0).Sample(rng))
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+)
+
+// Changes are streamed as Server-Sent Events, one per folder.Event:
+//
+//	id: 7
+//	event: folder.moved
+//	data: {"type":"folder.moved","org_id":"...","seq":7,...}
+//
+// The id is the Seq of the event within the org, so a client that reconnects
+// with Last-Event-ID gets the events it missed from the driver's history.
+// When they are no longer there it gets a reset event and should reload the
+// folders of the org. The stream ends when the client falls behind, clients
+// reconnect and resume from the last id like after any other disconnect.
+
+// resetEvent tells the client some events are lost and it has to reload
+const resetEvent = "reset"
+
+// DefaultKeepAlive is how often an idle stream gets a comment so proxies keep it open.
+const DefaultKeepAlive = 15 * time.Second
+
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	orgID, err := pathOrgID(r)
+	if err != nil {
+		return err
+	}
+	notifier, ok := s.driver.(folder.Notifier)
+	if !ok {
+		return &requestError{kind: problemNotImplemented, msg: "Error: the driver does not publish change events"}
+	}
+
+	// the validator checked both are integers
+	after, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	sub := notifier.Subscribe(folder.SubscribeOptions{
+		OrgID:   orgID,
+		Subtree: r.URL.Query().Get("subtree"),
+		After:   after,
+	})
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if sub.Missed {
+		fmt.Fprintf(w, "event: %s\ndata: {\"reason\":\"events after %d are no longer available\"}\n\n", resetEvent, after)
+	} else {
+		// an empty comment, so the client knows the stream is up
+		fmt.Fprint(w, ": ok\n\n")
+	}
+	if err := rc.Flush(); err != nil {
+		return nil
+	}
+
+	keepAlive := time.NewTicker(s.opts.KeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				// fell behind, the client reconnects with Last-Event-ID
+				return nil
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		}
+		if err := rc.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+// Close ends the event streams, call it before shutting down the http.Server
+// as Shutdown waits for them otherwise.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
@@ -0,0 +1,145 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stream opens the event stream of the org, lastID is sent as Last-Event-ID when set
+func stream(t *testing.T, ts *httptest.Server, path, lastID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, err := http.NewRequest("GET", ts.URL+path, nil)
+	require.NoError(t, err)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res, bufio.NewReader(res.Body)
+}
+
+// message reads the next message of a stream, comments have their text under ""
+func message(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	msg := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return msg
+		}
+		field, value, _ := strings.Cut(line, ":")
+		msg[field] = strings.TrimPrefix(value, " ")
+	}
+}
+
+func move(t *testing.T, ts *httptest.Server, name, dst string) {
+	t.Helper()
+	res := do(t, ts, "POST", "/orgs/"+orgA+"/folders/"+name+"/move", `{"destination": "`+dst+`"}`, nil)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func Test_server_EventStream(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	res, r := stream(t, ts, "/orgs/"+orgA+"/events", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+	assert.Equal(t, map[string]string{"": "ok"}, message(t, r))
+
+	move(t, ts, "bravo", "delta")
+	msg := message(t, r)
+	assert.Equal(t, "1", msg["id"])
+	assert.Equal(t, "folder.moved", msg["event"])
+
+	var e folder.Event
+	require.NoError(t, json.Unmarshal([]byte(msg["data"]), &e))
+	assert.Equal(t, uint64(1), e.Seq)
+	assert.Equal(t, []folder.Change{
+		{Name: "bravo", OldPath: "alpha.bravo", NewPath: "alpha.delta.bravo"},
+		{Name: "charlie", OldPath: "alpha.bravo.charlie", NewPath: "alpha.delta.bravo.charlie"},
+	}, e.Changes)
+}
+
+func Test_server_EventStreamResume(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+	move(t, ts, "bravo", "delta")
+	move(t, ts, "golf", "alpha")
+	move(t, ts, "golf", "echo")
+
+	// picks up after the last event the client saw
+	_, r := stream(t, ts, "/orgs/"+orgA+"/events", "1")
+	assert.Equal(t, map[string]string{"": "ok"}, message(t, r))
+	assert.Equal(t, "2", message(t, r)["id"])
+	assert.Equal(t, "3", message(t, r)["id"])
+
+	// an id the server has no history for
+	_, r = stream(t, ts, "/orgs/"+orgA+"/events", "42")
+	assert.Equal(t, "reset", message(t, r)["event"])
+
+	// only the events of the subtree
+	_, r = stream(t, ts, "/orgs/"+orgA+"/events?subtree=alpha.delta.echo", "1")
+	message(t, r)
+	msg := message(t, r)
+	assert.Equal(t, "3", msg["id"])
+	assert.Contains(t, msg["data"], `"new_path":"alpha.delta.echo.golf"`)
+}
+
+func Test_server_EventStreamKeepAlive(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{KeepAlive: 10 * time.Millisecond})
+
+	_, r := stream(t, ts, "/orgs/"+orgA+"/events", "")
+	message(t, r)
+	assert.Equal(t, map[string]string{"": "keep-alive"}, message(t, r))
+}
+
+func Test_server_EventStreamClose(t *testing.T) {
+	t.Parallel()
+	srv := server.New(folder.NewDriver(foldertest.MoveData()), server.Options{})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	_, r := stream(t, ts, "/orgs/"+orgA+"/events", "")
+	message(t, r)
+	srv.Close()
+	_, err := io.ReadAll(r)
+	assert.NoError(t, err)
+}
+
+func Test_server_EventStreamProblems(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	var problem server.Problem
+	res := do(t, ts, "GET", "/orgs/"+orgA+"/events?subtree=a..b", "", &problem)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "subtree", problem.Errors[0].Name)
+
+	res, _ = stream(t, ts, "/orgs/"+orgA+"/events", "soon")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// drivers that don't implement folder.Notifier have no events
+	plain := httptest.NewServer(server.New(struct{ folder.IDriver }{folder.NewDriver(nil)}, server.Options{}))
+	t.Cleanup(plain.Close)
+	res = do(t, plain, "GET", "/orgs/"+orgA+"/events", "", &problem)
+	assert.Equal(t, http.StatusNotImplemented, res.StatusCode)
+	assert.Equal(t, "/problems/not-implemented", problem.Type)
+}
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+// GraphQLSDL is the schema served at POST /graphql in the GraphQL schema
+// language, the tests check it against GraphQLSchema.
+const GraphQLSDL = `type Query {
+  org(id: ID!): Org!
+}
+
+type Mutation {
+  # moves a folder below destination and returns it and its descendants
+  moveFolder(org: ID!, name: String!, destination: String!): [Folder!]!
+}
+
+type Org {
+  id: ID!
+  # folders without a parent, ordered by path
+  roots: [Folder!]!
+  folder(name: String!): Folder
+  # folders whose path matches an lquery pattern, ordered by path
+  search(lquery: String!): [Folder!]!
+}
+
+type Folder {
+  name: String!
+  path: String!
+  org: Org!
+  parent: Folder
+  # descendants at most depth levels below, ordered by path
+  children(depth: Int = 1): [Folder!]!
+  # the folders above, the root first
+  ancestors: [Folder!]!
+}
+`
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// orgRef is the source of Org fields
+type orgRef struct {
+	id uuid.UUID
+}
+
+type loaderKey struct{}
+
+// orgLoader returns the loader of the request, see loader
+func orgLoader(ctx context.Context) *loader[uuid.UUID, *orgIndex] {
+	return ctx.Value(loaderKey{}).(*loader[uuid.UUID, *orgIndex])
+}
+
+// withOrg resolves a field from the index of orgID. The index is loaded
+// lazily so every field of a level that needs the same org shares one load.
+func withOrg(p graphql.ResolveParams, orgID uuid.UUID, resolve func(idx *orgIndex) (any, error)) (any, error) {
+	thunk := orgLoader(p.Context).load(orgID)
+	return func() (any, error) {
+		idx, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		return resolve(idx)
+	}, nil
+}
+
+// graphQLError is an error of a resolver, the problem type and status it
+// would have over REST are added to the error's extensions
+type graphQLError struct {
+	err error
+}
+
+func (e graphQLError) Error() string {
+	_, detail := classify(e.err)
+	return detail
+}
+
+func (e graphQLError) Extensions() map[string]any {
+	kind, _ := classify(e.err)
+	return map[string]any{"type": kind.typ, "status": kind.status}
+}
+
+func argumentError(name, reason string) error {
+	return graphQLError{&validationError{errors: []FieldError{{In: "argument", Name: name, Reason: reason}}}}
+}
+
+func parseOrgID(p graphql.ResolveParams, arg string) (uuid.UUID, error) {
+	orgID := uuid.FromStringOrNil(p.Args[arg].(string))
+	if orgID == uuid.Nil {
+		return uuid.Nil, argumentError(arg, "must be a uuid")
+	}
+	return orgID, nil
+}
+
+// GraphQLSchema returns the executable schema of POST /graphql.
+func (s *Server) GraphQLSchema() graphql.Schema {
+	return s.schema
+}
+
+func (s *Server) buildGraphQLSchema() graphql.Schema {
+	var folderType *graphql.Object
+	orgType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Org",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": {
+					Type: graphql.NewNonNull(graphql.ID),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source.(orgRef).id.String(), nil
+					},
+				},
+				"roots": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(folderType))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return withOrg(p, p.Source.(orgRef).id, func(idx *orgIndex) (any, error) {
+							return idx.children[""], nil
+						})
+					},
+				},
+				"folder": {
+					Type: folderType,
+					Args: graphql.FieldConfigArgument{
+						"name": {Type: graphql.NewNonNull(graphql.String)},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return withOrg(p, p.Source.(orgRef).id, func(idx *orgIndex) (any, error) {
+							if f, ok := idx.byName[p.Args["name"].(string)]; ok {
+								return f, nil
+							}
+							return nil, nil
+						})
+					},
+				},
+				"search": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(folderType))),
+					Args: graphql.FieldConfigArgument{
+						"lquery": {Type: graphql.NewNonNull(graphql.String)},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						q, err := folder.ParseLQuery(p.Args["lquery"].(string))
+						if err != nil {
+							return nil, argumentError("lquery", err.Error())
+						}
+						return withOrg(p, p.Source.(orgRef).id, func(idx *orgIndex) (any, error) {
+							return q.Filter(idx.folders), nil
+						})
+					},
+				},
+			}
+		}),
+	})
+
+	folderType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Folder",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"name": {
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source.(folder.Folder).Name, nil
+					},
+				},
+				"path": {
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return p.Source.(folder.Folder).Paths, nil
+					},
+				},
+				"org": {
+					Type: graphql.NewNonNull(orgType),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return orgRef{id: p.Source.(folder.Folder).OrgId}, nil
+					},
+				},
+				"parent": {
+					Type: folderType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						f := p.Source.(folder.Folder)
+						return withOrg(p, f.OrgId, func(idx *orgIndex) (any, error) {
+							if parent, ok := idx.byPath[parentPath(f.Paths)]; ok {
+								return parent, nil
+							}
+							return nil, nil
+						})
+					},
+				},
+				"children": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(folderType))),
+					Args: graphql.FieldConfigArgument{
+						"depth": {Type: graphql.Int, DefaultValue: 1},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						f := p.Source.(folder.Folder)
+						depth, _ := p.Args["depth"].(int)
+						if depth < 1 {
+							return nil, argumentError("depth", "must be at least 1")
+						}
+						return withOrg(p, f.OrgId, func(idx *orgIndex) (any, error) {
+							return idx.descendants(f.Paths, depth), nil
+						})
+					},
+				},
+				"ancestors": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(folderType))),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						f := p.Source.(folder.Folder)
+						return withOrg(p, f.OrgId, func(idx *orgIndex) (any, error) {
+							return idx.ancestors(f.Paths), nil
+						})
+					},
+				},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"org": {
+				Type: graphql.NewNonNull(orgType),
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					orgID, err := parseOrgID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return orgRef{id: orgID}, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"moveFolder": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(folderType))),
+				Args: graphql.FieldConfigArgument{
+					"org":         {Type: graphql.NewNonNull(graphql.ID)},
+					"name":        {Type: graphql.NewNonNull(graphql.String)},
+					"destination": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					orgID, err := parseOrgID(p, "org")
+					if err != nil {
+						return nil, err
+					}
+					moved, err := s.move(orgID, p.Args["name"].(string), p.Args["destination"].(string))
+					if err != nil {
+						return nil, s.resolverError(err)
+					}
+					// everything loaded before the move is stale
+					orgLoader(p.Context).clear()
+					return moved, nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// resolverError wraps err for the errors of a GraphQL response and logs the
+// errors the client doesn't get to see
+func (s *Server) resolverError(err error) error {
+	if kind, _ := classify(err); kind == problemInternal {
+		s.opts.ErrorLog.Printf("folder server: graphql: %v", err)
+	}
+	return graphQLError{err}
+}
+
+func (s *Server) graphQL(w http.ResponseWriter, r *http.Request) error {
+	// the validator checked the body against the GraphQLRequest schema
+	var req GraphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return badRequest("Error: invalid JSON body: " + err.Error())
+	}
+
+	ctx := context.WithValue(r.Context(), loaderKey{}, s.newOrgLoader())
+	res := graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+	// GraphQL errors are part of the response, the status stays 200
+	writeJSON(w, http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,250 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/server"
+	"github.com/gofrs/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type graphQLResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// query runs a GraphQL request and returns the result
+func query(t *testing.T, ts *httptest.Server, q string, vars map[string]any) graphQLResult {
+	t.Helper()
+	body, err := json.Marshal(server.GraphQLRequest{Query: q, Variables: vars})
+	require.NoError(t, err)
+	var res graphQLResult
+	require.Equal(t, http.StatusOK, do(t, ts, "POST", "/graphql", string(body), &res).StatusCode)
+	return res
+}
+
+// countingDriver counts the calls that scan an org
+type countingDriver struct {
+	folder.IDriver
+	byOrg atomic.Int32
+}
+
+func (d *countingDriver) GetFoldersByOrgID(orgID uuid.UUID) []folder.Folder {
+	d.byOrg.Add(1)
+	return d.IDriver.GetFoldersByOrgID(orgID)
+}
+
+func (d *countingDriver) GetAllChildFolders(orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	panic("the GraphQL resolvers must use the loader")
+}
+
+func Test_server_GraphQLTree(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	res := query(t, ts, `query($org: ID!) {
+		org(id: $org) {
+			roots { name children { name children(depth: 2) { path } } }
+			folder(name: "echo") { parent { name } ancestors { name } org { id } }
+			missing: folder(name: "nope") { name }
+			search(lquery: "*.delta.*") { name }
+		}
+	}`, map[string]any{"org": orgA})
+	assert.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"org": {
+		"roots": [
+			{"name": "alpha", "children": [
+				{"name": "bravo", "children": [{"path": "alpha.bravo.charlie"}]},
+				{"name": "delta", "children": [{"path": "alpha.delta.echo"}]}
+			]},
+			{"name": "golf", "children": []}
+		],
+		"folder": {"parent": {"name": "delta"}, "ancestors": [{"name": "alpha"}, {"name": "delta"}], "org": {"id": "`+orgA+`"}},
+		"missing": null,
+		"search": [{"name": "delta"}, {"name": "echo"}]
+	}}`, string(res.Data))
+}
+
+func Test_server_GraphQLBatching(t *testing.T) {
+	t.Parallel()
+	driver := &countingDriver{IDriver: folder.NewDriver(foldertest.MoveData())}
+	ts := httptest.NewServer(server.New(driver, server.Options{}))
+	t.Cleanup(ts.Close)
+
+	// every level needs the org, they all share one load per org
+	res := query(t, ts, `{
+		a: org(id: "`+orgA+`") { roots { name parent { name } children { name parent { name } children { ancestors { name } } } } }
+		b: org(id: "`+orgB+`") { roots { name children { name } } }
+	}`, nil)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, int32(2), driver.byOrg.Load())
+}
+
+func Test_server_GraphQLMoveFolder(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	res := query(t, ts, `mutation {
+		moveFolder(org: "`+orgA+`", name: "bravo", destination: "delta") { path parent { name } }
+	}`, nil)
+	assert.Empty(t, res.Errors)
+	assert.JSONEq(t, `{"moveFolder": [
+		{"path": "alpha.delta.bravo", "parent": {"name": "delta"}},
+		{"path": "alpha.delta.bravo.charlie", "parent": {"name": "bravo"}}
+	]}`, string(res.Data))
+
+	res = query(t, ts, `{ org(id: "`+orgA+`") { folder(name: "delta") { children { name } } } }`, nil)
+	assert.JSONEq(t, `{"org": {"folder": {"children": [{"name": "bravo"}, {"name": "echo"}]}}}`, string(res.Data))
+}
+
+func Test_server_GraphQLTwoMutations(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	// the fields of the first move resolve after the second one cleared the loader
+	res := query(t, ts, `mutation {
+		first: moveFolder(org: "`+orgA+`", name: "bravo", destination: "delta") { path parent { name } }
+		second: moveFolder(org: "`+orgA+`", name: "echo", destination: "golf") { path parent { name } }
+	}`, nil)
+	assert.Empty(t, res.Errors)
+	assert.JSONEq(t, `{
+		"first": [
+			{"path": "alpha.delta.bravo", "parent": {"name": "delta"}},
+			{"path": "alpha.delta.bravo.charlie", "parent": {"name": "bravo"}}
+		],
+		"second": [{"path": "golf.echo", "parent": {"name": "golf"}}]
+	}`, string(res.Data))
+}
+
+func Test_server_GraphQLErrors(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name   string
+		query  string
+		msg    string
+		typ    string
+		status float64
+	}{
+		{
+			name:  "bad org id",
+			query: `{ org(id: "nope") { id } }`,
+			msg:   "Error: invalid request: id must be a uuid", typ: "/problems/validation", status: 400,
+		},
+		{
+			name:  "bad depth",
+			query: `{ org(id: "` + orgA + `") { roots { children(depth: 0) { name } } } }`,
+			msg:   "Error: invalid request: depth must be at least 1", typ: "/problems/validation", status: 400,
+		},
+		{
+			name:  "bad lquery",
+			query: `{ org(id: "` + orgA + `") { search(lquery: "") { name } } }`,
+			msg:   "Error: invalid request: lquery " + folder.ErrInvalidLQuery.Error() + ": empty pattern", typ: "/problems/validation", status: 400,
+		},
+		{
+			name:  "move unknown source",
+			query: `mutation { moveFolder(org: "` + orgA + `", name: "nope", destination: "golf") { name } }`,
+			msg:   folder.ErrSourceNotFound.Error(), typ: "/problems/not-found", status: 404,
+		},
+		{
+			name:  "move to descendant",
+			query: `mutation { moveFolder(org: "` + orgA + `", name: "bravo", destination: "charlie") { name } }`,
+			msg:   folder.ErrMoveToDescendant.Error(), typ: "/problems/move-conflict", status: 409,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+			res := query(t, ts, tt.query, nil)
+			require.Len(t, res.Errors, 1)
+			assert.Equal(t, tt.msg, res.Errors[0].Message)
+			assert.Equal(t, map[string]any{"type": tt.typ, "status": tt.status}, res.Errors[0].Extensions)
+		})
+	}
+
+	// a query that doesn't parse is an error of the response, not of the request
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+	res := query(t, ts, `{ org(`, nil)
+	require.Len(t, res.Errors, 1)
+	assert.Contains(t, res.Errors[0].Message, "Syntax Error")
+
+	// an empty query is rejected by the OpenAPI validation
+	var problem server.Problem
+	assert.Equal(t, http.StatusBadRequest, do(t, ts, "POST", "/graphql", `{"query": ""}`, &problem).StatusCode)
+	assert.Equal(t, "/problems/validation", problem.Type)
+}
+
+// sdlFields returns "field(arg: Type = default): Type" for every field of every type of the SDL
+func sdlFields(t *testing.T, sdl string) map[string][]string {
+	doc, err := parser.Parse(parser.ParseParams{Source: sdl})
+	require.NoError(t, err)
+	res := map[string][]string{}
+	for _, def := range doc.Definitions {
+		obj := def.(*ast.ObjectDefinition)
+		for _, field := range obj.Fields {
+			args := []string{}
+			for _, arg := range field.Arguments {
+				s := arg.Name.Value + ": " + fmt.Sprint(printer.Print(arg.Type))
+				if arg.DefaultValue != nil {
+					s += " = " + fmt.Sprint(printer.Print(arg.DefaultValue))
+				}
+				args = append(args, s)
+			}
+			res[obj.Name.Value] = append(res[obj.Name.Value], signature(field.Name.Value, args, fmt.Sprint(printer.Print(field.Type))))
+		}
+		sort.Strings(res[obj.Name.Value])
+	}
+	return res
+}
+
+func signature(name string, args []string, typ string) string {
+	if len(args) == 0 {
+		return name + ": " + typ
+	}
+	sort.Strings(args)
+	return name + "(" + strings.Join(args, ", ") + "): " + typ
+}
+
+func Test_server_GraphQLSDLMatchesSchema(t *testing.T) {
+	t.Parallel()
+	schema := server.New(folder.NewDriver(nil), server.Options{}).GraphQLSchema()
+
+	want := sdlFields(t, server.GraphQLSDL)
+	assert.Len(t, want, 4)
+	got := map[string][]string{}
+	for name := range want {
+		obj, ok := schema.Type(name).(*graphql.Object)
+		require.True(t, ok, name)
+		for _, field := range obj.Fields() {
+			args := []string{}
+			for _, arg := range field.Args {
+				s := arg.Name() + ": " + arg.Type.String()
+				if arg.DefaultValue != nil {
+					s += " = " + fmt.Sprint(arg.DefaultValue)
+				}
+				args = append(args, s)
+			}
+			got[name] = append(got[name], signature(field.Name, args, field.Type.String()))
+		}
+		sort.Strings(got[name])
+	}
+	assert.Equal(t, want, got)
+}
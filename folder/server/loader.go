@@ -0,0 +1,157 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// loader batches and caches the loads of one GraphQL request, like a
+// dataloader: load queues a key and returns a thunk, the first thunk that runs
+// loads every queued key with a single call to batch. graphql-go runs the
+// thunks of a query breadth first, so the keys of a whole level of the result
+// end up in one batch, and later levels are served from the cache.
+type loader[K comparable, V any] struct {
+	batch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	cache   map[K]*loaded[V]
+}
+
+type loaded[V any] struct {
+	value V
+	err   error
+	done  bool
+}
+
+func newLoader[K comparable, V any](batch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{batch: batch, cache: map[K]*loaded[V]{}}
+}
+
+func (l *loader[K, V]) load(key K) func() (V, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &loaded[V]{}
+		l.cache[key] = res
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !res.done {
+			l.dispatch()
+		}
+		return res.value, res.err
+	}
+}
+
+// dispatch loads every pending key, the caller holds l.mu
+func (l *loader[K, V]) dispatch() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.batch(keys)
+	for _, key := range keys {
+		res := l.cache[key]
+		res.value, res.err, res.done = values[key], err, true
+	}
+}
+
+// clear drops the loaded values, after a mutation they are stale. Queued keys
+// stay, the thunks of an earlier mutation's fields still wait on them and they
+// load the state after this mutation.
+func (l *loader[K, V]) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, res := range l.cache {
+		if res.done {
+			delete(l.cache, key)
+		}
+	}
+}
+
+// orgIndex is a snapshot of the folders of an org, indexed so that resolving
+// a folder's parent or children doesn't scan the whole org
+type orgIndex struct {
+	// ordered by path
+	folders []folder.Folder
+	byName  map[string]folder.Folder
+	byPath  map[string]folder.Folder
+	// the children of each path, ordered by path, "" holds the roots
+	children map[string][]folder.Folder
+}
+
+func newOrgIndex(folders []folder.Folder) *orgIndex {
+	idx := &orgIndex{
+		folders:  append([]folder.Folder{}, folders...),
+		byName:   map[string]folder.Folder{},
+		byPath:   map[string]folder.Folder{},
+		children: map[string][]folder.Folder{},
+	}
+	sort.Slice(idx.folders, func(i, j int) bool { return idx.folders[i].Paths < idx.folders[j].Paths })
+	for _, f := range idx.folders {
+		idx.byName[f.Name] = f
+		idx.byPath[f.Paths] = f
+		parent := parentPath(f.Paths)
+		idx.children[parent] = append(idx.children[parent], f)
+	}
+	return idx
+}
+
+// descendants returns the folders at most depth levels below path, ordered by path
+func (idx *orgIndex) descendants(path string, depth int) []folder.Folder {
+	res := []folder.Folder{}
+	var walk func(path string, depth int)
+	walk = func(path string, depth int) {
+		for _, child := range idx.children[path] {
+			res = append(res, child)
+			if depth > 1 {
+				walk(child.Paths, depth-1)
+			}
+		}
+	}
+	walk(path, depth)
+	sort.Slice(res, func(i, j int) bool { return res[i].Paths < res[j].Paths })
+	return res
+}
+
+// ancestors returns the folders above path, the root first
+func (idx *orgIndex) ancestors(path string) []folder.Folder {
+	res := []folder.Folder{}
+	for i, r := range path {
+		if r != '.' {
+			continue
+		}
+		if f, ok := idx.byPath[path[:i]]; ok {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// parentPath is the path without its last label, "" for a root
+func parentPath(path string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// newOrgLoader loads org indexes with one GetFoldersByOrgID per org and batch
+func (s *Server) newOrgLoader() *loader[uuid.UUID, *orgIndex] {
+	return newLoader(func(orgIDs []uuid.UUID) (map[uuid.UUID]*orgIndex, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		res := make(map[uuid.UUID]*orgIndex, len(orgIDs))
+		for _, orgID := range orgIDs {
+			res[orgID] = newOrgIndex(s.driver.GetFoldersByOrgID(orgID))
+		}
+		return res, nil
+	})
+}
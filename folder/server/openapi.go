@@ -0,0 +1,485 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+// The OpenAPI 3 document is built from the same route table the mux is
+// registered from, so the two can't drift apart, and requests are validated
+// against the parameter and body schemas in it before they reach a handler.
+// The types below only cover the parts of OpenAPI the API uses.
+
+const openAPIVersion = "3.0.3"
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of the OpenAPI schema object the API needs.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+func intPtr(n int) *int    { return &n }
+func boolPtr(b bool) *bool { return &b }
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// pathPattern matches a whole ltree path, labels joined by "."
+var pathPattern = `^` + labelClass + `(\.` + labelClass + `)*$`
+
+// labelClass is folder.LabelPattern without its anchors
+var labelClass = strings.TrimSuffix(strings.TrimPrefix(folder.LabelPattern, "^"), "$")
+
+// labelFormat marks strings that are a single ltree label, e.g. folder names
+const labelFormat = "ltree-label"
+
+func labelSchema(description string) *Schema {
+	return &Schema{Type: "string", Format: labelFormat, Pattern: folder.LabelPattern, MaxLength: intPtr(folder.MaxLabelLength), Description: description}
+}
+
+func componentSchemas() map[string]*Schema {
+	folders := &Schema{Type: "array", Items: ref("Folder")}
+	return map[string]*Schema{
+		"Folder": {
+			Type:     "object",
+			Required: []string{"name", "org_id", "paths"},
+			Properties: map[string]*Schema{
+				"name":   labelSchema("unique name of the folder, the last label of its path"),
+				"org_id": {Type: "string", Format: "uuid"},
+				"paths":  {Type: "string", Pattern: pathPattern, Description: "ltree path from the root of the org, labels separated by ."},
+			},
+		},
+		"FolderPage": {
+			Type:     "object",
+			Required: []string{"folders"},
+			Properties: map[string]*Schema{
+				"folders":     folders,
+				"next_cursor": {Type: "string", Description: "cursor of the next page, missing on the last page"},
+			},
+		},
+		"MoveRequest": {
+			Type:                 "object",
+			Required:             []string{"destination"},
+			AdditionalProperties: boolPtr(false),
+			Properties: map[string]*Schema{
+				"destination": labelSchema("name of the new parent, in the same org"),
+			},
+		},
+		"MoveResponse": {
+			Type:     "object",
+			Required: []string{"folders"},
+			Properties: map[string]*Schema{
+				"folders": {Type: "array", Items: ref("Folder"), Description: "the moved folder and its descendants at their new paths"},
+			},
+		},
+		"Problem": {
+			Type:        "object",
+			Description: "RFC 9457 problem details",
+			Required:    []string{"type", "title", "status"},
+			Properties: map[string]*Schema{
+				"type":     {Type: "string"},
+				"title":    {Type: "string"},
+				"status":   {Type: "integer"},
+				"detail":   {Type: "string"},
+				"instance": {Type: "string"},
+				"errors":   {Type: "array", Items: ref("FieldError")},
+			},
+		},
+		"Event": {
+			Type:        "object",
+			Description: "a change to the folders of an org",
+			Required:    []string{"type", "org_id", "seq", "time", "name", "changes"},
+			Properties: map[string]*Schema{
+				"type":    {Type: "string", Description: "folder.created, folder.moved, folder.renamed or folder.deleted"},
+				"org_id":  {Type: "string", Format: "uuid"},
+				"seq":     {Type: "integer", Description: "numbers the events of the org from 1"},
+				"time":    {Type: "string", Format: "date-time"},
+				"name":    {Type: "string", Description: "the folder the change was made to, by its name before the change"},
+				"changes": {Type: "array", Items: ref("Change"), Description: "every affected folder ordered by path"},
+			},
+		},
+		"Change": {
+			Type:     "object",
+			Required: []string{"name"},
+			Properties: map[string]*Schema{
+				"name":     {Type: "string"},
+				"old_name": {Type: "string", Description: "the name before a rename"},
+				"old_path": {Type: "string", Description: "missing for a created folder"},
+				"new_path": {Type: "string", Description: "missing for a deleted folder"},
+			},
+		},
+		"GraphQLRequest": {
+			Type:                 "object",
+			Required:             []string{"query"},
+			AdditionalProperties: boolPtr(false),
+			Properties: map[string]*Schema{
+				"query":         {Type: "string", MinLength: intPtr(1)},
+				"operationName": {Type: "string"},
+				"variables":     {Type: "object", Nullable: true},
+			},
+		},
+		"GraphQLResponse": {
+			Type: "object",
+			Properties: map[string]*Schema{
+				"data":       {Type: "object", Nullable: true},
+				"errors":     {Type: "array", Items: &Schema{Type: "object"}},
+				"extensions": {Type: "object"},
+			},
+		},
+		"FieldError": {
+			Type:     "object",
+			Required: []string{"in", "name", "reason"},
+			Properties: map[string]*Schema{
+				"in":     {Type: "string", Description: "path, query, header, body or argument for GraphQL arguments"},
+				"name":   {Type: "string"},
+				"reason": {Type: "string"},
+			},
+		},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+func problemResponse(description string) Response {
+	return Response{Description: description, Content: map[string]MediaType{problemContentType: {Schema: ref("Problem")}}}
+}
+
+// buildDocument describes routes as an OpenAPI document
+func buildDocument(routes []route) *Document {
+	doc := &Document{
+		OpenAPI: openAPIVersion,
+		Info: Info{
+			Title:       "Folder API",
+			Description: "Folders of an organization, organized as ltree paths.",
+			Version:     "1.0.0",
+		},
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: componentSchemas()},
+	}
+	for _, rt := range routes {
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = map[string]*Operation{}
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = rt.op
+	}
+	return doc
+}
+
+// FieldError is one failed check of request validation.
+type FieldError struct {
+	// path, query, header, body or argument for GraphQL arguments
+	In     string `json:"in"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// validationError is returned when a request doesn't match its operation
+type validationError struct {
+	errors []FieldError
+}
+
+func (e *validationError) Error() string {
+	msgs := make([]string, len(e.errors))
+	for i, fe := range e.errors {
+		msgs[i] = fe.Name + " " + fe.Reason
+	}
+	return "Error: invalid request: " + strings.Join(msgs, ", ")
+}
+
+// validator checks requests against the schemas of a document
+type validator struct {
+	schemas  map[string]*Schema
+	patterns map[string]*regexp.Regexp
+}
+
+func newValidator(doc *Document) *validator {
+	v := &validator{schemas: doc.Components.Schemas, patterns: map[string]*regexp.Regexp{}}
+	var walk func(s *Schema)
+	walk = func(s *Schema) {
+		if s == nil {
+			return
+		}
+		if s.Pattern != "" && v.patterns[s.Pattern] == nil {
+			v.patterns[s.Pattern] = regexp.MustCompile(s.Pattern)
+		}
+		for _, p := range s.Properties {
+			walk(p)
+		}
+		walk(s.Items)
+	}
+	for _, s := range doc.Components.Schemas {
+		walk(s)
+	}
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			for _, p := range op.Parameters {
+				walk(p.Schema)
+			}
+		}
+	}
+	return v
+}
+
+// request validates the parameters and body of r. A valid body is put back
+// into r.Body so the handler can decode it.
+func (v *validator) request(op *Operation, r *http.Request, w http.ResponseWriter) error {
+	errs := []FieldError{}
+	for _, p := range op.Parameters {
+		var value string
+		var present bool
+		switch p.In {
+		case "path":
+			value = r.PathValue(p.Name)
+			present = value != ""
+		case "query":
+			present = r.URL.Query().Has(p.Name)
+			value = r.URL.Query().Get(p.Name)
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		}
+		if !present {
+			if p.Required {
+				errs = append(errs, FieldError{In: p.In, Name: p.Name, Reason: "is required"})
+			}
+			continue
+		}
+		if reason := v.param(p.Schema, value); reason != "" {
+			errs = append(errs, FieldError{In: p.In, Name: p.Name, Reason: reason})
+		}
+	}
+
+	if op.RequestBody != nil {
+		body, err := readJSONBody(w, r)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, v.value(op.RequestBody.Content["application/json"].Schema, body, "")...)
+	}
+
+	if len(errs) > 0 {
+		return &validationError{errors: errs}
+	}
+	return nil
+}
+
+// readJSONBody reads a single JSON value from the body and puts the bytes back
+func readJSONBody(w http.ResponseWriter, r *http.Request) (any, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+		return nil, &requestError{kind: problemUnsupportedMediaType, msg: "Error: the body must be application/json"}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		return nil, badRequest("Error: reading the body: " + err.Error())
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		return nil, badRequest("Error: invalid JSON body: " + err.Error())
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, badRequest("Error: invalid JSON body: more than one value")
+	}
+	return body, nil
+}
+
+// param checks a path or query parameter, the reason it's invalid or "" when it's fine
+func (v *validator) param(s *Schema, value string) string {
+	if s.Type == "integer" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be an integer"
+		}
+		return v.integer(s, n)
+	}
+	return v.str(s, value)
+}
+
+func (v *validator) integer(s *Schema, n int) string {
+	if s.Minimum != nil && n < *s.Minimum {
+		return fmt.Sprintf("must be at least %d", *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		return fmt.Sprintf("must be at most %d", *s.Maximum)
+	}
+	return ""
+}
+
+func (v *validator) str(s *Schema, value string) string {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case s.MinLength != nil && n < *s.MinLength:
+		return fmt.Sprintf("must be at least %d characters", *s.MinLength)
+	case s.MaxLength != nil && n > *s.MaxLength:
+		return fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+	case s.Format == "uuid" && uuid.FromStringOrNil(value) == uuid.Nil:
+		return "must be a uuid"
+	case s.Pattern != "" && !v.patterns[s.Pattern].MatchString(value):
+		if s.Format == labelFormat {
+			return "must be a valid ltree label"
+		}
+		return "must match " + s.Pattern
+	}
+	return ""
+}
+
+// value checks a decoded JSON value, name is its location for the errors
+func (v *validator) value(s *Schema, value any, name string) []FieldError {
+	if s.Ref != "" {
+		s = v.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	fail := func(reason string) []FieldError {
+		if name == "" {
+			name = "body"
+		}
+		return []FieldError{{In: "body", Name: name, Reason: reason}}
+	}
+
+	if value == nil && s.Nullable {
+		return nil
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		errs := []FieldError{}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				errs = append(errs, FieldError{In: "body", Name: join(name, key), Reason: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, FieldError{In: "body", Name: join(name, key), Reason: "is not allowed"})
+				}
+				continue
+			}
+			errs = append(errs, v.value(prop, obj[key], join(name, key))...)
+		}
+		return errs
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		errs := []FieldError{}
+		for i, item := range arr {
+			errs = append(errs, v.value(s.Items, item, fmt.Sprintf("%s[%d]", name, i))...)
+		}
+		return errs
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if reason := v.str(s, str); reason != "" {
+			return fail(reason)
+		}
+	case "integer":
+		num, ok := value.(json.Number)
+		n, err := num.Int64()
+		if !ok || err != nil {
+			return fail("must be an integer")
+		}
+		if reason := v.integer(s, int(n)); reason != "" {
+			return fail(reason)
+		}
+	}
+	return nil
+}
+
+// join builds a dotted location for nested body fields
+func join(name, key string) string {
+	if name == "" {
+		return key
+	}
+	return name + "." + key
+}
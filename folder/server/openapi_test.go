@@ -0,0 +1,124 @@
+package server_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/server"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func Test_server_OpenAPIGolden(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	res, err := ts.Client().Get(ts.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	var got json.RawMessage
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+	indented, err := json.MarshalIndent(got, "", "  ")
+	require.NoError(t, err)
+	indented = append(indented, '\n')
+
+	golden := filepath.Join("testdata", "openapi.golden.json")
+	if *update {
+		require.NoError(t, os.WriteFile(golden, indented, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(indented), "run go test -update to regenerate")
+}
+
+// jsonFields returns the JSON names of the fields of a struct type
+func jsonFields(typ reflect.Type) []string {
+	res := []string{}
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func Test_server_OpenAPISchemasMatchTypes(t *testing.T) {
+	t.Parallel()
+	doc := server.New(folder.NewDriver(nil), server.Options{}).OpenAPI()
+
+	types := map[string]any{
+		"Folder":       folder.Folder{},
+		"FolderPage":   server.FolderPage{},
+		"MoveRequest":  server.MoveRequest{},
+		"MoveResponse": server.MoveResponse{},
+		"Problem":      server.Problem{},
+		"FieldError":   server.FieldError{},
+		"Event":        folder.Event{},
+		"Change":       folder.Change{},
+		// the body of POST /graphql and the result graphql-go encodes
+		"GraphQLRequest":  server.GraphQLRequest{},
+		"GraphQLResponse": graphql.Result{},
+	}
+	assert.Len(t, doc.Components.Schemas, len(types))
+	for name, v := range types {
+		schema := doc.Components.Schemas[name]
+		if !assert.NotNil(t, schema, name) {
+			continue
+		}
+		props := []string{}
+		for prop := range schema.Properties {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+		assert.Equal(t, jsonFields(reflect.TypeOf(v)), props, name)
+		for _, req := range schema.Required {
+			assert.Contains(t, props, req, name)
+		}
+	}
+}
+
+func Test_server_OpenAPIOperations(t *testing.T) {
+	t.Parallel()
+	doc := server.New(folder.NewDriver(nil), server.Options{MaxPageSize: 50}).OpenAPI()
+
+	ops := map[string]string{}
+	for path, methods := range doc.Paths {
+		for method, op := range methods {
+			ops[op.OperationID] = method + " " + path
+			assert.Contains(t, op.Responses, "200", op.OperationID)
+			// every path parameter of the template is declared
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					assert.Contains(t, path, "{"+p.Name+"}", op.OperationID)
+					assert.True(t, p.Required, op.OperationID)
+				}
+				if p.Name == "limit" {
+					assert.Equal(t, 50, *p.Schema.Maximum)
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"listFolders":      "get /orgs/{orgID}/folders",
+		"listChildFolders": "get /orgs/{orgID}/folders/{name}/children",
+		"moveFolder":       "post /orgs/{orgID}/folders/{name}/move",
+		"streamEvents":     "get /orgs/{orgID}/events",
+		"graphql":          "post /graphql",
+		"getOpenAPI":       "get /openapi.json",
+	}, ops)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+)
+
+// Pages are keyset paginated on the folder path: a cursor is the last path of
+// the previous page, so folders moved or added between two requests don't
+// shift the pages after them the way offsets would.
+
+type page struct {
+	limit int
+	// only folders with a path after this one
+	after string
+}
+
+func (s *Server) parsePage(r *http.Request) (page, error) {
+	p := page{limit: s.opts.DefaultPageSize}
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > s.opts.MaxPageSize {
+			return p, badRequest("Error: limit must be between 1 and " + strconv.Itoa(s.opts.MaxPageSize))
+		}
+		p.limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		after, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(after) == 0 {
+			return p, badRequest("Error: cursor is not valid")
+		}
+		p.after = string(after)
+	}
+	return p, nil
+}
+
+// apply sorts folders by path and cuts out the page
+func (p page) apply(folders []folder.Folder) FolderPage {
+	sorted := append([]folder.Folder{}, folders...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Paths < sorted[j].Paths })
+
+	start := 0
+	if p.after != "" {
+		start = sort.Search(len(sorted), func(i int) bool { return sorted[i].Paths > p.after })
+	}
+	end := min(start+p.limit, len(sorted))
+
+	res := FolderPage{Folders: sorted[start:end]}
+	if end < len(sorted) {
+		res.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(sorted[end-1].Paths))
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+)
+
+// Problem is an RFC 9457 problem details body, sent as application/problem+json.
+type Problem struct {
+	// identifies the kind of problem, relative to the server root
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// the request path the problem is about
+	Instance string `json:"instance,omitempty"`
+	// every failed check when the request doesn't match the OpenAPI document
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+const problemContentType = "application/problem+json"
+
+// problemKind is the type, title and status of a class of errors
+type problemKind struct {
+	typ, title string
+	status     int
+}
+
+var (
+	problemNotFound             = problemKind{"/problems/not-found", "Not found", http.StatusNotFound}
+	problemInvalidMove          = problemKind{"/problems/invalid-move", "Invalid move", http.StatusUnprocessableEntity}
+	problemMoveConflict         = problemKind{"/problems/move-conflict", "Move conflicts with the folder tree", http.StatusConflict}
+	problemBadRequest           = problemKind{"/problems/bad-request", "Bad request", http.StatusBadRequest}
+	problemValidation           = problemKind{"/problems/validation", "Request validation failed", http.StatusBadRequest}
+	problemUnsupportedMediaType = problemKind{"/problems/unsupported-media-type", "Unsupported media type", http.StatusUnsupportedMediaType}
+	problemInternal             = problemKind{"/problems/internal", "Internal server error", http.StatusInternalServerError}
+	problemNotImplemented       = problemKind{"/problems/not-implemented", "Not implemented", http.StatusNotImplemented}
+)
+
+// driverProblems maps the folder package's errors to problems:
+// 404 when a folder in the URL doesn't exist, 422 when the request body names
+// a destination that can't be used, 409 when the move would break the tree and
+// 400 for a pattern that doesn't parse
+var driverProblems = []struct {
+	err  error
+	kind problemKind
+}{
+	{folder.ErrFolderNotFound, problemNotFound},
+	{folder.ErrFolderNotInOrg, problemNotFound},
+	{folder.ErrSourceNotFound, problemNotFound},
+	{folder.ErrDestinationNotFound, problemInvalidMove},
+	{folder.ErrMoveToSelf, problemInvalidMove},
+	{folder.ErrMoveToOtherOrg, problemMoveConflict},
+	{folder.ErrMoveToDescendant, problemMoveConflict},
+	{folder.ErrInvalidLQuery, problemBadRequest},
+}
+
+// requestError is a problem with the request itself rather than the data
+type requestError struct {
+	kind problemKind
+	msg  string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func badRequest(msg string) error {
+	return &requestError{kind: problemBadRequest, msg: msg}
+}
+
+// problemFor turns an error from a handler into a problem
+func problemFor(err error, r *http.Request) Problem {
+	kind, detail := classify(err)
+
+	var fieldErrs []FieldError
+	var valErr *validationError
+	if errors.As(err, &valErr) {
+		fieldErrs = valErr.errors
+	}
+	return Problem{Type: kind.typ, Title: kind.title, Status: kind.status, Detail: detail, Instance: r.URL.Path, Errors: fieldErrs}
+}
+
+// classify returns the kind of problem err is and the detail the client may see
+func classify(err error) (problemKind, string) {
+	var reqErr *requestError
+	var valErr *validationError
+	switch {
+	case errors.As(err, &valErr):
+		return problemValidation, valErr.Error()
+	case errors.As(err, &reqErr):
+		return reqErr.kind, reqErr.msg
+	}
+	for _, p := range driverProblems {
+		if errors.Is(err, p.err) {
+			return p.kind, err.Error()
+		}
+	}
+	return problemInternal, "Error: the request could not be completed"
+}
+
+func writeProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,372 @@
+// Package server exposes a folder.IDriver over HTTP as a JSON API.
+//
+//	GET  /orgs/{orgID}/folders                  every folder of the org
+//	GET  /orgs/{orgID}/folders/{name}/children  every descendant of a folder
+//	POST /orgs/{orgID}/folders/{name}/move      {"destination": "name"}, moves the folder and its subtree
+//	GET  /orgs/{orgID}/events                   Server-Sent Events of the org's changes, see streamEvents
+//	POST /graphql                               GraphQL queries of folder trees and moves, see GraphQLSDL
+//	GET  /openapi.json                          the OpenAPI 3 document of the routes above
+//
+// Lists are ordered by path and paginated with ?limit= and the opaque
+// ?cursor= of the previous page's next_cursor. Errors are RFC 9457 problem
+// details: 404 for folders in the URL that don't exist, 422 for a destination
+// that can't be used, 409 for a move that would break the tree and 400 for
+// requests that don't match the OpenAPI document, e.g. an org id that isn't a
+// uuid or a folder name that isn't an ltree label.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+	// limit on the size of request bodies
+	maxBodyBytes = 1 << 20
+)
+
+type Options struct {
+	// page size when the request has no limit, DefaultPageSize when zero
+	DefaultPageSize int
+	// largest limit a request may ask for, MaxPageSize when zero
+	MaxPageSize int
+	// logs errors the client doesn't get to see, log.Default() when nil
+	ErrorLog *log.Logger
+	// how often idle event streams get a keep-alive comment, DefaultKeepAlive when zero
+	KeepAlive time.Duration
+}
+
+// Server is an http.Handler for a driver. Drivers are not safe for concurrent
+// use, so reads share a lock and moves take it exclusively.
+type Server struct {
+	driver folder.IDriver
+	opts   Options
+	mu     sync.RWMutex
+	mux    *http.ServeMux
+
+	doc       *Document
+	docJSON   []byte
+	validator *validator
+	schema    graphql.Schema
+
+	// closed by Close to end the event streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// route is one operation of the API, both the mux and the OpenAPI document are built from these
+type route struct {
+	method string
+	// OpenAPI path template, also a valid ServeMux pattern
+	path    string
+	op      *Operation
+	handler handlerFunc
+}
+
+// New returns a Server for driver.
+func New(driver folder.IDriver, opts Options) *Server {
+	if opts.DefaultPageSize <= 0 {
+		opts.DefaultPageSize = DefaultPageSize
+	}
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = MaxPageSize
+	}
+	if opts.DefaultPageSize > opts.MaxPageSize {
+		opts.DefaultPageSize = opts.MaxPageSize
+	}
+	if opts.ErrorLog == nil {
+		opts.ErrorLog = log.Default()
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+
+	s := &Server{driver: driver, opts: opts, mux: http.NewServeMux(), done: make(chan struct{})}
+	if n, ok := driver.(folder.Notifier); ok {
+		// drivers publish from their first subscription on, so a client
+		// resuming the event stream finds every change made since the start
+		n.Subscribe(folder.SubscribeOptions{}).Close()
+	}
+	s.schema = s.buildGraphQLSchema()
+	routes := s.routes()
+	s.doc = buildDocument(routes)
+	s.validator = newValidator(s.doc)
+	docJSON, err := json.MarshalIndent(s.doc, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	s.docJSON = append(docJSON, '\n')
+
+	for _, rt := range routes {
+		s.handle(rt)
+	}
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, problemFor(&requestError{kind: problemNotFound, msg: "Error: no route for " + r.Method + " " + r.URL.Path}, r))
+	})
+	return s
+}
+
+// OpenAPI returns the document served at /openapi.json, it must not be modified.
+func (s *Server) OpenAPI() *Document {
+	return s.doc
+}
+
+func (s *Server) routes() []route {
+	orgID := Parameter{Name: "orgID", In: "path", Required: true, Schema: &Schema{Type: "string", Format: "uuid"}}
+	name := Parameter{Name: "name", In: "path", Required: true, Schema: labelSchema("folder name")}
+	limit := Parameter{Name: "limit", In: "query", Description: "page size", Schema: &Schema{
+		Type: "integer", Minimum: intPtr(1), Maximum: intPtr(s.opts.MaxPageSize), Default: s.opts.DefaultPageSize,
+	}}
+	cursor := Parameter{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: &Schema{
+		Type: "string", Pattern: `^[A-Za-z0-9_-]+$`,
+	}}
+
+	page := Response{Description: "one page of folders ordered by path", Content: jsonContent(ref("FolderPage"))}
+	invalid := problemResponse("the request does not match this document")
+
+	return []route{
+		{
+			method: "GET", path: "/orgs/{orgID}/folders", handler: s.listFolders,
+			op: &Operation{
+				OperationID: "listFolders",
+				Summary:     "List the folders of an org",
+				Parameters:  []Parameter{orgID, limit, cursor},
+				Responses:   map[string]Response{"200": page, "400": invalid},
+			},
+		},
+		{
+			method: "GET", path: "/orgs/{orgID}/folders/{name}/children", handler: s.listChildren,
+			op: &Operation{
+				OperationID: "listChildFolders",
+				Summary:     "List every descendant of a folder",
+				Parameters:  []Parameter{orgID, name, limit, cursor},
+				Responses: map[string]Response{
+					"200": page,
+					"400": invalid,
+					"404": problemResponse("the folder does not exist in the org"),
+				},
+			},
+		},
+		{
+			method: "POST", path: "/orgs/{orgID}/folders/{name}/move", handler: s.moveFolder,
+			op: &Operation{
+				OperationID: "moveFolder",
+				Summary:     "Move a folder and its subtree below another folder of the same org",
+				Parameters:  []Parameter{orgID, name},
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(ref("MoveRequest"))},
+				Responses: map[string]Response{
+					"200": {Description: "the folder was moved", Content: jsonContent(ref("MoveResponse"))},
+					"400": invalid,
+					"404": problemResponse("the folder does not exist in the org"),
+					"409": problemResponse("the destination is below the folder or in another org"),
+					"415": problemResponse("the body is not application/json"),
+					"422": problemResponse("the destination does not exist or is the folder itself"),
+				},
+			},
+		},
+		{
+			method: "GET", path: "/orgs/{orgID}/events", handler: s.streamEvents,
+			op: &Operation{
+				OperationID: "streamEvents",
+				Summary:     "Stream the changes of an org as Server-Sent Events",
+				Parameters: []Parameter{
+					orgID,
+					{Name: "subtree", In: "query", Description: "only changes at or below this path", Schema: &Schema{
+						Type: "string", Pattern: pathPattern,
+					}},
+					{Name: "Last-Event-ID", In: "header", Description: "resume after this event, sent by EventSource when it reconnects", Schema: &Schema{
+						Type: "integer", Minimum: intPtr(0),
+					}},
+				},
+				Responses: map[string]Response{
+					"200": {
+						Description: "one event per change, the id is the seq of the event and the data an Event",
+						Content:     map[string]MediaType{"text/event-stream": {Schema: ref("Event")}},
+					},
+					"400": invalid,
+					"501": problemResponse("the driver does not publish change events"),
+				},
+			},
+		},
+		{
+			method: "POST", path: "/graphql", handler: s.graphQL,
+			op: &Operation{
+				OperationID: "graphql",
+				Summary:     "Run a GraphQL query or mutation, errors of the operation are in the errors of the response",
+				RequestBody: &RequestBody{Required: true, Content: jsonContent(ref("GraphQLRequest"))},
+				Responses: map[string]Response{
+					"200": {Description: "the result of the operation", Content: jsonContent(ref("GraphQLResponse"))},
+					"400": invalid,
+					"415": problemResponse("the body is not application/json"),
+				},
+			},
+		},
+		{
+			method: "GET", path: "/openapi.json", handler: s.openAPI,
+			op: &Operation{
+				OperationID: "getOpenAPI",
+				Summary:     "This document",
+				Responses:   map[string]Response{"200": {Description: "the OpenAPI document", Content: jsonContent(&Schema{Type: "object"})}},
+			},
+		},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handlerFunc is an http.HandlerFunc that returns its error instead of writing it
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (s *Server) handle(rt route) {
+	s.mux.HandleFunc(rt.method+" "+rt.path, func(w http.ResponseWriter, r *http.Request) {
+		err := s.validator.request(rt.op, r, w)
+		if err == nil {
+			err = rt.handler(w, r)
+		}
+		if err == nil {
+			return
+		}
+		p := problemFor(err, r)
+		if p.Status == http.StatusInternalServerError {
+			s.opts.ErrorLog.Printf("folder server: %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		writeProblem(w, p)
+	})
+}
+
+func (s *Server) openAPI(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(s.docJSON)
+	return err
+}
+
+// FolderPage is one page of a folder list.
+type FolderPage struct {
+	Folders []folder.Folder `json:"folders"`
+	// pass as ?cursor= to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// MoveRequest is the body of a move.
+type MoveRequest struct {
+	Destination string `json:"destination"`
+}
+
+// MoveResponse lists the moved folder and its descendants at their new paths.
+type MoveResponse struct {
+	Folders []folder.Folder `json:"folders"`
+}
+
+// pathOrgID returns the org of the URL, the validator already checked it is a uuid
+func pathOrgID(r *http.Request) (uuid.UUID, error) {
+	orgID, err := uuid.FromString(r.PathValue("orgID"))
+	if err != nil {
+		return uuid.Nil, badRequest("Error: orgID is not a valid uuid")
+	}
+	return orgID, nil
+}
+
+func (s *Server) listFolders(w http.ResponseWriter, r *http.Request) error {
+	orgID, err := pathOrgID(r)
+	if err != nil {
+		return err
+	}
+	page, err := s.parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	folders := s.driver.GetFoldersByOrgID(orgID)
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, page.apply(folders))
+	return nil
+}
+
+func (s *Server) listChildren(w http.ResponseWriter, r *http.Request) error {
+	orgID, err := pathOrgID(r)
+	if err != nil {
+		return err
+	}
+	page, err := s.parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	children, err := s.driver.GetAllChildFolders(orgID, r.PathValue("name"))
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	writeJSON(w, http.StatusOK, page.apply(children))
+	return nil
+}
+
+func (s *Server) moveFolder(w http.ResponseWriter, r *http.Request) error {
+	orgID, err := pathOrgID(r)
+	if err != nil {
+		return err
+	}
+	name := r.PathValue("name")
+
+	// the validator checked the body against the MoveRequest schema
+	var req MoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return badRequest("Error: invalid JSON body: " + err.Error())
+	}
+
+	moved, err := s.move(orgID, name, req.Destination)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, MoveResponse{Folders: moved})
+	return nil
+}
+
+// move moves the folder called name of the org below dst and returns it and
+// its descendants at their new paths
+func (s *Server) move(orgID uuid.UUID, name, dst string) ([]folder.Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// MoveFolder looks folders up by name alone, the org in the URL has to be checked first
+	if _, err := s.driver.GetAllChildFolders(orgID, name); err != nil {
+		if errors.Is(err, folder.ErrFolderNotFound) {
+			return nil, folder.ErrSourceNotFound
+		}
+		return nil, err
+	}
+	if _, err := s.driver.MoveFolder(name, dst); err != nil {
+		return nil, err
+	}
+	return s.subtree(orgID, name)
+}
+
+// subtree returns the folder called name and all of its descendants
+func (s *Server) subtree(orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	children, err := s.driver.GetAllChildFolders(orgID, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range s.driver.GetFoldersByOrgID(orgID) {
+		if f.Name == name {
+			return append([]folder.Folder{f}, children...), nil
+		}
+	}
+	return nil, folder.ErrFolderNotInOrg
+}
@@ -0,0 +1,311 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	orgA = foldertest.OrgA.String()
+	orgB = foldertest.OrgB.String()
+)
+
+func newTestServer(t *testing.T, folders []folder.Folder, opts server.Options) *httptest.Server {
+	t.Helper()
+	ts := httptest.NewServer(server.New(folder.NewDriver(folders), opts))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// do sends a request and decodes the JSON response into v, if v is not nil
+func do(t *testing.T, ts *httptest.Server, method, path, body string, v any) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	if v != nil {
+		require.NoError(t, json.Unmarshal(data, v), string(data))
+	}
+	return res
+}
+
+func names(folders []folder.Folder) []string {
+	res := []string{}
+	for _, f := range folders {
+		res = append(res, f.Name)
+	}
+	return res
+}
+
+func Test_server_ListFolders(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	var page server.FolderPage
+	res := do(t, ts, "GET", "/orgs/"+orgA+"/folders", "", &page)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	// ordered by path
+	assert.Equal(t, []string{"alpha", "bravo", "charlie", "delta", "echo", "golf"}, names(page.Folders))
+	assert.Empty(t, page.NextCursor)
+
+	res = do(t, ts, "GET", "/orgs/"+foldertest.OrgEmpty.String()+"/folders", "", &page)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []folder.Folder{}, page.Folders)
+}
+
+func Test_server_Pagination(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{DefaultPageSize: 4})
+
+	got := [][]string{}
+	path := "/orgs/" + orgA + "/folders?limit=2"
+	for {
+		var page server.FolderPage
+		res := do(t, ts, "GET", path, "", &page)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		got = append(got, names(page.Folders))
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/orgs/" + orgA + "/folders?limit=2&cursor=" + page.NextCursor
+	}
+	assert.Equal(t, [][]string{{"alpha", "bravo"}, {"charlie", "delta"}, {"echo", "golf"}}, got)
+
+	// the default page size applies without a limit
+	var page server.FolderPage
+	do(t, ts, "GET", "/orgs/"+orgA+"/folders/alpha/children", "", &page)
+	assert.Equal(t, []string{"bravo", "charlie", "delta", "echo"}, names(page.Folders))
+	assert.Empty(t, page.NextCursor)
+}
+
+func Test_server_ListChildren(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	var page server.FolderPage
+	res := do(t, ts, "GET", "/orgs/"+orgA+"/folders/bravo/children", "", &page)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"charlie"}, names(page.Folders))
+
+	res = do(t, ts, "GET", "/orgs/"+orgA+"/folders/golf/children", "", &page)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []folder.Folder{}, page.Folders)
+}
+
+func Test_server_Move(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	var moved server.MoveResponse
+	res := do(t, ts, "POST", "/orgs/"+orgA+"/folders/bravo/move", `{"destination": "delta"}`, &moved)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []folder.Folder{
+		{Name: "bravo", OrgId: foldertest.OrgA, Paths: "alpha.delta.bravo"},
+		{Name: "charlie", OrgId: foldertest.OrgA, Paths: "alpha.delta.bravo.charlie"},
+	}, moved.Folders)
+
+	// the move is visible to later reads
+	var page server.FolderPage
+	do(t, ts, "GET", "/orgs/"+orgA+"/folders/delta/children", "", &page)
+	assert.Equal(t, []string{"bravo", "charlie", "echo"}, names(page.Folders))
+}
+
+func Test_server_Problems(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		typ    string
+		detail string
+		errors []server.FieldError
+	}{
+		{
+			name: "unknown folder", method: "GET", path: "/orgs/" + orgA + "/folders/nope/children",
+			status: 404, typ: "/problems/not-found", detail: folder.ErrFolderNotFound.Error(),
+		},
+		{
+			name: "folder of another org", method: "GET", path: "/orgs/" + orgB + "/folders/alpha/children",
+			status: 404, typ: "/problems/not-found", detail: folder.ErrFolderNotInOrg.Error(),
+		},
+		{
+			name: "bad org id", method: "GET", path: "/orgs/nope/folders",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: orgID must be a uuid",
+			errors: []server.FieldError{{In: "path", Name: "orgID", Reason: "must be a uuid"}},
+		},
+		{
+			name: "limit too small", method: "GET", path: "/orgs/" + orgA + "/folders?limit=0",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: limit must be at least 1",
+			errors: []server.FieldError{{In: "query", Name: "limit", Reason: "must be at least 1"}},
+		},
+		{
+			name: "limit too large", method: "GET", path: "/orgs/" + orgA + "/folders?limit=1001",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: limit must be at most 1000",
+			errors: []server.FieldError{{In: "query", Name: "limit", Reason: "must be at most 1000"}},
+		},
+		{
+			name: "limit not a number", method: "GET", path: "/orgs/" + orgA + "/folders?limit=ten",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: limit must be an integer",
+			errors: []server.FieldError{{In: "query", Name: "limit", Reason: "must be an integer"}},
+		},
+		{
+			name: "bad cursor", method: "GET", path: "/orgs/" + orgA + "/folders?cursor=***",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: cursor must match ^[A-Za-z0-9_-]+$",
+			errors: []server.FieldError{{In: "query", Name: "cursor", Reason: "must match ^[A-Za-z0-9_-]+$"}},
+		},
+		{
+			name: "cursor that doesn't decode", method: "GET", path: "/orgs/" + orgA + "/folders?cursor=a",
+			status: 400, typ: "/problems/bad-request", detail: "Error: cursor is not valid",
+		},
+		{
+			name: "name that is not an ltree label", method: "GET", path: "/orgs/" + orgA + "/folders/a.b/children",
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: name must be a valid ltree label",
+			errors: []server.FieldError{{In: "path", Name: "name", Reason: "must be a valid ltree label"}},
+		},
+		{
+			name: "unknown route", method: "GET", path: "/folders",
+			status: 404, typ: "/problems/not-found", detail: "Error: no route for GET /folders",
+		},
+		{
+			name: "move unknown source", method: "POST", path: "/orgs/" + orgA + "/folders/nope/move", body: `{"destination": "golf"}`,
+			status: 404, typ: "/problems/not-found", detail: folder.ErrSourceNotFound.Error(),
+		},
+		{
+			name: "move source of another org", method: "POST", path: "/orgs/" + orgB + "/folders/bravo/move", body: `{"destination": "foxtrot"}`,
+			status: 404, typ: "/problems/not-found", detail: folder.ErrFolderNotInOrg.Error(),
+		},
+		{
+			name: "move to unknown destination", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "nope"}`,
+			status: 422, typ: "/problems/invalid-move", detail: folder.ErrDestinationNotFound.Error(),
+		},
+		{
+			name: "move to self", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "bravo"}`,
+			status: 422, typ: "/problems/invalid-move", detail: folder.ErrMoveToSelf.Error(),
+		},
+		{
+			name: "move to descendant", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "charlie"}`,
+			status: 409, typ: "/problems/move-conflict", detail: folder.ErrMoveToDescendant.Error(),
+		},
+		{
+			name: "move to other org", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "foxtrot"}`,
+			status: 409, typ: "/problems/move-conflict", detail: folder.ErrMoveToOtherOrg.Error(),
+		},
+		{
+			name: "missing destination", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{}`,
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: destination is required",
+			errors: []server.FieldError{{In: "body", Name: "destination", Reason: "is required"}},
+		},
+		{
+			name: "destination that is not an ltree label", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "a b"}`,
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: destination must be a valid ltree label",
+			errors: []server.FieldError{{In: "body", Name: "destination", Reason: "must be a valid ltree label"}},
+		},
+		{
+			name: "destination of the wrong type", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": 1}`,
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: destination must be a string",
+			errors: []server.FieldError{{In: "body", Name: "destination", Reason: "must be a string"}},
+		},
+		{
+			name: "unknown field", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"dst": "golf"}`,
+			status: 400, typ: "/problems/validation", detail: "Error: invalid request: destination is required, dst is not allowed",
+			errors: []server.FieldError{
+				{In: "body", Name: "destination", Reason: "is required"},
+				{In: "body", Name: "dst", Reason: "is not allowed"},
+			},
+		},
+		{
+			name: "not json", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `destination=golf`,
+			status: 400, typ: "/problems/bad-request", detail: "Error: invalid JSON body: invalid character 'd' looking for beginning of value",
+		},
+		{
+			name: "trailing data", method: "POST", path: "/orgs/" + orgA + "/folders/bravo/move", body: `{"destination": "golf"} {}`,
+			status: 400, typ: "/problems/bad-request", detail: "Error: invalid JSON body: more than one value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+			var problem server.Problem
+			res := do(t, ts, tt.method, tt.path, tt.body, &problem)
+			assert.Equal(t, tt.status, res.StatusCode)
+			assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+			assert.Equal(t, server.Problem{
+				Type:     tt.typ,
+				Title:    problem.Title,
+				Status:   tt.status,
+				Detail:   tt.detail,
+				Instance: strings.Split(tt.path, "?")[0],
+				Errors:   tt.errors,
+			}, problem)
+			assert.NotEmpty(t, problem.Title)
+		})
+	}
+}
+
+func Test_server_UnsupportedMediaType(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	res, err := ts.Client().Post(ts.URL+"/orgs/"+orgA+"/folders/bravo/move", "text/plain", strings.NewReader(`{"destination": "delta"}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var problem server.Problem
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+	assert.Equal(t, "/problems/unsupported-media-type", problem.Type)
+	assert.Equal(t, "Error: the body must be application/json", problem.Detail)
+}
+
+func Test_server_ConcurrentMoves(t *testing.T) {
+	t.Parallel()
+	ts := newTestServer(t, foldertest.MoveData(), server.Options{})
+
+	// golf bounces between alpha and delta while others read, every request must succeed
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		dst := []string{"alpha", "delta"}[i%2]
+		go func() {
+			defer wg.Done()
+			res, err := ts.Client().Post(ts.URL+"/orgs/"+orgA+"/folders/golf/move", "application/json", strings.NewReader(`{"destination": "`+dst+`"}`))
+			if assert.NoError(t, err) {
+				res.Body.Close()
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			res, err := ts.Client().Get(ts.URL + "/orgs/" + orgA + "/folders")
+			if assert.NoError(t, err) {
+				res.Body.Close()
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,14 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+)
+
+func Test_sqlstore_DriverSuite(t *testing.T) {
+	foldertest.RunDriverSuite(t, func(t *testing.T, folders []folder.Folder) folder.IDriver {
+		return openDriver(t, folders)
+	})
+}
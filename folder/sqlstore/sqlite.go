@@ -0,0 +1,27 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	// pure Go SQLite, no cgo or server needed
+	_ "modernc.org/sqlite"
+)
+
+// OpenSQLite opens an embedded SQLite database, for example ":memory:" or a
+// file path, and prepares the folders table.
+func OpenSQLite(ctx context.Context, dsn string) (*Driver, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// an in-memory database only lives as long as its connection
+	db.SetMaxOpenConns(1)
+
+	d, err := New(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
+}
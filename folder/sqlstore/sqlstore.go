@@ -0,0 +1,213 @@
+// Package sqlstore is a folder driver that keeps folders in a SQL table through
+// database/sql instead of a []Folder.
+//
+// ltree is Postgres only, so paths are stored as plain text and the ltree
+// prefix semantics are emulated with a range scan over an (org_id, path)
+// index: the descendants of p are the paths in [p + ".", p + "/"), since "/"
+// is the byte right after ".". The SQL sticks to what SQLite understands,
+// see OpenSQLite for the embedded backend used by the tests. Only the
+// statements differ from ltreesql, the checks around them are those of
+// ltreesql.Driver.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/ltreesql"
+	"github.com/gofrs/uuid"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS folders (
+	id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	name   TEXT NOT NULL,
+	org_id TEXT NOT NULL,
+	path   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS folders_org_path ON folders (org_id, path);
+CREATE INDEX IF NOT EXISTS folders_name ON folders (name);
+`
+
+// Driver implements folder.IDriver on top of a *sql.DB.
+type Driver struct {
+	db *sql.DB
+	// runs single statements outside of a transaction
+	driver *ltreesql.Driver
+}
+
+var _ folder.IDriver = (*Driver)(nil)
+
+// New creates the folders table and its indexes when they don't exist yet.
+func New(ctx context.Context, db *sql.DB) (*Driver, error) {
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return nil, fmt.Errorf("Error: creating schema: %w", err)
+	}
+	return &Driver{db: db, driver: ltreesql.NewDriver(ltreesql.DB(db), statements{})}, nil
+}
+
+// DB returns the underlying database.
+func (d *Driver) DB() *sql.DB {
+	return d.db
+}
+
+// Insert adds folders in a single transaction, keeping their order.
+func (d *Driver) Insert(ctx context.Context, folders []folder.Folder) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO folders (name, org_id, path) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, f := range folders {
+		if _, err := stmt.ExecContext(ctx, f.Name, f.OrgId.String(), f.Paths); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// statements are the SQLite versions of the ltreesql.Builder statements, so
+// the checks ltreesql.Driver makes around them are shared with Postgres.
+type statements struct{}
+
+var _ ltreesql.Statements = statements{}
+
+const selectColumns = "SELECT name, org_id, path FROM folders"
+
+// descendantsOf is the where clause for path <@ $path, excluding the folder itself
+const descendantsOf = "org_id = ? AND path >= ? AND path < ?"
+
+// descendantArgs returns the arguments for descendantsOf
+func descendantArgs(orgID uuid.UUID, path string) []any {
+	return []any{orgID.String(), path + ".", path + "/"}
+}
+
+func (statements) All() ltreesql.Statement {
+	return ltreesql.Statement{SQL: selectColumns + " ORDER BY id"}
+}
+
+func (statements) FoldersByOrgID(orgID uuid.UUID) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  selectColumns + " WHERE org_id = ? ORDER BY id",
+		Args: []any{orgID.String()},
+	}
+}
+
+func (statements) FolderByName(name string) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  selectColumns + " WHERE name = ? ORDER BY id LIMIT 1",
+		Args: []any{name},
+	}
+}
+
+func (statements) FolderByOrgAndName(orgID uuid.UUID, name string) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  selectColumns + " WHERE org_id = ? AND name = ? ORDER BY id LIMIT 1",
+		Args: []any{orgID.String(), name},
+	}
+}
+
+func (statements) ChildFolders(orgID uuid.UUID, path string) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  selectColumns + " WHERE " + descendantsOf + " ORDER BY id",
+		Args: descendantArgs(orgID, path),
+	}
+}
+
+// MoveFolder is ltree's path = $new || subpath(path, nlevel($old)), substr counts characters not bytes
+func (statements) MoveFolder(orgID uuid.UUID, oldPath string, dstPath string, name string) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  "UPDATE folders SET path = ? || substr(path, ?) WHERE (org_id = ? AND path = ?) OR (" + descendantsOf + ")",
+		Args: append([]any{dstPath + "." + name, utf8.RuneCountInString(oldPath) + 1, orgID.String(), oldPath}, descendantArgs(orgID, oldPath)...),
+	}
+}
+
+func (statements) DeleteFolder(orgID uuid.UUID, path string) ltreesql.Statement {
+	return ltreesql.Statement{
+		SQL:  "DELETE FROM folders WHERE (org_id = ? AND path = ?) OR (" + descendantsOf + ")",
+		Args: append([]any{orgID.String(), path}, descendantArgs(orgID, path)...),
+	}
+}
+
+// inTx runs fn with a driver whose statements all run in one transaction,
+// committed when fn succeeds
+func (d *Driver) inTx(ctx context.Context, fn func(tx *ltreesql.Driver) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ltreesql.NewDriver(ltreesql.DB(tx), statements{})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListFolders is GetFoldersByOrgID with a context and an error.
+func (d *Driver) ListFolders(ctx context.Context, orgID uuid.UUID) ([]folder.Folder, error) {
+	return d.driver.ListFolders(ctx, orgID)
+}
+
+// GetFoldersByOrgID returns an empty slice when the query fails, use ListFolders to see the error.
+func (d *Driver) GetFoldersByOrgID(orgID uuid.UUID) []folder.Folder {
+	return d.driver.GetFoldersByOrgID(orgID)
+}
+
+// ChildFolders is GetAllChildFolders with a context.
+func (d *Driver) ChildFolders(ctx context.Context, orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	return d.driver.ChildFolders(ctx, orgID, name)
+}
+
+func (d *Driver) GetAllChildFolders(orgID uuid.UUID, name string) ([]folder.Folder, error) {
+	return d.ChildFolders(context.Background(), orgID, name)
+}
+
+// Move is MoveFolder with a context. The checks and the update run in one
+// transaction so a concurrent move can't slip in between them.
+func (d *Driver) Move(ctx context.Context, name string, dst string) ([]folder.Folder, error) {
+	var folders []folder.Folder
+	err := d.inTx(ctx, func(tx *ltreesql.Driver) error {
+		var err error
+		folders, err = tx.Move(ctx, name, dst)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (d *Driver) MoveFolder(name string, dst string) ([]folder.Folder, error) {
+	return d.Move(context.Background(), name, dst)
+}
+
+// DeleteFolder deletes a folder and all of its descendants within an org and
+// returns how many folders were removed.
+func (d *Driver) DeleteFolder(ctx context.Context, orgID uuid.UUID, name string) (int64, error) {
+	var n int64
+	err := d.inTx(ctx, func(tx *ltreesql.Driver) error {
+		var err error
+		n, err = tx.DeleteFolder(ctx, orgID, name)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close closes the underlying database.
+func (d *Driver) Close() error {
+	return d.db.Close()
+}
@@ -0,0 +1,134 @@
+package sqlstore_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/sqlstore"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestingData() []folder.Folder {
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	return []folder.Folder{
+		{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+		{Name: "charlie", OrgId: orgID, Paths: "alpha.bravo.charlie"},
+		{Name: "delta", OrgId: orgID, Paths: "alpha.delta"},
+		{Name: "echo", OrgId: orgID, Paths: "alpha.delta.echo"},
+		{Name: "foxtrot", OrgId: uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a"), Paths: "foxtrot"},
+		{Name: "golf", OrgId: orgID, Paths: "golf"},
+		// shares a prefix with alpha but is not below it
+		{Name: "alphabet", OrgId: orgID, Paths: "golf.alphabet"},
+		{Name: "ünïcode", OrgId: orgID, Paths: "golf.alphabet.ünïcode"},
+	}
+}
+
+// openDriver returns an in-memory SQLite driver loaded with folders
+func openDriver(t *testing.T, folders []folder.Folder) *sqlstore.Driver {
+	t.Helper()
+	ctx := context.Background()
+	d, err := sqlstore.OpenSQLite(ctx, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Close() })
+	require.NoError(t, d.Insert(ctx, folders))
+	return d
+}
+
+func Test_sqlstore_MatchesMemoryDriver(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	moves := [][2]string{
+		{"bravo", "delta"},
+		{"bravo", "golf"},
+		{"alpha", "golf"},
+		{"alphabet", "charlie"},
+		{"ünïcode", "echo"},
+		{"bravo", "charlie"},
+		{"alpha", "echo"},
+		{"bravo", "bravo"},
+		{"bravo", "foxtrot"},
+		{"invalid_folder", "delta"},
+		{"bravo", "invalid_folder"},
+	}
+	for _, move := range moves {
+		t.Run(move[0]+"->"+move[1], func(t *testing.T) {
+			memory := folder.NewDriver(getTestingData())
+			sql := openDriver(t, getTestingData())
+
+			want, wantErr := memory.MoveFolder(move[0], move[1])
+			got, err := sql.MoveFolder(move[0], move[1])
+			assert.Equal(t, wantErr, err)
+			assert.Equal(t, want, got)
+
+			for _, name := range []string{"alpha", "bravo", "golf", "echo", "foxtrot", "missing"} {
+				got, err := sql.GetAllChildFolders(orgID, name)
+				want, wantErr := memory.GetAllChildFolders(orgID, name)
+				assert.Equal(t, wantErr, err, name)
+				assert.ElementsMatch(t, want, got, name)
+			}
+			assert.Equal(t, memory.GetFoldersByOrgID(orgID), sql.GetFoldersByOrgID(orgID))
+		})
+	}
+}
+
+func Test_sqlstore_PersistsToFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "folders.db")
+
+	d, err := sqlstore.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, d.Insert(ctx, getTestingData()))
+	_, err = d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	require.NoError(t, d.Close())
+
+	d, err = sqlstore.OpenSQLite(ctx, path)
+	require.NoError(t, err)
+	defer d.Close()
+
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	children, err := d.GetAllChildFolders(orgID, "golf")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []folder.Folder{
+		{Name: "bravo", OrgId: orgID, Paths: "golf.bravo"},
+		{Name: "charlie", OrgId: orgID, Paths: "golf.bravo.charlie"},
+		{Name: "alphabet", OrgId: orgID, Paths: "golf.alphabet"},
+		{Name: "ünïcode", OrgId: orgID, Paths: "golf.alphabet.ünïcode"},
+	}, children)
+}
+
+func Test_sqlstore_DeleteFolder(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	d := openDriver(t, getTestingData())
+
+	n, err := d.DeleteFolder(ctx, orgID, "alpha")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Len(t, d.GetFoldersByOrgID(orgID), 3)
+
+	_, err = d.DeleteFolder(ctx, orgID, "foxtrot")
+	assert.ErrorIs(t, err, folder.ErrFolderNotInOrg)
+	_, err = d.DeleteFolder(ctx, orgID, "alpha")
+	assert.ErrorIs(t, err, folder.ErrFolderNotFound)
+}
+
+func Test_sqlstore_ClosedDatabase(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	d := openDriver(t, getTestingData())
+	require.NoError(t, d.Close())
+
+	_, err := d.MoveFolder("bravo", "golf")
+	assert.Error(t, err)
+	_, err = d.ListFolders(context.Background(), orgID)
+	assert.Error(t, err)
+	assert.Equal(t, []folder.Folder{}, d.GetFoldersByOrgID(orgID))
+}
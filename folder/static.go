@@ -3,11 +3,7 @@ package folder
 import (
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/fs"
-	"os"
-	"path/filepath"
-	"runtime"
+	"math/rand"
 
 	"github.com/gofrs/uuid"
 	"github.com/lucasepe/codename"
@@ -35,113 +31,152 @@ type Folder struct {
 	Paths string    `json:"paths"`
 }
 
-func GenerateData() []Folder {
-	rng, _ := codename.DefaultRNG()
-	tree := []Folder{}
+// Distribution picks a number for the generator, e.g. how many children a folder gets.
+type Distribution interface {
+	Sample(rng *rand.Rand) int
+}
 
-	for i := 0; i < MaxRootSet; i++ {
-		orgId := uuid.FromStringOrNil(DefaultOrgID)
-		if i%3 == 0 {
-			orgId = uuid.Must(uuid.NewV4())
-		}
+// Uniform picks any number from Min to Max inclusive with equal odds.
+type Uniform struct {
+	Min, Max int
+}
 
-		name := codename.Generate(rng, 0)
-
-		subtree := make(chan []Folder)
-		go func() {
-			subtree <- generateTree(1, []Folder{
-				{
-					Name:  name,
-					OrgId: orgId,
-					Paths: name,
-				},
-			})
-		}()
-		tree = append(tree, <-subtree...)
+func (u Uniform) Sample(rng *rand.Rand) int {
+	if u.Max <= u.Min {
+		return u.Min
 	}
+	return u.Min + rng.Intn(u.Max-u.Min+1)
+}
 
-	return tree
+// Fixed always picks the same number.
+type Fixed int
+
+func (f Fixed) Sample(*rand.Rand) int {
+	return int(f)
 }
 
-func generateTree(depth int, tree []Folder) []Folder {
-	rng, _ := codename.DefaultRNG()
+// GeneratorConfig controls the shape of the data made by GenerateData.
+// The same config, seed included, always generates exactly the same folders.
+type GeneratorConfig struct {
+	Seed int64
+	// number of orgs to generate, ignored when OrgIDs is set
+	Orgs int
+	// use these orgs instead of generating Orgs random ones
+	OrgIDs []uuid.UUID
+	// number of root folders in every org
+	RootsPerOrg int
+	// number of levels in each root's tree, sampled once per root, 1 is just the root
+	Depth Distribution
+	// number of children of each folder, sampled once per folder
+	FanOut Distribution
+	// never reuse a folder name anywhere in the generated set
+	UniqueNames bool
+}
 
-	if depth >= MaxDepth {
-		return tree
+// DefaultGeneratorConfig is roughly the shape of the original sample data:
+// MaxRootSet trees split over the default org and a generated one, MaxDepth
+// levels deep with 1 to MaxChild children per folder.
+func DefaultGeneratorConfig() GeneratorConfig {
+	return GeneratorConfig{
+		Seed:        1,
+		OrgIDs:      []uuid.UUID{uuid.FromStringOrNil(DefaultOrgID)},
+		Orgs:        2,
+		RootsPerOrg: MaxRootSet / 2,
+		Depth:       Fixed(MaxDepth),
+		FanOut:      Uniform{Min: 1, Max: MaxChild},
+		UniqueNames: true,
 	}
+}
 
-	for _, t := range tree {
-		numOfChild := rng.Int()%MaxChild + 1
-		for i := 0; i < numOfChild; i++ {
-			name := codename.Generate(rng, 0)
-
-			childTree := make(chan []Folder)
-			go func() {
-				childTree <- generateTree(depth+1, []Folder{
-					{
-						Name:  name,
-						OrgId: t.OrgId,
-						Paths: t.Paths + "." + name,
-					},
-				})
-			}()
-			tree = append(tree, <-childTree...)
-		}
-	}
+// how many times a unique name is retried before a random token is added to it
+const maxNameAttempts = 16
 
-	return tree
+type generator struct {
+	cfg  GeneratorConfig
+	rng  *rand.Rand
+	used map[string]bool
 }
 
-func MarshalJson(b interface{}) []byte {
-	s, _ := json.MarshalIndent(b, "", "\t")
+// GenerateData builds random folder trees from cfg. Nothing but the seeded rng
+// feeds the output, so it is reproducible and safe to regenerate fixtures with.
+func GenerateData(cfg GeneratorConfig) []Folder {
+	g := &generator{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), used: map[string]bool{}}
+	if cfg.Depth == nil {
+		g.cfg.Depth = Fixed(MaxDepth)
+	}
+	if cfg.FanOut == nil {
+		g.cfg.FanOut = Uniform{Min: 1, Max: MaxChild}
+	}
 
-	return s
-}
+	tree := []Folder{}
+	for _, orgID := range g.orgIDs() {
+		for i := 0; i < cfg.RootsPerOrg; i++ {
+			name := g.name()
+			root := Folder{Name: name, OrgId: orgID, Paths: name}
+			tree = g.generateTree(1, g.cfg.Depth.Sample(g.rng), append(tree, root), root)
+		}
+	}
 
-func PrettyPrint(b interface{}) {
-	s := MarshalJson(b)
-	fmt.Print(string(s))
+	return tree
 }
 
-func GetSampleData() []Folder {
-	_, filename, _, _ := runtime.Caller(0)
-	fmt.Println(filename)
-	basePath := filepath.Dir(filename)
-	filePath := filepath.Join(basePath, "sample.json")
-
-	fmt.Println(filePath)
+// orgIDs returns the configured orgs, or Orgs new ones drawn from the rng.
+// When OrgIDs is shorter than Orgs the rest are generated.
+func (g *generator) orgIDs() []uuid.UUID {
+	ids := append([]uuid.UUID{}, g.cfg.OrgIDs...)
+	for len(ids) < g.cfg.Orgs {
+		var id uuid.UUID
+		g.rng.Read(id[:])
+		id.SetVersion(uuid.V4)
+		id.SetVariant(uuid.VariantRFC4122)
+		ids = append(ids, id)
+	}
+	return ids
+}
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		panic(err)
+func (g *generator) name() string {
+	if !g.cfg.UniqueNames {
+		return codename.Generate(g.rng, 0)
 	}
-	defer file.Close()
+	for i := 0; ; i++ {
+		name := codename.Generate(g.rng, 0)
+		if i >= maxNameAttempts {
+			name = codename.Generate(g.rng, 4)
+		}
+		if !g.used[name] {
+			g.used[name] = true
+			return name
+		}
+	}
+}
 
-	jsonByte, err := io.ReadAll(file)
-	if err != nil {
-		panic(err)
+// generateTree appends the descendants of parent, depth first, until maxDepth levels
+func (g *generator) generateTree(depth int, maxDepth int, tree []Folder, parent Folder) []Folder {
+	if depth >= maxDepth {
+		return tree
 	}
 
-	folders := []Folder{}
-	err = json.Unmarshal(jsonByte, &folders)
-	if err != nil {
-		panic(err)
+	numOfChild := g.cfg.FanOut.Sample(g.rng)
+	for i := 0; i < numOfChild; i++ {
+		name := g.name()
+		child := Folder{
+			Name:  name,
+			OrgId: parent.OrgId,
+			Paths: parent.Paths + "." + name,
+		}
+		tree = g.generateTree(depth+1, maxDepth, append(tree, child), child)
 	}
 
-	return folders
+	return tree
 }
 
-func WriteSampleData(data interface{}) {
-	b := MarshalJson(data)
-	_, filename, _, _ := runtime.Caller(0)
-	fmt.Println(filename)
-	basePath := filepath.Dir(filename)
-	filePath := filepath.Join(basePath, "sample.json")
+func MarshalJson(b interface{}) []byte {
+	s, _ := json.MarshalIndent(b, "", "\t")
 
-	fmt.Println(filePath)
+	return s
+}
 
-	err := os.WriteFile(filePath, b, fs.ModeType)
-	if err != nil {
-		panic(err)
-	}
+func PrettyPrint(b interface{}) {
+	s := MarshalJson(b)
+	fmt.Print(string(s))
 }
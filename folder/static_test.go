@@ -0,0 +1,116 @@
+package folder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_GenerateData_Deterministic(t *testing.T) {
+	t.Parallel()
+	cfg := folder.DefaultGeneratorConfig()
+	cfg.Seed = 1234
+
+	first := folder.MarshalJson(folder.GenerateData(cfg))
+	second := folder.MarshalJson(folder.GenerateData(cfg))
+	assert.Equal(t, string(first), string(second), "same seed must give byte identical output")
+
+	cfg.Seed = 1235
+	assert.NotEqual(t, string(first), string(folder.MarshalJson(folder.GenerateData(cfg))))
+}
+
+func Test_folder_GenerateData_Shape(t *testing.T) {
+	t.Parallel()
+	orgA := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	orgB := uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")
+	tests := [...]struct {
+		name      string
+		cfg       folder.GeneratorConfig
+		wantCount int
+		wantOrgs  []uuid.UUID
+	}{
+		{
+			name: "Single root only",
+			cfg: folder.GeneratorConfig{
+				OrgIDs: []uuid.UUID{orgA}, RootsPerOrg: 1, Depth: folder.Fixed(1), FanOut: folder.Fixed(3),
+			},
+			wantCount: 1,
+			wantOrgs:  []uuid.UUID{orgA},
+		},
+		{
+			name: "Full binary trees",
+			cfg: folder.GeneratorConfig{
+				OrgIDs: []uuid.UUID{orgA, orgB}, RootsPerOrg: 2, Depth: folder.Fixed(3), FanOut: folder.Fixed(2),
+			},
+			// 1 + 2 + 4 folders per root, 4 roots
+			wantCount: 28,
+			wantOrgs:  []uuid.UUID{orgA, orgB},
+		},
+		{
+			name: "Generated orgs",
+			cfg: folder.GeneratorConfig{
+				Orgs: 3, RootsPerOrg: 1, Depth: folder.Fixed(2), FanOut: folder.Fixed(1),
+			},
+			wantCount: 6,
+		},
+		{
+			name: "Explicit orgs topped up to Orgs",
+			cfg: folder.GeneratorConfig{
+				Orgs: 2, OrgIDs: []uuid.UUID{orgB}, RootsPerOrg: 1, Depth: folder.Fixed(1),
+			},
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := folder.GenerateData(tt.cfg)
+			require.Len(t, got, tt.wantCount)
+			require.NoError(t, checkTree(got))
+
+			orgs := []uuid.UUID{}
+			for _, f := range got {
+				if len(orgs) == 0 || orgs[len(orgs)-1] != f.OrgId {
+					orgs = append(orgs, f.OrgId)
+				}
+			}
+			if tt.wantOrgs != nil {
+				assert.Equal(t, tt.wantOrgs, orgs)
+			}
+			for _, org := range orgs {
+				assert.Equal(t, uuid.V4, org.Version())
+			}
+		})
+	}
+}
+
+func Test_folder_GenerateData_UniqueNames(t *testing.T) {
+	t.Parallel()
+	cfg := folder.GeneratorConfig{
+		Seed: 7, Orgs: 2, RootsPerOrg: 5, Depth: folder.Uniform{Min: 3, Max: 6}, FanOut: folder.Uniform{Min: 0, Max: 5},
+		UniqueNames: true,
+	}
+	got := folder.GenerateData(cfg)
+	require.NoError(t, checkTree(got))
+
+	seen := map[string]bool{}
+	for _, f := range got {
+		assert.False(t, seen[f.Name], "duplicate name %s", f.Name)
+		seen[f.Name] = true
+	}
+}
+
+func Test_folder_Distributions(t *testing.T) {
+	t.Parallel()
+	cfg := folder.GeneratorConfig{Seed: 3, Orgs: 1, RootsPerOrg: 50, Depth: folder.Uniform{Min: 1, Max: 3}, FanOut: folder.Fixed(1)}
+	depths := map[int]int{}
+	for _, f := range folder.GenerateData(cfg) {
+		depths[strings.Count(f.Paths, ".")+1]++
+	}
+	// every depth from 1 to 3 shows up, nothing deeper
+	assert.Len(t, depths, 3)
+	assert.Equal(t, 50, depths[1])
+}
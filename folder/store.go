@@ -0,0 +1,152 @@
+package folder
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// Store keeps folders across driver restarts. The driver loads the full set
+// once on start up and then hands every successful mutation to Apply before
+// changing its own copy, so a mutation the store rejects never happens.
+type Store interface {
+	// Load returns every stored folder
+	Load() ([]Folder, error)
+	// Apply persists a mutation
+	Apply(m Mutation) error
+	Close() error
+}
+
+// mutation ops
+const (
+	OpMove   = "move"
+	OpCreate = "create"
+	OpRename = "rename"
+	OpDelete = "delete"
+)
+
+// Mutation describes one change made through the driver.
+type Mutation struct {
+	Op string `json:"op"`
+	// arguments the driver method was called with, e.g. name and dst for a move
+	Args []string `json:"args,omitempty"`
+	// the new state of every folder the mutation touched, matched on org and name
+	Changed []Folder `json:"changed"`
+	// folders that no longer exist, matched on org and name and removed before Changed is applied
+	Removed []Folder `json:"removed,omitempty"`
+}
+
+var ErrStoreClosed = errors.New("Error: Store is closed")
+
+// NewDriverWithStore loads the folders from store and returns a driver
+// that persists its changes to it.
+func NewDriverWithStore(store Store) (IDriver, error) {
+	folders, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	f := newDriver(folders)
+	f.store = store
+	return f, nil
+}
+
+// folderKey identifies a folder within a store, names are only unique per org
+type folderKey struct {
+	org  uuid.UUID
+	name string
+}
+
+// applyMutation applies m to folders and returns the result.
+// index maps a folder key to its position in folders and is kept up to date.
+func applyMutation(folders []Folder, index map[folderKey]int, m Mutation) []Folder {
+	if len(m.Removed) > 0 {
+		removed := make(map[folderKey]bool, len(m.Removed))
+		for _, f := range m.Removed {
+			removed[folderKey{f.OrgId, f.Name}] = true
+		}
+		kept := folders[:0]
+		for _, f := range folders {
+			if !removed[folderKey{f.OrgId, f.Name}] {
+				kept = append(kept, f)
+			}
+		}
+		folders = kept
+		clear(index)
+		for key, i := range indexFolders(folders) {
+			index[key] = i
+		}
+	}
+
+	for _, changed := range m.Changed {
+		key := folderKey{changed.OrgId, changed.Name}
+		if i, ok := index[key]; ok {
+			folders[i] = changed
+			continue
+		}
+		index[key] = len(folders)
+		folders = append(folders, changed)
+	}
+	return folders
+}
+
+func indexFolders(folders []Folder) map[folderKey]int {
+	index := make(map[folderKey]int, len(folders))
+	for i, folder := range folders {
+		key := folderKey{folder.OrgId, folder.Name}
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+	return index
+}
+
+// MemoryStore is a Store that only keeps folders in memory, mostly useful in tests.
+type MemoryStore struct {
+	mu        sync.Mutex
+	folders   []Folder
+	index     map[folderKey]int
+	mutations []Mutation
+	closed    bool
+}
+
+// NewMemoryStore returns a store holding a copy of folders.
+func NewMemoryStore(folders []Folder) *MemoryStore {
+	folders = append([]Folder{}, folders...)
+	return &MemoryStore{folders: folders, index: indexFolders(folders)}
+}
+
+func (s *MemoryStore) Load() ([]Folder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	return append([]Folder{}, s.folders...), nil
+}
+
+func (s *MemoryStore) Apply(m Mutation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	s.folders = applyMutation(s.folders, s.index, m)
+	s.mutations = append(s.mutations, m)
+	return nil
+}
+
+// Mutations returns every mutation applied so far.
+func (s *MemoryStore) Mutations() []Mutation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mutation{}, s.mutations...)
+}
+
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
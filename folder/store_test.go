@@ -0,0 +1,217 @@
+package folder_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_MemoryStore(t *testing.T) {
+	t.Parallel()
+	store := folder.NewMemoryStore(GetTestingSampleData2())
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+
+	_, err = d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+
+	// failed moves never reach the store
+	_, err = d.MoveFolder("bravo", "foxtrot")
+	require.Error(t, err)
+
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	assert.Equal(t, []folder.Mutation{{
+		Op:   folder.OpMove,
+		Args: []string{"bravo", "golf"},
+		Changed: []folder.Folder{
+			{Name: "bravo", OrgId: orgID, Paths: "golf.bravo"},
+			{Name: "charlie", OrgId: orgID, Paths: "golf.bravo.charlie"},
+		},
+	}}, store.Mutations())
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	d2 := folder.NewDriver(loaded)
+	children, err := d2.GetAllChildFolders(orgID, "golf")
+	require.NoError(t, err)
+	assert.Len(t, children, 2)
+
+	// a closed store rejects moves and the driver is left untouched
+	require.NoError(t, store.Close())
+	_, err = d.MoveFolder("bravo", "delta")
+	assert.ErrorIs(t, err, folder.ErrStoreClosed)
+	children, err = d.GetAllChildFolders(orgID, "golf")
+	require.NoError(t, err)
+	assert.Len(t, children, 2)
+}
+
+// openFileDriver opens a file store in dir seeded with the move test data
+func openFileDriver(t *testing.T, dir string, opts folder.FileStoreOptions) (*folder.FileStore, folder.IDriver) {
+	t.Helper()
+	store, err := folder.OpenFileStore(dir, GetTestingSampleData2(), opts)
+	require.NoError(t, err)
+	d, err := folder.NewDriverWithStore(store)
+	require.NoError(t, err)
+	return store, d
+}
+
+func orgPaths(t *testing.T, d folder.IDriver) map[string]string {
+	t.Helper()
+	paths := map[string]string{}
+	for _, orgID := range []string{"38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "c1556e17-b7c0-45a3-a6ae-9546248fb17a"} {
+		for _, f := range d.GetFoldersByOrgID(uuid.FromStringOrNil(orgID)) {
+			paths[f.Name] = f.Paths
+		}
+	}
+	return paths
+}
+
+func Test_folder_FileStore_SurvivesRestart(t *testing.T) {
+	t.Parallel()
+	for _, compactEvery := range []int{0, 1, 2} {
+		dir := t.TempDir()
+		store, d := openFileDriver(t, dir, folder.FileStoreOptions{CompactEvery: compactEvery})
+		_, err := d.MoveFolder("bravo", "golf")
+		require.NoError(t, err)
+		_, err = d.MoveFolder("delta", "charlie")
+		require.NoError(t, err)
+		_, err = d.MoveFolder("alpha", "golf")
+		require.NoError(t, err)
+		want := orgPaths(t, d)
+		require.NoError(t, store.Close())
+
+		store, d = openFileDriver(t, dir, folder.FileStoreOptions{})
+		assert.Equal(t, want, orgPaths(t, d), "compactEvery=%d", compactEvery)
+		assert.Equal(t, "golf.bravo.charlie.delta.echo", want["echo"])
+		require.NoError(t, store.Close())
+	}
+}
+
+func Test_folder_FileStore_TornFinalRecord(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, d := openFileDriver(t, dir, folder.FileStoreOptions{})
+	_, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	afterFirst := orgPaths(t, d)
+
+	journalPath := filepath.Join(dir, "journal.ndjson")
+	info, err := os.Stat(journalPath)
+	require.NoError(t, err)
+	firstLen := info.Size()
+
+	_, err = d.MoveFolder("delta", "charlie")
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	full, err := os.ReadFile(journalPath)
+	require.NoError(t, err)
+
+	// simulate a crash at every byte of the second record
+	for cut := firstLen; cut < int64(len(full)); cut++ {
+		crashDir := t.TempDir()
+		copyFile(t, filepath.Join(dir, "snapshot.json"), filepath.Join(crashDir, "snapshot.json"))
+		require.NoError(t, os.WriteFile(filepath.Join(crashDir, "journal.ndjson"), full[:cut], folder.FileMode))
+
+		store, d := openFileDriver(t, crashDir, folder.FileStoreOptions{})
+		require.Equal(t, afterFirst, orgPaths(t, d), "cut at %d", cut)
+
+		// the torn tail is cut off so new records append cleanly
+		_, err = d.MoveFolder("golf", "alpha")
+		require.NoError(t, err)
+		want := orgPaths(t, d)
+		require.NoError(t, store.Close())
+
+		store, d = openFileDriver(t, crashDir, folder.FileStoreOptions{})
+		require.Equal(t, want, orgPaths(t, d), "cut at %d", cut)
+		require.NoError(t, store.Close())
+	}
+}
+
+func Test_folder_FileStore_CompactionFails(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, d := openFileDriver(t, dir, folder.FileStoreOptions{CompactEvery: 1})
+	// the new snapshot can't replace a directory
+	snapshot := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, os.Remove(snapshot))
+	require.NoError(t, os.Mkdir(snapshot, 0o755))
+
+	_, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	journal, err := os.ReadFile(filepath.Join(dir, "journal.ndjson"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, journal)
+
+	// the next move compacts both
+	require.NoError(t, os.Remove(snapshot))
+	_, err = d.MoveFolder("echo", "golf")
+	require.NoError(t, err)
+	journal, err = os.ReadFile(filepath.Join(dir, "journal.ndjson"))
+	require.NoError(t, err)
+	assert.Empty(t, journal)
+	want := orgPaths(t, d)
+	require.NoError(t, store.Close())
+
+	_, reopened := openFileDriver(t, dir, folder.FileStoreOptions{})
+	assert.Equal(t, want, orgPaths(t, reopened))
+	assert.Equal(t, "golf.bravo.charlie", want["charlie"])
+}
+
+func Test_folder_FileStore_CrashDuringCompaction(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, d := openFileDriver(t, dir, folder.FileStoreOptions{})
+	_, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("delta", "charlie")
+	require.NoError(t, err)
+	want := orgPaths(t, d)
+
+	journal, err := os.ReadFile(filepath.Join(dir, "journal.ndjson"))
+	require.NoError(t, err)
+	require.NoError(t, store.Compact())
+	require.NoError(t, store.Close())
+
+	// crash after the snapshot was renamed into place but before the journal was emptied
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "journal.ndjson"), journal, folder.FileMode))
+	// and a temp file from an earlier snapshot that never got renamed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".snapshot.json.tmp-123"), []byte(`{"seq": 9`), folder.FileMode))
+
+	store, d = openFileDriver(t, dir, folder.FileStoreOptions{})
+	assert.Equal(t, want, orgPaths(t, d))
+	require.NoError(t, store.Close())
+}
+
+func Test_folder_FileStore_CorruptRecord(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, d := openFileDriver(t, dir, folder.FileStoreOptions{})
+	_, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("delta", "charlie")
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	journalPath := filepath.Join(dir, "journal.ndjson")
+	journal, err := os.ReadFile(journalPath)
+	require.NoError(t, err)
+	// flip a byte inside the first record, a record follows so this can't be a torn write
+	journal[20] ^= 0x01
+	require.NoError(t, os.WriteFile(journalPath, journal, folder.FileMode))
+
+	_, err = folder.OpenFileStore(dir, nil, folder.FileStoreOptions{})
+	assert.ErrorIs(t, err, folder.ErrCorruptJournal)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	b, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, b, folder.FileMode))
+}
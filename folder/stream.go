@@ -0,0 +1,223 @@
+package folder
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Streaming helpers for folder dumps that are too big to read in one go.
+// Both a JSON array (the sample.json layout) and newline-delimited JSON
+// (one folder object per line) are accepted, the format is picked from the
+// first non-whitespace byte of the input.
+
+// how often the progress callback fires by default
+const DefaultProgressEvery = 10000
+
+type Format int
+
+const (
+	// FormatJSON is a single JSON array of folders, like sample.json
+	FormatJSON Format = iota
+	// FormatNDJSON is one JSON folder object per line
+	FormatNDJSON
+)
+
+// Progress is passed to StreamOptions.OnProgress while decoding.
+type Progress struct {
+	// number of folders decoded so far
+	Folders int
+	// number of input bytes consumed so far
+	Bytes int64
+}
+
+type StreamOptions struct {
+	// OnProgress is called every ProgressEvery folders and once more at the end
+	OnProgress func(Progress)
+	// defaults to DefaultProgressEvery when zero
+	ProgressEvery int
+}
+
+// StreamFolders decodes folders from r one at a time and hands each one to fn.
+// Returning an error from fn stops decoding and that error is returned.
+func StreamFolders(r io.Reader, opts StreamOptions, fn func(Folder) error) error {
+	br := bufio.NewReader(r)
+
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		// empty input is an empty set of folders
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	every := opts.ProgressEvery
+	if every <= 0 {
+		every = DefaultProgressEvery
+	}
+
+	dec := json.NewDecoder(br)
+	count := 0
+	report := func() {
+		if opts.OnProgress != nil {
+			opts.OnProgress(Progress{Folders: count, Bytes: dec.InputOffset()})
+		}
+	}
+	emit := func(folder Folder) error {
+		if err := fn(folder); err != nil {
+			return err
+		}
+		count++
+		if count%every == 0 {
+			report()
+		}
+		return nil
+	}
+
+	switch first {
+	case '[':
+		// consume the opening bracket then decode elements one by one
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		for dec.More() {
+			var folder Folder
+			if err := dec.Decode(&folder); err != nil {
+				return fmt.Errorf("Error: decoding folder %d: %w", count, err)
+			}
+			if err := emit(folder); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case '{':
+		// json.Decoder reads whitespace separated values, which covers one object per line
+		for {
+			var folder Folder
+			err := dec.Decode(&folder)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("Error: decoding folder %d: %w", count, err)
+			}
+			if err := emit(folder); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Error: expected a JSON array or object, found %q", first)
+	}
+
+	report()
+	return nil
+}
+
+// LoadDriver streams folders from r straight into a driver, building its
+// indexes as each folder arrives instead of decoding a full slice first.
+func LoadDriver(r io.Reader, opts StreamOptions) (IDriver, error) {
+	b := NewBuilder()
+	err := StreamFolders(r, opts, func(folder Folder) error {
+		b.Add(folder)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Driver(), nil
+}
+
+// peekNonSpace skips leading whitespace and returns the next byte without consuming it
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+// Encoder writes folders one at a time in either format.
+// Close must be called to terminate a JSON array.
+type Encoder struct {
+	w      *bufio.Writer
+	format Format
+	count  int
+	closed bool
+}
+
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w), format: format}
+}
+
+func (e *Encoder) Encode(folder Folder) error {
+	if e.closed {
+		return errors.New("Error: encoder is closed")
+	}
+
+	b, err := json.Marshal(folder)
+	if err != nil {
+		return err
+	}
+
+	if e.format == FormatJSON {
+		sep := ",\n\t"
+		if e.count == 0 {
+			sep = "[\n\t"
+		}
+		if _, err := e.w.WriteString(sep); err != nil {
+			return err
+		}
+	}
+	if _, err := e.w.Write(b); err != nil {
+		return err
+	}
+	if e.format == FormatNDJSON {
+		if err := e.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	e.count++
+	return nil
+}
+
+// Close finishes the output and flushes it, it does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	if e.format == FormatJSON {
+		end := "\n]\n"
+		if e.count == 0 {
+			end = "[]\n"
+		}
+		if _, err := e.w.WriteString(end); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// EncodeFolders writes every folder with a new Encoder and closes it.
+func EncodeFolders(w io.Writer, format Format, folders []Folder) error {
+	enc := NewEncoder(w, format)
+	for _, folder := range folders {
+		if err := enc.Encode(folder); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
@@ -0,0 +1,154 @@
+package folder_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_folder_StreamFolders(t *testing.T) {
+	t.Parallel()
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	want := []folder.Folder{
+		{Name: "alpha", OrgId: orgID, Paths: "alpha"},
+		{Name: "bravo", OrgId: orgID, Paths: "alpha.bravo"},
+	}
+	tests := [...]struct {
+		name    string
+		input   string
+		want    []folder.Folder
+		wantErr bool
+	}{
+		{
+			name: "JSON array",
+			input: `[
+				{"name": "alpha", "org_id": "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "paths": "alpha"},
+				{"name": "bravo", "org_id": "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "paths": "alpha.bravo"}
+			]`,
+			want: want,
+		},
+		{
+			name: "Newline delimited JSON",
+			input: `{"name": "alpha", "org_id": "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "paths": "alpha"}
+{"name": "bravo", "org_id": "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "paths": "alpha.bravo"}
+`,
+			want: want,
+		},
+		{
+			name:  "Empty input",
+			input: "  \n",
+			want:  nil,
+		},
+		{
+			name:  "Empty array",
+			input: "[]",
+			want:  nil,
+		},
+		{
+			name:    "Not JSON",
+			input:   "alpha",
+			wantErr: true,
+		},
+		{
+			name:    "Truncated array",
+			input:   `[{"name": "alpha", "org_id": "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7", "paths": "alpha"},`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []folder.Folder
+			err := folder.StreamFolders(strings.NewReader(tt.input), folder.StreamOptions{}, func(f folder.Folder) error {
+				got = append(got, f)
+				return nil
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_folder_StreamFolders_Progress(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, folder.EncodeFolders(&buf, folder.FormatNDJSON, GetTestingSampleData2()))
+
+	var reports []folder.Progress
+	err := folder.StreamFolders(&buf, folder.StreamOptions{
+		ProgressEvery: 3,
+		OnProgress:    func(p folder.Progress) { reports = append(reports, p) },
+	}, func(folder.Folder) error { return nil })
+	require.NoError(t, err)
+
+	// every 3rd folder plus the final report
+	require.Len(t, reports, 3)
+	assert.Equal(t, 3, reports[0].Folders)
+	assert.Equal(t, 6, reports[1].Folders)
+	assert.Equal(t, 7, reports[2].Folders)
+	assert.Less(t, reports[0].Bytes, reports[2].Bytes)
+}
+
+func Test_folder_StreamFolders_CallbackError(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, folder.EncodeFolders(&buf, folder.FormatJSON, GetTestingSampleData2()))
+
+	stop := errors.New("stop")
+	seen := 0
+	err := folder.StreamFolders(&buf, folder.StreamOptions{}, func(folder.Folder) error {
+		seen++
+		if seen == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 2, seen)
+}
+
+func Test_folder_Encoder_RoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, format := range []folder.Format{folder.FormatJSON, folder.FormatNDJSON} {
+		var buf bytes.Buffer
+		require.NoError(t, folder.EncodeFolders(&buf, format, GetTestingSampleData2()))
+
+		var got []folder.Folder
+		require.NoError(t, folder.StreamFolders(&buf, folder.StreamOptions{}, func(f folder.Folder) error {
+			got = append(got, f)
+			return nil
+		}))
+		assert.Equal(t, GetTestingSampleData2(), got)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, folder.EncodeFolders(&buf, folder.FormatJSON, nil))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func Test_folder_LoadDriver(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, folder.EncodeFolders(&buf, folder.FormatNDJSON, GetTestingSampleData2()))
+
+	d, err := folder.LoadDriver(&buf, folder.StreamOptions{})
+	require.NoError(t, err)
+
+	orgID := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	got, err := d.GetAllChildFolders(orgID, "delta")
+	require.NoError(t, err)
+	assert.Equal(t, []folder.Folder{{Name: "echo", OrgId: orgID, Paths: "alpha.delta.echo"}}, got)
+
+	moved, err := d.MoveFolder("bravo", "golf")
+	require.NoError(t, err)
+	assert.Len(t, moved, 7)
+}
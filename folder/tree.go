@@ -0,0 +1,154 @@
+package folder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// A tree(1) style printer, one block per org:
+//
+//	38b9879b-f73b-4b0e-b9d9-4fc4c23643a7
+//	├── alpha (2)
+//	│   ├── bravo (1)
+//	│   │   └── charlie
+//	│   └── delta
+//	└── golf
+
+type TreeOptions struct {
+	// folders deeper than this are not printed, 0 means no limit
+	MaxDepth int
+	// print the number of direct children after each folder that has any
+	ShowCount bool
+	// use |-- and `-- instead of box drawing characters
+	ASCII bool
+	// order siblings by name instead of the order they appear in
+	Sorted bool
+}
+
+type treeGlyphs struct {
+	branch, last, pipe, space string
+}
+
+var (
+	unicodeGlyphs = treeGlyphs{branch: "├── ", last: "└── ", pipe: "│   ", space: "    "}
+	asciiGlyphs   = treeGlyphs{branch: "|-- ", last: "`-- ", pipe: "|   ", space: "    "}
+)
+
+// treeNode is one folder and the folders directly below it
+type treeNode struct {
+	name     string
+	children []*treeNode
+}
+
+// WriteTree prints the hierarchy of every org in folders to w. Orgs are printed
+// in the order they first appear and siblings keep their order from folders.
+func WriteTree(w io.Writer, folders []Folder, opts TreeOptions) error {
+	glyphs := unicodeGlyphs
+	if opts.ASCII {
+		glyphs = asciiGlyphs
+	}
+
+	orgs, roots := buildForest(folders)
+	for i, orgID := range orgs {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, orgID); err != nil {
+			return err
+		}
+		if opts.Sorted {
+			sortForest(roots[orgID])
+		}
+		if err := writeTreeLevel(w, roots[orgID], "", 1, glyphs, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderTree is WriteTree into a string.
+func RenderTree(folders []Folder, opts TreeOptions) string {
+	var b strings.Builder
+	// writes to a strings.Builder never fail
+	_ = WriteTree(&b, folders, opts)
+	return b.String()
+}
+
+// PrintTree prints the hierarchy to stdout with the default options.
+func PrintTree(folders []Folder) {
+	fmt.Print(RenderTree(folders, TreeOptions{ShowCount: true}))
+}
+
+func writeTreeLevel(w io.Writer, nodes []*treeNode, prefix string, depth int, glyphs treeGlyphs, opts TreeOptions) error {
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return nil
+	}
+
+	for i, node := range nodes {
+		connector, indent := glyphs.branch, glyphs.pipe
+		if i == len(nodes)-1 {
+			connector, indent = glyphs.last, glyphs.space
+		}
+
+		line := prefix + connector + node.name
+		if opts.ShowCount && len(node.children) > 0 {
+			line += fmt.Sprintf(" (%d)", len(node.children))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		if err := writeTreeLevel(w, node.children, prefix+indent, depth+1, glyphs, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildForest links folders into one tree per org using their paths.
+// Folders whose parent is missing are treated as roots so nothing is dropped.
+func buildForest(folders []Folder) ([]uuid.UUID, map[uuid.UUID][]*treeNode) {
+	type key struct {
+		org  uuid.UUID
+		path string
+	}
+
+	nodes := make(map[key]*treeNode, len(folders))
+	for _, folder := range folders {
+		nodes[key{folder.OrgId, folder.Paths}] = &treeNode{name: folder.Name}
+	}
+
+	orgs := []uuid.UUID{}
+	roots := map[uuid.UUID][]*treeNode{}
+	for _, folder := range folders {
+		if _, ok := roots[folder.OrgId]; !ok {
+			orgs = append(orgs, folder.OrgId)
+			roots[folder.OrgId] = nil
+		}
+
+		node := nodes[key{folder.OrgId, folder.Paths}]
+		if dot := strings.LastIndex(folder.Paths, "."); dot >= 0 {
+			if parent, ok := nodes[key{folder.OrgId, folder.Paths[:dot]}]; ok {
+				parent.children = append(parent.children, node)
+				continue
+			}
+		}
+		roots[folder.OrgId] = append(roots[folder.OrgId], node)
+	}
+
+	return orgs, roots
+}
+
+// sortForest orders siblings by name at every level
+func sortForest(nodes []*treeNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+	for _, node := range nodes {
+		sortForest(node.children)
+	}
+}
@@ -0,0 +1,60 @@
+package folder_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// run `go test ./folder -update` to rewrite the golden files after an intended output change
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// assertGolden compares got with testdata/<name>.golden
+func assertGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), folder.FileMode))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+func Test_folder_RenderTree(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name    string
+		opts    folder.TreeOptions
+		reverse bool
+	}{
+		{name: "tree_unicode", opts: folder.TreeOptions{}},
+		{name: "tree_ascii_counts", opts: folder.TreeOptions{ASCII: true, ShowCount: true}},
+		{name: "tree_max_depth", opts: folder.TreeOptions{MaxDepth: 2}},
+		{name: "tree_reversed", opts: folder.TreeOptions{}, reverse: true},
+		{name: "tree_sorted", opts: folder.TreeOptions{Sorted: true, ShowCount: true}, reverse: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folders := GetTestingSampleData2()
+			if tt.reverse {
+				slices.Reverse(folders)
+			}
+			assertGolden(t, tt.name, folder.RenderTree(folders, tt.opts))
+		})
+	}
+}
+
+func Test_folder_RenderTree_MissingParent(t *testing.T) {
+	t.Parallel()
+	// a folder whose parent isn't in the set is printed as a root instead of being dropped
+	folders := GetTestingSampleData1()[2:3]
+	assert.Equal(t, "38b9879b-f73b-4b0e-b9d9-4fc4c23643a7\n└── charlie\n", folder.RenderTree(folders, folder.TreeOptions{}))
+	assert.Equal(t, "", folder.RenderTree(nil, folder.TreeOptions{}))
+}
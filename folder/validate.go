@@ -0,0 +1,99 @@
+package folder
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// MaxLabelLength is the longest label ltree accepts.
+const MaxLabelLength = 1000
+
+// LabelPattern matches a valid ltree label. Folder names are labels, so they
+// must match it too. Hyphens are only valid in labels from PostgreSQL 16 on,
+// older servers accept letters, digits and underscores. They are allowed here
+// because the folder names in use have them.
+const LabelPattern = `^[A-Za-z0-9_-]+$`
+
+var labelRegexp = regexp.MustCompile(LabelPattern)
+
+// IsValidLabel reports whether s can be used as an ltree label and so as a folder name.
+func IsValidLabel(s string) bool {
+	return len(s) <= MaxLabelLength && labelRegexp.MatchString(s)
+}
+
+// ValidationError is one problem found by Validate.
+type ValidationError struct {
+	// position of the folder in the validated slice
+	Index  int
+	Folder Folder
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("folder %d (%q): %s", e.Index, e.Folder.Name, e.Reason)
+}
+
+// Validate checks the invariants the driver relies on and returns every
+// problem it finds, in folder order. An empty result means the set is valid.
+//   - names are valid ltree labels and unique across all orgs
+//   - every folder has an org
+//   - the last label of a path is the folder's name
+//   - the parent of every path exists in the same org
+//   - paths are unique within an org
+func Validate(folders []Folder) []ValidationError {
+	problems := []ValidationError{}
+	report := func(i int, format string, args ...any) {
+		problems = append(problems, ValidationError{Index: i, Folder: folders[i], Reason: fmt.Sprintf(format, args...)})
+	}
+
+	type pathKey struct {
+		org  uuid.UUID
+		path string
+	}
+	names := make(map[string]int, len(folders))
+	paths := make(map[pathKey]int, len(folders))
+	for i, folder := range folders {
+		if prev, ok := names[folder.Name]; ok {
+			report(i, "name is already used by folder %d", prev)
+		} else {
+			names[folder.Name] = i
+		}
+		if prev, ok := paths[pathKey{folder.OrgId, folder.Paths}]; ok {
+			report(i, "path %q is already used by folder %d", folder.Paths, prev)
+		} else {
+			paths[pathKey{folder.OrgId, folder.Paths}] = i
+		}
+	}
+
+	for i, folder := range folders {
+		switch {
+		case folder.Name == "":
+			report(i, "name is empty")
+		case strings.Contains(folder.Name, "."):
+			report(i, "name contains \".\"")
+		case !IsValidLabel(folder.Name):
+			report(i, "name is not a valid ltree label")
+		}
+		if folder.OrgId == uuid.Nil {
+			report(i, "org_id is missing")
+		}
+
+		dot := strings.LastIndex(folder.Paths, ".")
+		if folder.Paths[dot+1:] != folder.Name {
+			report(i, "path %q does not end with the folder's name", folder.Paths)
+		}
+		if dot >= 0 {
+			if _, ok := paths[pathKey{folder.OrgId, folder.Paths[:dot]}]; !ok {
+				report(i, "parent %q does not exist in org %s", folder.Paths[:dot], folder.OrgId)
+			}
+		}
+	}
+
+	// by folder, keeping the order they were found in for each folder
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Index < problems[j].Index })
+	return problems
+}
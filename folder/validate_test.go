@@ -0,0 +1,98 @@
+package folder_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_folder_Validate(t *testing.T) {
+	t.Parallel()
+	orgA := uuid.FromStringOrNil("38b9879b-f73b-4b0e-b9d9-4fc4c23643a7")
+	orgB := uuid.FromStringOrNil("c1556e17-b7c0-45a3-a6ae-9546248fb17a")
+
+	tests := [...]struct {
+		name    string
+		folders []folder.Folder
+		want    []string
+	}{
+		{
+			name:    "sample data is valid",
+			folders: GetTestingSampleData1(),
+			want:    []string{},
+		},
+		{
+			name:    "empty set is valid",
+			folders: []folder.Folder{},
+			want:    []string{},
+		},
+		{
+			name: "duplicate name across orgs",
+			folders: []folder.Folder{
+				{Name: "alpha", OrgId: orgA, Paths: "alpha"},
+				{Name: "alpha", OrgId: orgB, Paths: "alpha"},
+			},
+			want: []string{`folder 1 ("alpha"): name is already used by folder 0`},
+		},
+		{
+			name: "missing parent, the same path in another org doesn't count",
+			folders: []folder.Folder{
+				{Name: "alpha", OrgId: orgA, Paths: "alpha"},
+				{Name: "bravo", OrgId: orgB, Paths: "alpha.bravo"},
+			},
+			want: []string{`folder 1 ("bravo"): parent "alpha" does not exist in org c1556e17-b7c0-45a3-a6ae-9546248fb17a`},
+		},
+		{
+			name: "path does not end with the name",
+			folders: []folder.Folder{
+				{Name: "alpha", OrgId: orgA, Paths: "alpha"},
+				{Name: "bravo", OrgId: orgA, Paths: "alpha.charlie"},
+			},
+			want: []string{`folder 1 ("bravo"): path "alpha.charlie" does not end with the folder's name`},
+		},
+		{
+			name: "bad names and missing org, problems are grouped by folder",
+			folders: []folder.Folder{
+				{Name: "", OrgId: orgA, Paths: ""},
+				{Name: "a.b", OrgId: uuid.Nil, Paths: "a.b"},
+			},
+			want: []string{
+				`folder 0 (""): name is empty`,
+				`folder 1 ("a.b"): name contains "."`,
+				`folder 1 ("a.b"): org_id is missing`,
+				`folder 1 ("a.b"): path "a.b" does not end with the folder's name`,
+				`folder 1 ("a.b"): parent "a" does not exist in org 00000000-0000-0000-0000-000000000000`,
+			},
+		},
+		{
+			name: "name that is not an ltree label",
+			folders: []folder.Folder{
+				{Name: "two words", OrgId: orgA, Paths: "two words"},
+			},
+			want: []string{`folder 0 ("two words"): name is not a valid ltree label`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := []string{}
+			for _, problem := range folder.Validate(tt.folders) {
+				got = append(got, problem.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_folder_IsValidLabel(t *testing.T) {
+	t.Parallel()
+	for _, label := range []string{"alpha", "noble-vixen", "snake_case", "A1", strings.Repeat("a", folder.MaxLabelLength)} {
+		assert.True(t, folder.IsValidLabel(label), label)
+	}
+	for _, label := range []string{"", "a.b", "two words", "ünïcode", "a/b", strings.Repeat("a", folder.MaxLabelLength+1)} {
+		assert.False(t, folder.IsValidLabel(label), label)
+	}
+}
@@ -0,0 +1,395 @@
+// Package webhook POSTs the folder moves of an org to the URLs integrations
+// registered for it.
+//
+// Every delivery is a JSON Payload with the folder.Event of the move, signed
+// with the secret of the webhook:
+//
+//	X-Webhook-Id:        the webhook
+//	X-Webhook-Delivery:  the same for every attempt of one event, for deduplication
+//	X-Webhook-Timestamp: unix seconds of the attempt
+//	X-Webhook-Signature: sha256=hex(HMAC-SHA256(secret, timestamp + "." + body))
+//
+// Receivers check the signature with Verify. A delivery succeeds on any 2xx.
+// Network errors, 408, 429 and 5xx are retried with exponential backoff, other
+// statuses are not. Events that still can't be delivered go to the dead-letter
+// list. The events of a webhook are delivered one at a time in Seq order, so
+// a failing receiver delays the later events rather than reordering them.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultTimeout        = 10 * time.Second
+	// DefaultLogSize is how many attempts of each webhook the delivery log keeps.
+	DefaultLogSize = 100
+)
+
+var (
+	ErrWebhookNotFound = errors.New("Error: Webhook does not exist")
+	ErrInvalidURL      = errors.New("Error: Webhook URL must be an absolute http or https URL")
+	ErrInvalidOrg      = errors.New("Error: Webhook needs an org")
+)
+
+type Options struct {
+	// sends the requests, an http.Client with Timeout when nil
+	Client *http.Client
+	// timeout of the default client, DefaultTimeout when zero
+	Timeout time.Duration
+	// attempts per event including the first, DefaultMaxAttempts when zero
+	MaxAttempts int
+	// wait before the first retry, doubled for each one after it up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// attempts kept per webhook, DefaultLogSize when zero
+	LogSize int
+	// events held for a webhook while it retries, folder.DefaultEventBuffer when zero
+	Buffer int
+}
+
+// Backoff is how long a delivery waits after its attempt-th failed attempt.
+func (o Options) Backoff(attempt int) time.Duration {
+	delay := o.InitialBackoff
+	for i := 1; i < attempt && delay < o.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, o.MaxBackoff)
+}
+
+// Webhook is a URL the folder moves of an org are POSTed to.
+type Webhook struct {
+	ID    uuid.UUID `json:"id"`
+	OrgID uuid.UUID `json:"org_id"`
+	URL   string    `json:"url"`
+	// the HMAC key of the signatures, generated by Add when empty
+	Secret string `json:"secret"`
+}
+
+// Payload is the body of a delivery.
+type Payload struct {
+	DeliveryID uuid.UUID    `json:"delivery_id"`
+	WebhookID  uuid.UUID    `json:"webhook_id"`
+	Event      folder.Event `json:"event"`
+}
+
+// Attempt is one entry of the delivery log.
+type Attempt struct {
+	WebhookID  uuid.UUID     `json:"webhook_id"`
+	DeliveryID uuid.UUID     `json:"delivery_id"`
+	Seq        uint64        `json:"seq"`
+	Attempt    int           `json:"attempt"`
+	Time       time.Time     `json:"time"`
+	Duration   time.Duration `json:"duration"`
+	// 0 when there was no response
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DeadLetter is an event that was given up on.
+type DeadLetter struct {
+	WebhookID  uuid.UUID    `json:"webhook_id"`
+	DeliveryID uuid.UUID    `json:"delivery_id"`
+	Event      folder.Event `json:"event"`
+	Attempts   int          `json:"attempts"`
+	// the error of the last attempt
+	Error string    `json:"error"`
+	Time  time.Time `json:"time"`
+}
+
+// Dispatcher delivers the moves published by a folder.Notifier to webhooks.
+// It is safe for concurrent use.
+type Dispatcher struct {
+	notifier folder.Notifier
+	opts     Options
+
+	mu    sync.Mutex
+	hooks map[uuid.UUID]*hook
+	// attempts of each webhook, oldest first
+	logs map[uuid.UUID][]Attempt
+	dead []DeadLetter
+	wg   sync.WaitGroup
+}
+
+// hook is a webhook and the goroutine delivering to it
+type hook struct {
+	Webhook
+	cancel context.CancelFunc
+}
+
+func New(notifier folder.Notifier, opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: opts.Timeout}
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.LogSize <= 0 {
+		opts.LogSize = DefaultLogSize
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = folder.DefaultEventBuffer
+	}
+	return &Dispatcher{
+		notifier: notifier,
+		opts:     opts,
+		hooks:    map[uuid.UUID]*hook{},
+		logs:     map[uuid.UUID][]Attempt{},
+	}
+}
+
+// Add registers w and starts delivering the moves of its org made from now
+// on. It returns w with its ID and Secret filled in.
+func (d *Dispatcher) Add(w Webhook) (Webhook, error) {
+	if w.OrgID == uuid.Nil {
+		return Webhook{}, ErrInvalidOrg
+	}
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, ErrInvalidURL
+	}
+	var err error
+	if w.ID, err = uuid.NewV4(); err != nil {
+		return Webhook{}, err
+	}
+	if w.Secret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return Webhook{}, err
+		}
+		w.Secret = hex.EncodeToString(key)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &hook{Webhook: w, cancel: cancel}
+	// subscribed before Add returns, so no move after it is missed
+	sub := d.notifier.Subscribe(folder.SubscribeOptions{OrgID: w.OrgID, Buffer: d.opts.Buffer})
+
+	d.mu.Lock()
+	d.hooks[w.ID] = h
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go d.run(ctx, h, sub)
+	return w, nil
+}
+
+// Remove stops the deliveries of a webhook, an attempt in flight is cancelled.
+// Its log and dead letters are kept.
+func (d *Dispatcher) Remove(id uuid.UUID) error {
+	d.mu.Lock()
+	h, ok := d.hooks[id]
+	delete(d.hooks, id)
+	d.mu.Unlock()
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	h.cancel()
+	return nil
+}
+
+// Webhooks returns the webhooks of orgID, of every org when uuid.Nil.
+func (d *Dispatcher) Webhooks(orgID uuid.UUID) []Webhook {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	res := []Webhook{}
+	for _, h := range d.hooks {
+		if orgID == uuid.Nil || h.OrgID == orgID {
+			res = append(res, h.Webhook)
+		}
+	}
+	return res
+}
+
+// Log returns the latest attempts to deliver to a webhook, oldest first.
+func (d *Dispatcher) Log(id uuid.UUID) []Attempt {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Attempt{}, d.logs[id]...)
+}
+
+// DeadLetters returns the events that were given up on, oldest first.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DeadLetter{}, d.dead...)
+}
+
+// Close stops every webhook and waits for their goroutines.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	for id, h := range d.hooks {
+		h.cancel()
+		delete(d.hooks, id)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+}
+
+// run delivers the moves of h until it is removed
+func (d *Dispatcher) run(ctx context.Context, h *hook, sub *folder.Subscription) {
+	defer d.wg.Done()
+	defer func() { sub.Close() }()
+
+	// where a new subscription resumes, from the start of the first one until
+	// an event arrives
+	last := sub.Start
+	for ctx.Err() == nil {
+		select {
+		case e, ok := <-sub.C:
+			if ok {
+				last = e.Seq
+				if e.Type == folder.FolderMoved {
+					d.deliver(ctx, h, e)
+				}
+				continue
+			}
+			if sub.Err() == nil {
+				// ended by the notifier, not because it fell behind
+				return
+			}
+			// fell behind while retrying, the history has the events since
+			sub = d.notifier.Subscribe(folder.SubscribeOptions{OrgID: h.OrgID, Buffer: d.opts.Buffer, After: last})
+			if sub.Missed {
+				d.record(Attempt{WebhookID: h.ID, Seq: last, Time: time.Now(), Error: fmt.Sprintf("Error: events after %d were lost while retrying", last)})
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver sends e to h until it succeeds, fails for good or h is removed
+func (d *Dispatcher) deliver(ctx context.Context, h *hook, e folder.Event) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	body, err := json.Marshal(Payload{DeliveryID: id, WebhookID: h.ID, Event: e})
+	if err != nil {
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		a := d.send(ctx, h, id, body)
+		a.Seq, a.Attempt = e.Seq, attempt
+		if ctx.Err() != nil {
+			// removed, the attempt didn't fail on the receiver's side
+			return
+		}
+		d.record(a)
+
+		if a.Error == "" {
+			return
+		}
+		permanent := a.StatusCode != 0 && !retryable(a.StatusCode)
+		if permanent || attempt == d.opts.MaxAttempts {
+			d.mu.Lock()
+			d.dead = append(d.dead, DeadLetter{WebhookID: h.ID, DeliveryID: id, Event: e, Attempts: attempt, Error: a.Error, Time: a.Time})
+			d.mu.Unlock()
+			return
+		}
+
+		timer := time.NewTimer(d.opts.Backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// send makes one attempt, the Attempt has an Error unless the receiver answered 2xx
+func (d *Dispatcher) send(ctx context.Context, h *hook, id uuid.UUID, body []byte) Attempt {
+	start := time.Now()
+	a := Attempt{WebhookID: h.ID, DeliveryID: id, Time: start}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "folder-webhook")
+	req.Header.Set("X-Webhook-Id", h.ID.String())
+	req.Header.Set("X-Webhook-Delivery", id.String())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", Sign(h.Secret, timestamp, body))
+
+	resp, err := d.opts.Client.Do(req)
+	a.Duration = time.Since(start)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	// drained so the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+
+	a.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		a.Error = "Error: receiver answered " + resp.Status
+	}
+	return a
+}
+
+// record adds a to the log of its webhook, dropping the oldest attempts past LogSize
+func (d *Dispatcher) record(a Attempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	log := append(d.logs[a.WebhookID], a)
+	if len(log) > d.opts.LogSize {
+		log = append([]Attempt{}, log[len(log)-d.opts.LogSize:]...)
+	}
+	d.logs[a.WebhookID] = log
+}
+
+// retryable reports whether a receiver answering status may accept the event later
+func retryable(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
+
+// Sign returns the X-Webhook-Signature of body sent at timestamp.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at timestamp,
+// in constant time. Receivers should also reject old timestamps to stop replays.
+func Verify(secret string, timestamp string, signature string, body []byte) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,346 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/georgechieng-sc/interns-2022/folder"
+	"github.com/georgechieng-sc/interns-2022/folder/foldertest"
+	"github.com/georgechieng-sc/interns-2022/folder/webhook"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delivery is a request the receiver got
+type delivery struct {
+	header  http.Header
+	body    []byte
+	payload webhook.Payload
+}
+
+// receiver answers with the statuses in order, then with 200, and sends every request to the channel
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan delivery) {
+	t.Helper()
+	ch := make(chan delivery, 16)
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		d := delivery{header: r.Header, body: body}
+		assert.NoError(t, json.Unmarshal(body, &d.payload))
+		ch <- d
+
+		mu.Lock()
+		status := http.StatusOK
+		if requests < len(statuses) {
+			status = statuses[requests]
+		}
+		requests++
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+func next(t *testing.T, ch <-chan delivery) delivery {
+	t.Helper()
+	select {
+	case d := <-ch:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery")
+		return delivery{}
+	}
+}
+
+func none(t *testing.T, ch <-chan delivery) {
+	t.Helper()
+	select {
+	case d := <-ch:
+		t.Fatalf("unexpected delivery of %v", d.payload.Event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// dispatcher returns a driver with the move data and a dispatcher for it that retries quickly
+func dispatcher(t *testing.T, opts webhook.Options) (folder.IDriver, *webhook.Dispatcher) {
+	t.Helper()
+	d := folder.NewDriver(foldertest.MoveData())
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Millisecond
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 3
+	}
+	w := webhook.New(d.(folder.Notifier), opts)
+	t.Cleanup(w.Close)
+	return d, w
+}
+
+// eventually waits for cond, deliveries are logged after the receiver answered
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	require.Eventually(t, cond, 5*time.Second, time.Millisecond)
+}
+
+func Test_webhook_Delivery(t *testing.T) {
+	t.Parallel()
+	d, w := dispatcher(t, webhook.Options{})
+	srv, ch := receiver(t)
+	hook, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srv.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+
+	// other changes and the moves of other orgs aren't delivered
+	_, err = d.(folder.Editor).CreateFolder(foldertest.OrgB, "hotel", "")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("hotel", "foxtrot")
+	require.NoError(t, err)
+	_, err = d.(folder.Editor).RenameFolder(foldertest.OrgA, "golf", "india")
+	require.NoError(t, err)
+
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+
+	got := next(t, ch)
+	assert.Equal(t, "application/json", got.header.Get("Content-Type"))
+	assert.Equal(t, hook.ID.String(), got.header.Get("X-Webhook-Id"))
+	assert.Equal(t, got.payload.DeliveryID.String(), got.header.Get("X-Webhook-Delivery"))
+	assert.True(t, webhook.Verify("s3cret", got.header.Get("X-Webhook-Timestamp"), got.header.Get("X-Webhook-Signature"), got.body))
+	assert.False(t, webhook.Verify("other", got.header.Get("X-Webhook-Timestamp"), got.header.Get("X-Webhook-Signature"), got.body))
+
+	e := got.payload.Event
+	e.Time = time.Time{}
+	assert.Equal(t, hook.ID, got.payload.WebhookID)
+	assert.Equal(t, folder.Event{Type: folder.FolderMoved, OrgID: foldertest.OrgA, Seq: 2, Name: "bravo", Changes: []folder.Change{
+		{Name: "bravo", OldPath: "alpha.bravo", NewPath: "alpha.delta.bravo"},
+		{Name: "charlie", OldPath: "alpha.bravo.charlie", NewPath: "alpha.delta.bravo.charlie"},
+	}}, e)
+	none(t, ch)
+
+	eventually(t, func() bool { return len(w.Log(hook.ID)) == 1 })
+	log := w.Log(hook.ID)[0]
+	assert.Equal(t, got.payload.DeliveryID, log.DeliveryID)
+	assert.Equal(t, uint64(2), log.Seq)
+	assert.Equal(t, 1, log.Attempt)
+	assert.Equal(t, http.StatusOK, log.StatusCode)
+	assert.Empty(t, log.Error)
+	assert.Empty(t, w.DeadLetters())
+}
+
+func Test_webhook_Retries(t *testing.T) {
+	t.Parallel()
+	d, w := dispatcher(t, webhook.Options{})
+	srv, ch := receiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	hook, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srv.URL})
+	require.NoError(t, err)
+	assert.Len(t, hook.Secret, 64, "a secret is generated")
+
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	_, err = d.MoveFolder("echo", "golf")
+	require.NoError(t, err)
+
+	// the second move waits for the first one to be delivered
+	first := next(t, ch)
+	for range 2 {
+		retry := next(t, ch)
+		assert.Equal(t, first.payload.DeliveryID, retry.payload.DeliveryID)
+		assert.Equal(t, first.body, retry.body)
+		assert.True(t, webhook.Verify(hook.Secret, retry.header.Get("X-Webhook-Timestamp"), retry.header.Get("X-Webhook-Signature"), retry.body))
+	}
+	second := next(t, ch)
+	assert.Equal(t, "echo", second.payload.Event.Name)
+	assert.NotEqual(t, first.payload.DeliveryID, second.payload.DeliveryID)
+
+	eventually(t, func() bool { return len(w.Log(hook.ID)) == 4 })
+	statuses := []int{}
+	for _, a := range w.Log(hook.ID) {
+		statuses = append(statuses, a.StatusCode)
+	}
+	assert.Equal(t, []int{503, 429, 200, 200}, statuses)
+	assert.Equal(t, 3, w.Log(hook.ID)[2].Attempt)
+	assert.Empty(t, w.DeadLetters())
+}
+
+func Test_webhook_DeadLetters(t *testing.T) {
+	t.Parallel()
+	tests := [...]struct {
+		name     string
+		statuses []int
+		attempts int
+	}{
+		{name: "retries run out", statuses: []int{500, 502, 504}, attempts: 3},
+		{name: "permanent failure", statuses: []int{410}, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d, w := dispatcher(t, webhook.Options{})
+			srv, ch := receiver(t, tt.statuses...)
+			hook, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srv.URL})
+			require.NoError(t, err)
+
+			_, err = d.MoveFolder("bravo", "delta")
+			require.NoError(t, err)
+			_, err = d.MoveFolder("echo", "golf")
+			require.NoError(t, err)
+
+			for range tt.attempts {
+				assert.Equal(t, "bravo", next(t, ch).payload.Event.Name)
+			}
+			// later events are still delivered
+			assert.Equal(t, "echo", next(t, ch).payload.Event.Name)
+
+			eventually(t, func() bool { return len(w.Log(hook.ID)) == tt.attempts+1 })
+			dead := w.DeadLetters()
+			require.Len(t, dead, 1)
+			assert.Equal(t, hook.ID, dead[0].WebhookID)
+			assert.Equal(t, "bravo", dead[0].Event.Name)
+			assert.Equal(t, tt.attempts, dead[0].Attempts)
+			assert.Contains(t, dead[0].Error, http.StatusText(tt.statuses[len(tt.statuses)-1]))
+		})
+	}
+}
+
+func Test_webhook_Unreachable(t *testing.T) {
+	t.Parallel()
+	d, w := dispatcher(t, webhook.Options{MaxAttempts: 2})
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	hook, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srv.URL})
+	require.NoError(t, err)
+
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+
+	eventually(t, func() bool { return len(w.DeadLetters()) == 1 })
+	log := w.Log(hook.ID)
+	require.Len(t, log, 2)
+	for _, a := range log {
+		assert.Zero(t, a.StatusCode)
+		assert.NotEmpty(t, a.Error)
+	}
+}
+
+func Test_webhook_Remove(t *testing.T) {
+	t.Parallel()
+	d, w := dispatcher(t, webhook.Options{})
+	srvA, chA := receiver(t)
+	srvB, chB := receiver(t)
+	a, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srvA.URL})
+	require.NoError(t, err)
+	b, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srvB.URL})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []webhook.Webhook{a, b}, w.Webhooks(foldertest.OrgA))
+	assert.Empty(t, w.Webhooks(foldertest.OrgB))
+
+	require.NoError(t, w.Remove(a.ID))
+	assert.ErrorIs(t, w.Remove(a.ID), webhook.ErrWebhookNotFound)
+	assert.Equal(t, []webhook.Webhook{b}, w.Webhooks(uuid.Nil))
+
+	_, err = d.MoveFolder("bravo", "delta")
+	require.NoError(t, err)
+	assert.Equal(t, "bravo", next(t, chB).payload.Event.Name)
+	none(t, chA)
+}
+
+func Test_webhook_AddErrors(t *testing.T) {
+	t.Parallel()
+	_, w := dispatcher(t, webhook.Options{})
+	tests := [...]struct {
+		name string
+		hook webhook.Webhook
+		want error
+	}{
+		{name: "no org", hook: webhook.Webhook{URL: "http://example.com"}, want: webhook.ErrInvalidOrg},
+		{name: "relative", hook: webhook.Webhook{OrgID: foldertest.OrgA, URL: "/hooks"}, want: webhook.ErrInvalidURL},
+		{name: "scheme", hook: webhook.Webhook{OrgID: foldertest.OrgA, URL: "ftp://example.com"}, want: webhook.ErrInvalidURL},
+		{name: "unparsable", hook: webhook.Webhook{OrgID: foldertest.OrgA, URL: "http://[::1"}, want: webhook.ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := w.Add(tt.hook)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+	assert.Empty(t, w.Webhooks(uuid.Nil))
+}
+
+func Test_webhook_Backoff(t *testing.T) {
+	t.Parallel()
+	opts := webhook.Options{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range want {
+		assert.Equal(t, delay, opts.Backoff(i+1), "attempt %d", i+1)
+	}
+}
+
+func Test_webhook_Sign(t *testing.T) {
+	t.Parallel()
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac key
+	assert.Equal(t, "sha256=9d713ed406bb7076d4123f0dc2c39d2df5c654ed4b0cd56b52c8b4c940bd63ae", webhook.Sign("key", "1700000000", []byte("{}")))
+}
+
+func Test_webhook_FallsBehind(t *testing.T) {
+	t.Parallel()
+	d, w := dispatcher(t, webhook.Options{Buffer: 1, InitialBackoff: 20 * time.Millisecond})
+	srv, ch := receiver(t, http.StatusServiceUnavailable)
+	_, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: srv.URL})
+	require.NoError(t, err)
+
+	// more moves than the buffer holds while the first one waits for its retry
+	for _, dst := range []string{"delta", "golf", "alpha", "echo"} {
+		_, err = d.MoveFolder("bravo", dst)
+		require.NoError(t, err)
+	}
+
+	seqs := []uint64{}
+	for len(seqs) < 4 {
+		got := next(t, ch)
+		if len(seqs) == 0 || seqs[len(seqs)-1] != got.payload.Event.Seq {
+			seqs = append(seqs, got.payload.Event.Seq)
+		}
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4}, seqs)
+	none(t, ch)
+	assert.Empty(t, w.DeadLetters())
+}
+
+// endingNotifier ends every subscription right away, like a driver that
+// doesn't publish events behind a wrapper that has Subscribe
+type endingNotifier struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (n *endingNotifier) Subscribe(opts folder.SubscribeOptions) *folder.Subscription {
+	n.mu.Lock()
+	n.calls++
+	n.mu.Unlock()
+	sub := folder.NewEventBus(folder.EventBusOptions{}).Subscribe(opts)
+	sub.Close()
+	return sub
+}
+
+func Test_webhook_SubscriptionEnded(t *testing.T) {
+	t.Parallel()
+	n := &endingNotifier{}
+	w := webhook.New(n, webhook.Options{})
+	_, err := w.Add(webhook.Webhook{OrgID: foldertest.OrgA, URL: "http://localhost/hook"})
+	require.NoError(t, err)
+
+	// the webhook stops instead of subscribing again and again
+	time.Sleep(20 * time.Millisecond)
+	w.Close()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	assert.Equal(t, 1, n.calls)
+}